@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// newAttachmentsCommand builds the attachments subcommand, which copies the files of an export out under
+// their original names.
+func newAttachmentsCommand() *cobra.Command {
+	var (
+		outputFolder string
+		ids          []string
+		since        string
+		until        string
+	)
+	attachmentsCmd := &cobra.Command{
+		Use:   "attachments -f <archive_file.zip> -o <output_folder> [--id <id>] [--since DATE] [--until DATE]",
+		Short: "Copy every uploaded file and generated image of an export into a folder, renamed to their original file names",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if outputFolder == "" {
+				return errors.New("missing required flag: -o")
+			}
+			_, filterErr := attachmentFilter(ids, since, until)
+			return filterErr
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			filter, filterErr := attachmentFilter(ids, since, until)
+			if filterErr != nil {
+				return filterErr
+			}
+			return extract.RunAttachments(cmd.Context(), inputSettings, outputFolder, filter)
+		},
+	}
+	attachmentsCmd.Flags().StringVarP(&outputFolder, "output", "o", "",
+		"Folder the files are copied into, under files/ and dalle-generations/")
+	attachmentsCmd.Flags().StringSliceVar(&ids, "id", nil,
+		"Only copy the files of these conversations (repeatable or comma-separated)")
+	attachmentsCmd.Flags().StringVar(&since, "since", "",
+		"Only copy the files of conversations created at or after this time (RFC3339 or YYYY-MM-DD)")
+	attachmentsCmd.Flags().StringVar(&until, "until", "",
+		"Only copy the files of conversations created at or before this time (RFC3339 or YYYY-MM-DD, inclusive of the whole day)")
+	return attachmentsCmd
+}
+
+func attachmentFilter(ids []string, since string, until string) (extract.AttachmentFilter, error) {
+	filter := extract.AttachmentFilter{IDs: splitCommaValues(ids)}
+	if since != "" {
+		parsed, parseErr := utils.ParseDateBound(since, false)
+		if parseErr != nil {
+			return extract.AttachmentFilter{}, fmt.Errorf("invalid --since: %w", parseErr)
+		}
+		filter.Since = parsed
+	}
+	if until != "" {
+		parsed, parseErr := utils.ParseDateBound(until, true)
+		if parseErr != nil {
+			return extract.AttachmentFilter{}, fmt.Errorf("invalid --until: %w", parseErr)
+		}
+		filter.Until = parsed
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return extract.AttachmentFilter{}, errors.New("--until must not be earlier than --since")
+	}
+	return filter, nil
+}
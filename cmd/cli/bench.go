@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/render"
+	"openai_extract/internal/synthetic"
+	"openai_extract/internal/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultBenchRuns = 3
+	// defaultBenchPattern matches about a quarter of a synthetic export's conversations.
+	defaultBenchPattern = "terraform"
+	syntheticExportName = "synthetic-export.zip"
+)
+
+// newBenchCommand builds the bench subcommand, which times the matching pipeline over the exports given to -f,
+// or over a synthetic export generated for the purpose.
+func newBenchCommand() *cobra.Command {
+	var (
+		format       string
+		runs         int
+		generatePath string
+		synthOptions synthetic.Options
+	)
+	benchCmd := &cobra.Command{
+		Use:   "bench [-f <archive_file.zip>] [-p <pattern> ...] [--conversations N --attachments M] [--runs R] [--format table|json]",
+		Short: "Time reading, decoding, and matching an export, or a synthetic one of any size, to compare performance across releases",
+		Args:  cobra.NoArgs,
+		// Without -f a synthetic export is benchmarked, so -f is not required.
+		Annotations: map[string]string{exportOptionalAnnotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// The selection flags share their names with extraction's, so they are bound once it is known which command runs.
+			if bindErr := viper.BindPFlags(cmd.Flags()); bindErr != nil {
+				return bindErr
+			}
+			if benchFormat := render.StatsFormat(format); !slices.Contains(render.KnownStatsFormats, benchFormat) {
+				return fmt.Errorf("unknown bench format %q (supported: %s, %s)", benchFormat, render.StatsTable, render.StatsJSON)
+			}
+			if runs < 0 || viper.GetInt("jobs") < 1 {
+				return errors.New("--runs must not be negative and --jobs must be at least 1")
+			}
+			if len(viper.GetStringSlice("file")) > 0 && generatePath != "" {
+				return errors.New("--generate writes a synthetic export to benchmark instead of -f; pass one or the other")
+			}
+			if len(viper.GetStringSlice("pattern")) == 0 && len(viper.GetStringSlice("id")) == 0 && viper.GetString("ids-file") == "" && viper.GetString("semantic") == "" {
+				viper.Set("pattern", []string{defaultBenchPattern})
+			}
+			return validateSelection()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(viper.GetStringSlice("file")) == 0 {
+				exportPath, cleanup, generateErr := generateBenchExport(generatePath, synthOptions)
+				if generateErr != nil {
+					return generateErr
+				}
+				defer cleanup()
+				viper.Set("file", []string{exportPath})
+			}
+			if runs == 0 {
+				return nil
+			}
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			query, queryErr := buildQuery(viper.GetViper())
+			if queryErr != nil {
+				return queryErr
+			}
+			criteria, criteriaErr := buildCriteria(viper.GetViper())
+			if criteriaErr != nil {
+				return criteriaErr
+			}
+			return extract.RunBench(cmd.Context(), inputSettings, query, criteria, viper.GetInt("jobs"), runs, render.StatsFormat(format))
+		},
+	}
+	addSelectionFlags(benchCmd.Flags())
+	benchCmd.Flags().Int("jobs", runtime.NumCPU(),
+		"How many conversations are decoded and matched at once (1 processes them one at a time)")
+	benchCmd.Flags().IntVar(&runs, "runs", defaultBenchRuns,
+		"How many times the pipeline is timed; the median run is reported too (0 only generates the synthetic export)")
+	benchCmd.Flags().StringVar(&format, "format", string(render.StatsTable), "Output format: table or json (for keeping and comparing results)")
+	benchCmd.Flags().StringVar(&generatePath, "generate", "",
+		"Without -f, write the synthetic export here and keep it instead of using a temporary file")
+	benchCmd.Flags().IntVar(&synthOptions.Conversations, "conversations", synthetic.DefaultConversations,
+		"Conversations in the synthetic export")
+	benchCmd.Flags().IntVar(&synthOptions.Messages, "messages", synthetic.DefaultMessages,
+		"User and assistant messages in each synthetic conversation")
+	benchCmd.Flags().IntVar(&synthOptions.Attachments, "attachments", synthetic.DefaultAttachments,
+		"Image attachments in the synthetic export, spread evenly over its conversations")
+	benchCmd.Flags().Uint64Var(&synthOptions.Seed, "seed", 1,
+		"Seed of the synthetic export; the same seed and sizes always produce the same export")
+	return benchCmd
+}
+
+// generateBenchExport writes the synthetic export to generatePath, or to a temporary folder removed by cleanup.
+func generateBenchExport(generatePath string, options synthetic.Options) (string, func(), error) {
+	cleanup := func() {}
+	if generatePath == "" {
+		temporary, tempErr := os.MkdirTemp("", "openai_extract-bench-")
+		if tempErr != nil {
+			return "", nil, fmt.Errorf("create temporary folder: %w", tempErr)
+		}
+		cleanup = func() { _ = os.RemoveAll(temporary) }
+		generatePath = filepath.Join(temporary, syntheticExportName)
+	}
+	if generateErr := synthetic.Generate(generatePath, options); generateErr != nil {
+		cleanup()
+		return "", nil, generateErr
+	}
+	if info, statErr := os.Stat(generatePath); statErr == nil {
+		fmt.Fprintf(os.Stderr, "generated %s (%s)\n", generatePath, utils.FormatByteSize(info.Size()))
+	}
+	return generatePath, cleanup, nil
+}
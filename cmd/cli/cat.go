@@ -0,0 +1,27 @@
+package main
+
+import (
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+)
+
+// newCatCommand builds the cat subcommand, which prints the JSON of conversations for use in pipelines.
+func newCatCommand() *cobra.Command {
+	var pretty bool
+	catCmd := &cobra.Command{
+		Use:   "cat -f <archive_file.zip> <id or pattern> [--pretty]",
+		Short: "Write the JSON of the conversation with this id, or of every conversation matching it as a pattern, to stdout for jq",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.RunCat(cmd.Context(), inputSettings, args[0], pretty)
+		},
+	}
+	catCmd.Flags().BoolVar(&pretty, "pretty", false,
+		"Indent each conversation instead of writing it compacted on one line")
+	return catCmd
+}
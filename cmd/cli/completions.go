@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/extract"
+	"openai_extract/internal/filters"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const listSeparator = ","
+
+// completionShells maps each supported shell to the generator of its completion script.
+var completionShells = map[string]func(root *cobra.Command, out io.Writer) error{
+	"bash":       func(root *cobra.Command, out io.Writer) error { return root.GenBashCompletionV2(out, true) },
+	"zsh":        func(root *cobra.Command, out io.Writer) error { return root.GenZshCompletion(out) },
+	"fish":       func(root *cobra.Command, out io.Writer) error { return root.GenFishCompletion(out, true) },
+	"powershell": func(root *cobra.Command, out io.Writer) error { return root.GenPowerShellCompletionWithDesc(out) },
+}
+
+// newCompletionsCommand builds the completions subcommand, which prints the shell completion script for a shell.
+func newCompletionsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completions bash|zsh|fish|powershell",
+		Short: "Print the shell completion script; --language and --content-type values complete from the export given to -f",
+		Long: `Print the shell completion script for the given shell. For example:
+
+  bash:        source <(openai_extract completions bash)
+  zsh:         openai_extract completions zsh > "${fpath[1]}/_openai_extract"
+  fish:        openai_extract completions fish > ~/.config/fish/completions/openai_extract.fish
+  powershell:  openai_extract completions powershell | Out-String | Invoke-Expression
+
+Once -f is on the command line, --language and --content-type complete to the values found in that export;
+without it, --language completes to the languages that can be detected in code.`,
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		// The completion script does not read an export, so the root's -f checks do not apply.
+		Annotations: map[string]string{exportOptionalAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if generateErr := completionShells[args[0]](cmd.Root(), os.Stdout); generateErr != nil {
+				return fmt.Errorf("generate %s completion: %w", args[0], generateErr)
+			}
+			return nil
+		},
+	}
+}
+
+// registerFilterCompletions completes --language and --content-type of a command that has the selection flags.
+func registerFilterCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("content-type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		contentTypes, _ := exportFilterValues(cmd.Context())
+		return completeListValue(contentTypes, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+	_ = cmd.RegisterFlagCompletionFunc("language", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		_, languages := exportFilterValues(cmd.Context())
+		if len(languages) == 0 {
+			languages = filters.DetectableLanguages()
+		}
+		return completeListValue(languages, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// exportFilterValues reads the content types and languages of the local exports given to -f. It never prompts
+// or downloads, as it runs while the user is typing; any failure yields no values.
+func exportFilterValues(ctx context.Context) ([]string, []string) {
+	inputSettings, inputErr := buildInputSettings()
+	if inputErr != nil || len(inputSettings.Paths) == 0 {
+		return nil, nil
+	}
+	for _, archiveFilePath := range inputSettings.Paths {
+		if archive.IsRemote(archiveFilePath) || archive.IsShareURL(archiveFilePath) {
+			return nil, nil
+		}
+	}
+	if viper.GetString("zip-password") == "" {
+		inputSettings.Password = nil
+	}
+	contentTypes, languages, valuesErr := extract.FilterValues(ctx, inputSettings)
+	if valuesErr != nil {
+		return nil, nil
+	}
+	return contentTypes, languages
+}
+
+// completeListValue completes the last item of a comma-separated flag value, keeping the items before it.
+func completeListValue(values []string, toComplete string) []string {
+	typed := ""
+	if separator := strings.LastIndex(toComplete, listSeparator); separator >= 0 {
+		typed = toComplete[:separator+1]
+	}
+	var completions []string
+	for _, value := range values {
+		if strings.HasPrefix(value, toComplete[len(typed):]) {
+			completions = append(completions, typed+value)
+		}
+	}
+	return completions
+}
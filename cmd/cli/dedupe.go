@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/render"
+
+	"github.com/spf13/cobra"
+)
+
+// newDedupeCommand builds the dedupe subcommand, which reports duplicate conversations and can write the
+// exports without them.
+func newDedupeCommand() *cobra.Command {
+	var (
+		format   string
+		settings extract.DedupeSettings
+	)
+	dedupeCmd := &cobra.Command{
+		Use:   "dedupe -f <archive_file.zip> [-f ...] [--threshold 0.9] [-o <deduplicated.zip|folder>]",
+		Short: "Find exact and near-duplicate conversations, such as retried prompts, and optionally write the exports without them",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if dedupeFormat := render.StatsFormat(format); !slices.Contains(render.KnownStatsFormats, dedupeFormat) {
+				return fmt.Errorf("unknown dedupe format %q (supported: %s, %s)", dedupeFormat, render.StatsTable, render.StatsJSON)
+			}
+			if settings.Threshold <= 0 || settings.Threshold > 1 {
+				return errors.New("--threshold must be above 0 and at most 1")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			settings.Format = render.StatsFormat(format)
+			return extract.RunDedupe(cmd.Context(), inputSettings, settings)
+		},
+	}
+	dedupeCmd.Flags().StringVar(&format, "format", string(render.StatsTable), "Report format: table or json")
+	dedupeCmd.Flags().Float64Var(&settings.Threshold, "threshold", extract.DefaultDuplicateThreshold,
+		"Transcript similarity (0-1) from which conversations count as near duplicates; 1 reports only identical transcripts")
+	dedupeCmd.Flags().StringVarP(&settings.OutputPath, "output", "o", "",
+		"Also write the exports without the duplicates: a ZIP archive when the name ends in .zip, otherwise an empty or new folder")
+	return dedupeCmd
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+	"openai_extract/internal/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// newExportCommand builds the export subcommand, which writes matched conversations in the chosen format. The
+// root command runs the same extraction with the same flags, so existing scripts keep working.
+func newExportCommand() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export -f <archive_file.zip> -p <pattern> [-p <pattern> ...] -o <output_folder> [--format json|md|html|sqlite|sharegpt]",
+		Short: "Extract full conversations matched by patterns and filters into an output folder, in the chosen format",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// The export flags are defined on the root too, so they are bound once it is known which command runs.
+			if bindErr := viper.BindPFlags(cmd.Flags()); bindErr != nil {
+				return bindErr
+			}
+			return validateExport()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(cmd.Context())
+		},
+	}
+	addExportFlags(exportCmd.Flags())
+	registerFilterCompletions(exportCmd)
+	return exportCmd
+}
+
+// addExportFlags defines the flags of extraction: the selection flags, where and in which format matches are
+// written, and the extra outputs.
+func addExportFlags(flags *pflag.FlagSet) {
+	flags.Bool("verify", false,
+		"Before extracting, check every archive entry (ZIP CRCs) and that every attachment referenced by a conversation exists; stop on problems")
+	flags.String("state", "",
+		"Record extracted conversation ids and update times in this JSON file, and skip conversations already extracted unchanged")
+	flags.Bool("trust-archive", false,
+		"Write linked files under their archive names without rejecting absolute, '..', or otherwise unsafe entry names")
+	flags.StringP("output", "o", "", "Output folder (required unless --digest, --feed, or --context is given)")
+	flags.String("queries", "",
+		"Run every named search of this YAML, JSON, or TOML file in a single scan of the exports, each into its own subfolder of -o (see README)")
+	addSelectionFlags(flags)
+	flags.String("format", render.DefaultFormat,
+		"Per-conversation document format: "+strings.Join(render.FormatNames(), ", "))
+	flags.String("branches", string(model.BranchCurrent),
+		"Which branches rendered outputs include: current (the branch shown in ChatGPT) or all (also regenerated answers and edited prompts, marked as alternatives)")
+	flags.String("messages-from", "",
+		"Only include messages of this author role in rendered transcripts (template, split messages, code, digest, feed): "+strings.Join(filters.KnownRoles, ", "))
+	flags.Bool("visible-only", false,
+		"Leave hidden messages, tool calls and results, and system prompts out of rendered transcripts, as the ChatGPT UI does")
+	flags.String("template", "",
+		"Render each matched conversation through this Go text/template file (output named conversation<ext> from the template name)")
+	flags.Bool("split-messages", false,
+		"Also write each message as a numbered file under messages/ (001-user.md, 002-assistant.md, ...)")
+	flags.Bool("extract-code", false,
+		"Also write every fenced code block under code/ as snippet_NNN.<ext>, with the extension inferred from the fence language")
+	flags.Bool("extract-dalle", false,
+		"Also copy DALL-E generated images under dalle/ with a prompts.json listing each image's prompt and seed")
+	flags.Bool("canvas-revisions", false,
+		"Besides the final version of each canvas document under canvas/, also write every revision as <title>.vN.<ext>")
+	flags.String("analysis", "",
+		"Also write code interpreter cells with their stdout, stderr, and results: notebook (analysis.ipynb) or scripts (analysis/NNN.py and NNN.out.txt)")
+	flags.Bool("extract-voice", false,
+		"Also copy the audio clips of voice conversations under voice/ with a merged transcript.md labelled by speaker and time")
+	flags.Bool("include-reasoning", false,
+		"Render the reasoning summaries of o1-style models in rendered transcripts as collapsible sections instead of dropping them")
+	flags.Bool("show-matches", false,
+		"Report where each pattern hit (message id, role, character offset) in matches.txt per conversation folder, or on stdout without -o")
+	flags.Int("context", 0,
+		"Print every pattern hit with this many lines of surrounding message text to stdout, grep style; -o is then optional (0 disables)")
+	flags.String("highlight", "",
+		"Mark pattern hits in Markdown and HTML transcripts, split messages, the digest, and template output: mark (<mark>) or bold (**)")
+	flags.Bool("timestamps", false,
+		"Annotate each message in Markdown and HTML transcripts, the digest, and split message files with the time it was written")
+	flags.String("timezone", "",
+		"Time zone for rendered times, folder names included, as an IANA name such as Europe/Berlin or UTC (default: the local zone)")
+	flags.String("digest", "",
+		"Write all matched conversations into this single chronologically ordered Markdown file with a table of contents")
+	flags.String("feed", "",
+		"Write an Atom feed of matched conversations (title, date, summary, link to the extracted folder) to this file")
+	addRunFlags(flags)
+}
+
+// addRunFlags defines --jobs and --quiet, shared by the commands that run an extraction.
+func addRunFlags(flags *pflag.FlagSet) {
+	flags.Int("jobs", runtime.NumCPU(),
+		"How many conversations are decoded, matched, and written at once (1 processes them one at a time)")
+	flags.BoolP("quiet", "q", false,
+		"Do not show the progress line (conversations scanned and matched, bytes read, ETA) on stderr")
+}
+
+// showProgress reports whether a progress line is drawn: unless --quiet is given, whenever stderr is a terminal.
+func showProgress() bool {
+	return !viper.GetBool("quiet") && utils.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// validateExport checks the extraction flags bound to viper before anything is read.
+func validateExport() error {
+	if highlight := render.HighlightStyle(viper.GetString("highlight")); highlight != render.HighlightNone && !slices.Contains(render.KnownHighlightStyles, highlight) {
+		return fmt.Errorf("unknown highlight style %q (supported: %s, %s)", highlight, render.HighlightMark, render.HighlightBold)
+	}
+	if viper.GetInt("context") < 0 {
+		return errors.New("--context must not be negative")
+	}
+	if viper.GetInt("jobs") < 1 {
+		return errors.New("--jobs must be at least 1")
+	}
+	if viper.GetString("output") == "" && viper.GetString("digest") == "" && viper.GetString("feed") == "" && viper.GetInt("context") == 0 {
+		return errors.New("missing required flag: -o, --output (or --digest / --feed / --context)")
+	}
+	if _, formatErr := render.LookupFormat(viper.GetString("format")); formatErr != nil {
+		return formatErr
+	}
+	if branches := model.BranchMode(viper.GetString("branches")); !slices.Contains(model.KnownBranchModes, branches) {
+		return fmt.Errorf("unknown branches mode %q (supported: %s, %s)", branches, model.BranchCurrent, model.BranchAll)
+	}
+	if analysis := render.AnalysisFormat(viper.GetString("analysis")); analysis != render.AnalysisNone && !slices.Contains(render.KnownAnalysisFormats, analysis) {
+		return fmt.Errorf("unknown analysis format %q (supported: %s, %s)", analysis, render.AnalysisNotebook, render.AnalysisScripts)
+	}
+	if _, zoneErr := timeZone(); zoneErr != nil {
+		return zoneErr
+	}
+	if role := viper.GetString("messages-from"); role != "" && !slices.Contains(filters.KnownRoles, role) {
+		return fmt.Errorf("unknown --messages-from role %q (supported: %s)", role, strings.Join(filters.KnownRoles, ", "))
+	}
+	if queriesPath := viper.GetString("queries"); queriesPath != "" {
+		if viper.GetString("digest") != "" || viper.GetString("feed") != "" || viper.GetString("state") != "" {
+			return errors.New("--digest, --feed, and --state cannot be combined with --queries")
+		}
+		_, loadErr := loadQueries(queriesPath)
+		return loadErr
+	}
+	return validateSelection()
+}
+
+// runExport extracts the conversations selected by the flags bound to viper, stopping once ctx is done.
+func runExport(ctx context.Context) error {
+	inputSettings, inputErr := buildInputSettings()
+	if inputErr != nil {
+		return inputErr
+	}
+	inputSettings.Verify = viper.GetBool("verify")
+	outputRoot := viper.GetString("output")
+	location, zoneErr := timeZone()
+	if zoneErr != nil {
+		return zoneErr
+	}
+	outputSettings := extract.OutputSettings{
+		Format:           viper.GetString("format"),
+		TemplatePath:     viper.GetString("template"),
+		SplitMessages:    viper.GetBool("split-messages"),
+		ExtractCode:      viper.GetBool("extract-code"),
+		ExtractDalle:     viper.GetBool("extract-dalle"),
+		CanvasRevisions:  viper.GetBool("canvas-revisions"),
+		Analysis:         render.AnalysisFormat(viper.GetString("analysis")),
+		ExtractVoice:     viper.GetBool("extract-voice"),
+		IncludeReasoning: viper.GetBool("include-reasoning"),
+		ShowMatches:      viper.GetBool("show-matches"),
+		ContextLines:     viper.GetInt("context"),
+		Highlight:        render.HighlightStyle(viper.GetString("highlight")),
+		Timestamps:       viper.GetBool("timestamps"),
+		Location:         location,
+		DigestPath:       viper.GetString("digest"),
+		FeedPath:         viper.GetString("feed"),
+		TrustArchive:     viper.GetBool("trust-archive"),
+		StatePath:        viper.GetString("state"),
+		Branches:         model.BranchMode(viper.GetString("branches")),
+		MessagesFrom:     viper.GetString("messages-from"),
+		VisibleOnly:      viper.GetBool("visible-only"),
+		Jobs:             viper.GetInt("jobs"),
+		Progress:         showProgress(),
+	}
+	queries, queriesErr := selectedQueries()
+	if queriesErr != nil {
+		return queriesErr
+	}
+	return extract.Run(ctx, extract.Options{Input: inputSettings, Queries: queries, OutputRoot: outputRoot, Output: outputSettings})
+}
+
+// selectedQueries returns the searches of --queries, or else the single search of the selection flags.
+func selectedQueries() ([]extract.NamedQuery, error) {
+	if queriesPath := viper.GetString("queries"); queriesPath != "" {
+		return loadQueries(queriesPath)
+	}
+	query, queryErr := buildQuery(viper.GetViper())
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	criteria, criteriaErr := buildCriteria(viper.GetViper())
+	if criteriaErr != nil {
+		return nil, criteriaErr
+	}
+	return []extract.NamedQuery{{Query: query, Criteria: criteria}}, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"openai_extract/internal/filters"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// addSelectionFlags defines the flags that choose which conversations match: patterns, ids, semantic ranking,
+// attribute filters, and paging. Extraction and search share them.
+func addSelectionFlags(flags *pflag.FlagSet) {
+	flags.StringSliceP("pattern", "p", nil,
+		"Case-insensitive search terms or raw regexes; repeat -p to combine multiple patterns (all must match unless --match-mode any)")
+	flags.StringSlice("id", nil,
+		"Extract exactly these conversation ids, bypassing pattern matching (repeatable or comma-separated)")
+	flags.String("ids-file", "",
+		"Read conversation ids to extract from this file, one per line (# starts a comment)")
+	flags.String("match-mode", string(filters.MatchAll),
+		"How multiple -p patterns combine: all (AND) or any (OR)")
+	flags.StringSlice("content-type", nil,
+		"Require these content types to be present, ALL of them unless --filter-mode any (comma-separated or repeated flag)")
+	flags.StringSliceP("language", "l", nil,
+		"Require these languages to be present, ALL of them unless --filter-mode any (comma-separated or repeated flag). Example: -l python -l go,js")
+	flags.String("filter-mode", string(filters.MatchAll),
+		"How --content-type and --language values combine: all (every value present) or any (at least one)")
+	flags.BoolP("word", "w", false,
+		"Match literal patterns as whole words only (\"go\" no longer matches \"google\")")
+	flags.Bool("case-sensitive", false,
+		"Match patterns with exact case instead of the default case-insensitive matching")
+	flags.Bool("regex", false,
+		"Treat every pattern as a regular expression (a single pattern can opt in with a re: prefix)")
+	flags.Bool("literal", false,
+		"Treat every pattern as plain text, even if it contains regex characters (a single pattern can opt in with a lit: prefix)")
+	flags.StringSlice("exclude", nil,
+		"Skip conversations matching ANY of these patterns, even if all -p patterns match (repeatable)")
+	flags.String("semantic", "",
+		"Rank conversations by lexical similarity to this query, the words and word pairs they share with it, instead of literal matching; no language model is involved, so synonyms do not count (combine with --limit for the top N)")
+	flags.Float64("semantic-min-score", defaultSemanticMinScore,
+		"Minimum cosine similarity of the word vectors for --semantic results")
+	flags.String("embedding-cache", defaultCachePath(embeddingCacheFileName),
+		"File caching the word vectors of conversations for --semantic between runs; vectors of conversations no longer in the export are dropped (empty disables caching)")
+	flags.Bool("title-only", false,
+		"Match patterns against the conversation title instead of the whole conversation (per pattern: -p title:<term>)")
+	flags.String("role", "",
+		"Match patterns only against messages authored by this role: "+strings.Join(filters.KnownRoles, ", "))
+	flags.String("search-scope", string(filters.SearchAll),
+		"What patterns are matched against: all (entire conversation JSON) or visible (title and messages shown in the ChatGPT UI)")
+	flags.String("since", "",
+		"Only match conversations created at or after this time (RFC3339 or YYYY-MM-DD)")
+	flags.String("until", "",
+		"Only match conversations created at or before this time (RFC3339 or YYYY-MM-DD, inclusive of the whole day)")
+	flags.String("updated-since", "",
+		"Only match conversations updated at or after this time (RFC3339 or YYYY-MM-DD); useful for incremental re-runs")
+	flags.Int("min-messages", 0,
+		"Only match conversations with at least this many user/assistant messages")
+	flags.Int("max-messages", 0,
+		"Only match conversations with at most this many user/assistant messages (0 means no limit)")
+	flags.Int("min-words", 0,
+		"Only match conversations whose user/assistant text has at least this many words")
+	flags.Int("min-tokens", 0,
+		"Only match conversations whose user/assistant text has at least this many estimated tokens")
+	flags.Duration("min-duration", 0,
+		"Only match conversations whose first-to-last message span is at least this long (e.g. 30m, 2h)")
+	flags.Duration("max-duration", 0,
+		"Only match conversations whose first-to-last message span is at most this long (0 means no limit)")
+	flags.Bool("has-files", false,
+		"Only match conversations that reference files present in the archive's files/ directory")
+	flags.Bool("has-images", false,
+		"Only match conversations that reference image files present in the archive's files/ directory")
+	flags.Bool("has-dalle", false,
+		"Only match conversations where DALL-E generated images (dalle tool calls or image pointers with DALL-E metadata)")
+	flags.Bool("has-canvas", false,
+		"Only match conversations containing canvas/textdoc documents")
+	flags.Bool("voice", false,
+		"Only match voice-mode conversations (audio asset pointers, transcriptions, voice metadata)")
+	flags.StringSlice("tool", nil,
+		"Require ALL of these tools to appear in message metadata: "+strings.Join(filters.KnownTools(), ", "))
+	flags.StringSlice("gpt", nil,
+		"Only match conversations held with one of these custom GPTs, by gizmo id (g-...), GPT URL/slug, or display name")
+	flags.StringSlice("project", nil,
+		"Only match conversations inside one of these ChatGPT Projects, by project id (g-p-...), project URL, or name")
+	flags.StringSlice("member", nil,
+		"In Team/Enterprise workspace exports, only match conversations of these members, by email or member folder name")
+	flags.Bool("archived", false,
+		"Only match archived conversations (is_archived)")
+	flags.Bool("no-archived", false,
+		"Skip archived conversations (is_archived)")
+	flags.Bool("starred", false,
+		"Only match starred conversations (is_starred)")
+	flags.Int("skip", 0,
+		"Skip this many matched conversations, ordered by create_time, before extracting")
+	flags.Int("limit", 0,
+		"Extract at most this many matched conversations, ordered by create_time (0 means no limit)")
+}
+
+// validateSelection checks that the selection flags name something to match and combine into a valid query and criteria.
+func validateSelection() error {
+	if len(viper.GetStringSlice("pattern")) == 0 && len(viper.GetStringSlice("id")) == 0 && viper.GetString("ids-file") == "" && viper.GetString("semantic") == "" {
+		return errors.New("missing required flag: -p, --pattern (repeat -p to combine multiple patterns), or --id / --ids-file / --semantic")
+	}
+	query, queryErr := buildQuery(viper.GetViper())
+	if queryErr != nil {
+		return queryErr
+	}
+	if validateErr := query.Validate(); validateErr != nil {
+		return validateErr
+	}
+	_, criteriaErr := buildCriteria(viper.GetViper())
+	return criteriaErr
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newIndexCommand builds the index subcommand, which writes the full-text index later runs pass to --index.
+func newIndexCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "index -f <archive_file.zip> --index <index_file>",
+		Short: "Build a persistent full-text index of an export so repeated searches and extractions skip conversations that cannot match",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if viper.GetString("index") == "" {
+				return errors.New("missing required flag: --index")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.RunIndex(cmd.Context(), inputSettings)
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/render"
+
+	"github.com/spf13/cobra"
+)
+
+// newInfoCommand builds the info subcommand, which identifies each export given to -f.
+func newInfoCommand() *cobra.Command {
+	var format string
+	infoCmd := &cobra.Command{
+		Use:   "info -f <archive_file.zip> [--format table|json]",
+		Short: "Print each export's date, account email, conversation count, attachment size, and schema version",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if infoFormat := render.StatsFormat(format); !slices.Contains(render.KnownStatsFormats, infoFormat) {
+				return fmt.Errorf("unknown info format %q (supported: %s, %s)", infoFormat, render.StatsTable, render.StatsJSON)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.RunInfo(cmd.Context(), inputSettings, render.StatsFormat(format))
+		},
+	}
+	infoCmd.Flags().StringVar(&format, "format", string(render.StatsTable), "Output format: table or json")
+	return infoCmd
+}
@@ -1,79 +1,368 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
+	"openai_extract/internal/archive"
 	"openai_extract/internal/extract"
+	"openai_extract/internal/filters"
 	"openai_extract/internal/utils"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+const (
+	applicationCacheFolder  = "openai_extract"
+	embeddingCacheFileName  = "embeddings.gob"
+	downloadCacheFolderName = "downloads"
+	defaultSemanticMinScore = 0.1
+	// exportOptionalAnnotation marks commands that run without -f, so the root does not require it.
+	exportOptionalAnnotation = "openai_extract/export-optional"
+)
+
+// rootCommand is the root of the command tree together with the profiling session it keeps for the command
+// being run: started before the command runs and stopped once it returns, whether or not it failed.
+type rootCommand struct {
+	command  *cobra.Command
+	profiles profileSession
+}
+
 func main() {
 	baseName := filepath.Base(os.Args[0])
 
-	rootCmd := &cobra.Command{
-		Use:   baseName + " -f <archive_file.zip> -p <pattern> [-p <pattern> ...] -o <output_folder> [--content-type code,code_interpreter] [--language python,go]",
-		Short: "Extract full conversations from an OpenAI ChatGPT export ZIP by multiple patterns (AND), with optional content-type/language filters",
+	root := &rootCommand{}
+	root.command = &cobra.Command{
+		Use:               baseName + " <command> -f <archive_file.zip> [flags]",
+		Short:             "Search, extract, and inspect OpenAI ChatGPT exports; without a command, extracts as the export command does",
+		PersistentPreRunE: root.prepare,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			viper.SetEnvPrefix("openai_search")
-			viper.AutomaticEnv()
-			if viper.GetString("file") == "" {
-				return errors.New("missing required flag: -f, --file")
-			}
-			if len(viper.GetStringSlice("pattern")) == 0 {
-				return errors.New("missing required flag: -p, --pattern (repeat -p to AND multiple patterns)")
-			}
-			if viper.GetString("output") == "" {
-				return errors.New("missing required flag: -o, --output")
-			}
-			return nil
+			return validateExport()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			archiveFilePath := viper.GetString("file")
-			searchPatterns := viper.GetStringSlice("pattern")
-			outputRoot := viper.GetString("output")
-			contentTypes := viper.GetStringSlice("content-type")
-			languagesRaw := viper.GetStringSlice("language")
-
-			languages := make([]string, 0, len(languagesRaw))
-			for _, raw := range languagesRaw {
-				for _, piece := range strings.Split(raw, ",") {
-					trimmed := strings.TrimSpace(piece)
-					if trimmed != "" {
-						languages = append(languages, trimmed)
-					}
-				}
-			}
-			return extract.Run(archiveFilePath, searchPatterns, outputRoot, contentTypes, languages)
+			return runExport(cmd.Context())
 		},
 	}
+	rootCmd := root.command
+
+	rootCmd.PersistentFlags().StringArrayP("file", "f", nil,
+		"Path to an OpenAI ChatGPT export: ZIP, .tar.gz/.tgz, bare conversations.json, or the folder it was unzipped into (required); repeat -f or pass a glob to search several exports, keeping the newest copy of each conversation")
+	rootCmd.PersistentFlags().String("source", string(archive.SourceOpenAI),
+		"Service the export comes from: openai (ChatGPT data export) or gemini (Google Takeout with Gemini Apps activity)")
+	rootCmd.PersistentFlags().String("download-cache", defaultCachePath(downloadCacheFolderName),
+		"Folder where exports given to -f as http(s) URLs are downloaded; interrupted downloads resume from here")
+	rootCmd.PersistentFlags().String("record-cache", defaultCachePath(""),
+		"Folder where the position of every conversation in an export file is cached under the file's size and modification time, checked against its SHA-256, so later runs against the unchanged file skip parsing it (empty disables)")
+	rootCmd.PersistentFlags().String("max-memory", "",
+		"Cap memory used for decompressed archive entries (e.g. 512MB); entries beyond it spill to a temporary folder (empty means no cap)")
+	rootCmd.PersistentFlags().String("index", "",
+		"Full-text index built by the index command from the same exports; searches and extractions then read only conversations that can match")
+	rootCmd.PersistentFlags().String("zip-password", "",
+		"Password for encrypted (ZipCrypto or AES) export ZIPs; prompted for on the terminal when omitted")
+	addProfileFlags(rootCmd.PersistentFlags())
+	addExportFlags(rootCmd.Flags())
+
+	_ = viper.BindPFlag("file", rootCmd.PersistentFlags().Lookup("file"))
+	_ = viper.BindPFlags(rootCmd.Flags())
+	_ = viper.BindPFlag("source", rootCmd.PersistentFlags().Lookup("source"))
+	_ = viper.BindPFlag("download-cache", rootCmd.PersistentFlags().Lookup("download-cache"))
+	_ = viper.BindPFlag("record-cache", rootCmd.PersistentFlags().Lookup("record-cache"))
+	_ = viper.BindPFlag("max-memory", rootCmd.PersistentFlags().Lookup("max-memory"))
+	_ = viper.BindPFlag("index", rootCmd.PersistentFlags().Lookup("index"))
+	_ = viper.BindPFlag("zip-password", rootCmd.PersistentFlags().Lookup("zip-password"))
+	for _, profileFlag := range []string{"cpuprofile", "memprofile", "trace"} {
+		_ = viper.BindPFlag(profileFlag, rootCmd.PersistentFlags().Lookup(profileFlag))
+	}
 
-	rootCmd.Flags().StringP("file", "f", "", "Path to the OpenAI ChatGPT ZIP archive (required)")
-	rootCmd.Flags().StringP("output", "o", "", "Output folder (required)")
-	rootCmd.Flags().StringSliceP("pattern", "p", nil,
-		"Case-insensitive search terms or raw regexes; repeat -p to AND multiple patterns (all must match)")
-	rootCmd.Flags().StringSlice("content-type", nil,
-		"Require ALL of these content types to be present (comma-separated or repeated flag)")
-	rootCmd.Flags().StringSliceP("language", "l", nil,
-		"Require ALL of these languages to be present (comma-separated or repeated flag). Example: -l python -l go,js")
+	registerFilterCompletions(rootCmd)
 
-	_ = viper.BindPFlag("file", rootCmd.Flags().Lookup("file"))
-	_ = viper.BindPFlag("pattern", rootCmd.Flags().Lookup("pattern"))
-	_ = viper.BindPFlag("output", rootCmd.Flags().Lookup("output"))
-	_ = viper.BindPFlag("content-type", rootCmd.Flags().Lookup("content-type"))
-	_ = viper.BindPFlag("language", rootCmd.Flags().Lookup("language"))
+	// completions replaces cobra's default completion command.
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(newStatsCommand(), newSearchCommand(), newIndexCommand(), newServeCommand(), newMergeCommand(), newValidateCommand(), newInfoCommand(), newShowCommand(), newCompletionsCommand(), newExportCommand(), newAttachmentsCommand(), newWatchCommand(), newDedupeCommand(), newRedactCommand(), newSplitCommand(), newCatCommand(), newBenchCommand())
 
 	// Support -ct shorthand → --content-type
 	rootCmd.SetArgs(utils.NormalizeCTShorthand(os.Args[1:]))
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	executeErr := root.execute(ctx)
+	stop()
+	if executeErr != nil {
+		fmt.Fprintln(os.Stderr, executeErr)
 		os.Exit(1)
 	}
 }
+
+// prepare runs before every command: it reads the environment, starts the profiles the flags ask for, and
+// checks the export flags of commands that read an export.
+func (root *rootCommand) prepare(cmd *cobra.Command, args []string) error {
+	viper.SetEnvPrefix("openai_search")
+	viper.AutomaticEnv()
+	if startErr := root.profiles.start(viper.GetString("cpuprofile"), viper.GetString("trace")); startErr != nil {
+		return startErr
+	}
+	if cmd.Annotations[exportOptionalAnnotation] != "" || cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+		return nil
+	}
+	if len(viper.GetStringSlice("file")) == 0 {
+		return errors.New("missing required flag: -f, --file")
+	}
+	_, inputErr := buildInputSettings()
+	return inputErr
+}
+
+// execute runs the command the arguments name and then stops the profiles, writing the heap profile; a
+// failure to write them is reported without changing the outcome of the command.
+func (root *rootCommand) execute(ctx context.Context) error {
+	executeErr := root.command.ExecuteContext(ctx)
+	if stopErr := root.profiles.stop(viper.GetString("memprofile")); stopErr != nil {
+		fmt.Fprintln(os.Stderr, stopErr)
+	}
+	return executeErr
+}
+
+// buildInputSettings validates the export flags shared by every command and turns them into input settings.
+func buildInputSettings() (extract.InputSettings, error) {
+	archiveFilePaths, pathsErr := expandArchivePaths()
+	if pathsErr != nil {
+		return extract.InputSettings{}, pathsErr
+	}
+	maxMemory, memoryErr := maxMemoryBytes()
+	if memoryErr != nil {
+		return extract.InputSettings{}, memoryErr
+	}
+	source := archive.Source(viper.GetString("source"))
+	if !slices.Contains(archive.KnownSources, source) {
+		return extract.InputSettings{}, fmt.Errorf("unknown source %q (supported: %s, %s)", source, archive.SourceOpenAI, archive.SourceGemini)
+	}
+	return extract.InputSettings{
+		Paths:            archiveFilePaths,
+		DownloadCacheDir: viper.GetString("download-cache"),
+		RecordCacheDir:   viper.GetString("record-cache"),
+		Password:         zipPasswordSource(viper.GetString("zip-password")),
+		MaxMemory:        maxMemory,
+		Source:           source,
+		IndexPath:        viper.GetString("index"),
+	}, nil
+}
+
+// expandArchivePaths resolves the -f values, expanding globs and dropping repeated paths.
+func expandArchivePaths() ([]string, error) {
+	var archivePaths []string
+	seen := make(map[string]struct{})
+	for _, value := range viper.GetStringSlice("file") {
+		expanded := []string{value}
+		if !archive.IsRemote(value) && strings.ContainsAny(value, "*?[") {
+			globbed, globErr := filepath.Glob(value)
+			if globErr != nil {
+				return nil, fmt.Errorf("invalid --file glob %q: %w", value, globErr)
+			}
+			if len(globbed) == 0 {
+				return nil, fmt.Errorf("no exports match --file %q", value)
+			}
+			expanded = globbed
+		}
+		for _, archivePath := range expanded {
+			if _, duplicate := seen[archivePath]; duplicate {
+				continue
+			}
+			seen[archivePath] = struct{}{}
+			archivePaths = append(archivePaths, archivePath)
+		}
+	}
+	return archivePaths, nil
+}
+
+// maxMemoryBytes parses --max-memory, returning 0 when no cap is set.
+func maxMemoryBytes() (int64, error) {
+	maxMemory := viper.GetString("max-memory")
+	if maxMemory == "" {
+		return 0, nil
+	}
+	parsed, parseErr := utils.ParseByteSize(maxMemory)
+	if parseErr != nil {
+		return 0, fmt.Errorf("invalid --max-memory: %w", parseErr)
+	}
+	return parsed, nil
+}
+
+// timeZone resolves --timezone, defaulting to the local zone.
+func timeZone() (*time.Location, error) {
+	name := viper.GetString("timezone")
+	if name == "" {
+		return time.Local, nil
+	}
+	location, loadErr := time.LoadLocation(name)
+	if loadErr != nil {
+		return nil, fmt.Errorf("invalid --timezone: %w", loadErr)
+	}
+	return location, nil
+}
+
+// zipPasswordSource returns the configured password, or prompts for one when an encrypted entry is first opened.
+func zipPasswordSource(configured string) archive.PasswordSource {
+	return func() (string, error) {
+		if configured != "" {
+			return configured, nil
+		}
+		prompted, promptErr := utils.PromptPassword("ZIP password: ")
+		if errors.Is(promptErr, utils.ErrNoTerminal) {
+			return "", errors.New("archive is encrypted: pass --zip-password or set OPENAI_SEARCH_ZIP_PASSWORD")
+		}
+		return prompted, promptErr
+	}
+}
+
+// buildQuery turns the pattern settings into a query; settings are the flags bound to viper or one search of
+// a --queries file.
+func buildQuery(settings *viper.Viper) (filters.Query, error) {
+	ids := splitCommaValues(settings.GetStringSlice("id"))
+	if idsFile := settings.GetString("ids-file"); idsFile != "" {
+		listed, readErr := utils.ReadListFile(idsFile)
+		if readErr != nil {
+			return filters.Query{}, fmt.Errorf("read --ids-file: %w", readErr)
+		}
+		ids = append(ids, listed...)
+	}
+	syntax := utils.SyntaxAuto
+	switch {
+	case settings.GetBool("regex") && settings.GetBool("literal"):
+		return filters.Query{}, errors.New("--regex and --literal cannot be combined")
+	case settings.GetBool("regex"):
+		syntax = utils.SyntaxRegex
+	case settings.GetBool("literal"):
+		syntax = utils.SyntaxLiteral
+	}
+	return filters.Query{
+		IDs:           ids,
+		Patterns:      settings.GetStringSlice("pattern"),
+		Excludes:      settings.GetStringSlice("exclude"),
+		MatchMode:     filters.MatchMode(settings.GetString("match-mode")),
+		WholeWord:     settings.GetBool("word"),
+		CaseSensitive: settings.GetBool("case-sensitive"),
+		TitleOnly:     settings.GetBool("title-only"),
+		Role:          settings.GetString("role"),
+		SearchScope:   filters.SearchScope(settings.GetString("search-scope")),
+		Syntax:        syntax,
+		Semantic: filters.SemanticQuery{
+			Text:      settings.GetString("semantic"),
+			MinScore:  settings.GetFloat64("semantic-min-score"),
+			CachePath: settings.GetString("embedding-cache"),
+		},
+	}, nil
+}
+
+// buildCriteria turns the filter and paging settings into criteria, read from settings as buildQuery does.
+func buildCriteria(settings *viper.Viper) (filters.Criteria, error) {
+	criteria := filters.Criteria{
+		ContentTypes: settings.GetStringSlice("content-type"),
+		Languages:    splitCommaValues(settings.GetStringSlice("language")),
+		FilterMode:   filters.MatchMode(settings.GetString("filter-mode")),
+		MinMessages:  settings.GetInt("min-messages"),
+		MaxMessages:  settings.GetInt("max-messages"),
+		MinWords:     settings.GetInt("min-words"),
+		MinTokens:    settings.GetInt("min-tokens"),
+		MinDuration:  settings.GetDuration("min-duration"),
+		MaxDuration:  settings.GetDuration("max-duration"),
+		HasFiles:     settings.GetBool("has-files"),
+		HasImages:    settings.GetBool("has-images"),
+		HasDalle:     settings.GetBool("has-dalle"),
+		HasCanvas:    settings.GetBool("has-canvas"),
+		Voice:        settings.GetBool("voice"),
+		Tools:        splitCommaValues(settings.GetStringSlice("tool")),
+		GPTs:         splitCommaValues(settings.GetStringSlice("gpt")),
+		Projects:     splitCommaValues(settings.GetStringSlice("project")),
+		Members:      splitCommaValues(settings.GetStringSlice("member")),
+		Page:         filters.Page{Skip: settings.GetInt("skip"), Limit: settings.GetInt("limit")},
+	}
+	if criteria.MinDuration < 0 || criteria.MaxDuration < 0 {
+		return filters.Criteria{}, errors.New("--min-duration and --max-duration must not be negative")
+	}
+	if criteria.MaxDuration > 0 && criteria.MaxDuration < criteria.MinDuration {
+		return filters.Criteria{}, errors.New("--max-duration must not be lower than --min-duration")
+	}
+	if validateErr := criteria.Validate(); validateErr != nil {
+		return filters.Criteria{}, validateErr
+	}
+	if criteria.Page.Skip < 0 || criteria.Page.Limit < 0 {
+		return filters.Criteria{}, errors.New("--skip and --limit must not be negative")
+	}
+	for _, tool := range criteria.Tools {
+		if !slices.Contains(filters.KnownTools(), filters.NormalizeToolName(tool)) {
+			return filters.Criteria{}, fmt.Errorf("unknown tool %q (supported: %s)", tool, strings.Join(filters.KnownTools(), ", "))
+		}
+	}
+	if settings.GetBool("archived") && settings.GetBool("no-archived") {
+		return filters.Criteria{}, errors.New("--archived and --no-archived cannot be combined")
+	}
+	if settings.GetBool("archived") || settings.GetBool("no-archived") {
+		archived := settings.GetBool("archived")
+		criteria.Archived = &archived
+	}
+	if settings.GetBool("starred") {
+		starred := true
+		criteria.Starred = &starred
+	}
+	if criteria.MinMessages < 0 || criteria.MaxMessages < 0 || criteria.MinWords < 0 || criteria.MinTokens < 0 {
+		return filters.Criteria{}, errors.New("--min-messages, --max-messages, --min-words and --min-tokens must not be negative")
+	}
+	if criteria.MaxMessages > 0 && criteria.MaxMessages < criteria.MinMessages {
+		return filters.Criteria{}, errors.New("--max-messages must not be lower than --min-messages")
+	}
+	if since := settings.GetString("since"); since != "" {
+		parsed, parseErr := utils.ParseDateBound(since, false)
+		if parseErr != nil {
+			return filters.Criteria{}, fmt.Errorf("invalid --since: %w", parseErr)
+		}
+		criteria.Since = parsed
+	}
+	if until := settings.GetString("until"); until != "" {
+		parsed, parseErr := utils.ParseDateBound(until, true)
+		if parseErr != nil {
+			return filters.Criteria{}, fmt.Errorf("invalid --until: %w", parseErr)
+		}
+		criteria.Until = parsed
+	}
+	if updatedSince := settings.GetString("updated-since"); updatedSince != "" {
+		parsed, parseErr := utils.ParseDateBound(updatedSince, false)
+		if parseErr != nil {
+			return filters.Criteria{}, fmt.Errorf("invalid --updated-since: %w", parseErr)
+		}
+		criteria.UpdatedSince = parsed
+	}
+	if !criteria.Since.IsZero() && !criteria.Until.IsZero() && criteria.Until.Before(criteria.Since) {
+		return filters.Criteria{}, errors.New("--until must not be earlier than --since")
+	}
+	return criteria, nil
+}
+
+func splitCommaValues(rawValues []string) []string {
+	values := make([]string, 0, len(rawValues))
+	for _, raw := range rawValues {
+		for _, piece := range strings.Split(raw, ",") {
+			trimmed := strings.TrimSpace(piece)
+			if trimmed != "" {
+				values = append(values, trimmed)
+			}
+		}
+	}
+	return values
+}
+
+// defaultCachePath places name under the per-user cache folder, or returns "" when there is none.
+func defaultCachePath(name string) string {
+	cacheRoot, cacheErr := os.UserCacheDir()
+	if cacheErr != nil {
+		return ""
+	}
+	return filepath.Join(cacheRoot, applicationCacheFolder, name)
+}
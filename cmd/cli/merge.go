@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+)
+
+// newMergeCommand builds the merge subcommand, which combines the exports given to -f into one.
+func newMergeCommand() *cobra.Command {
+	var outputPath string
+	mergeCmd := &cobra.Command{
+		Use:   "merge -f <archive_file.zip> -f <archive_file.zip> -o <merged.zip|folder>",
+		Short: "Combine several exports into one ZIP archive or folder, keeping the newest copy of each conversation",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if outputPath == "" {
+				return errors.New("missing required flag: -o")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.RunMerge(cmd.Context(), inputSettings, outputPath)
+		},
+	}
+	mergeCmd.Flags().StringVarP(&outputPath, "output", "o", "",
+		"Merged export to write: a ZIP archive when the name ends in .zip, otherwise an empty or new folder")
+	return mergeCmd
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/spf13/pflag"
+)
+
+// profileSession holds the CPU profile and execution trace started for the running command.
+type profileSession struct {
+	cpuFile   *os.File
+	traceFile *os.File
+}
+
+// addProfileFlags defines the flags that profile a run, for every command.
+func addProfileFlags(flags *pflag.FlagSet) {
+	flags.String("cpuprofile", "", "Write a CPU profile of the run to this file, for go tool pprof")
+	flags.String("memprofile", "", "Write a heap profile (memory in use and allocated over the run) to this file when the run ends, for go tool pprof")
+	flags.String("trace", "", "Write an execution trace of the run to this file, for go tool trace")
+}
+
+// start starts the CPU profile and execution trace written to the paths that are not empty.
+func (session *profileSession) start(cpuPath string, tracePath string) error {
+	if cpuPath != "" {
+		cpuFile, createErr := os.Create(cpuPath)
+		if createErr != nil {
+			return fmt.Errorf("create CPU profile: %w", createErr)
+		}
+		if profileErr := pprof.StartCPUProfile(cpuFile); profileErr != nil {
+			cpuFile.Close()
+			return fmt.Errorf("start CPU profile: %w", profileErr)
+		}
+		session.cpuFile = cpuFile
+	}
+	if tracePath != "" {
+		traceFile, createErr := os.Create(tracePath)
+		if createErr != nil {
+			session.stop("")
+			return fmt.Errorf("create trace: %w", createErr)
+		}
+		if traceErr := trace.Start(traceFile); traceErr != nil {
+			traceFile.Close()
+			session.stop("")
+			return fmt.Errorf("start trace: %w", traceErr)
+		}
+		session.traceFile = traceFile
+	}
+	return nil
+}
+
+// stop finishes the CPU profile and execution trace and, when memPath is not empty, writes the heap profile.
+func (session *profileSession) stop(memPath string) error {
+	var stopErrs []error
+	if session.cpuFile != nil {
+		pprof.StopCPUProfile()
+		if closeErr := session.cpuFile.Close(); closeErr != nil {
+			stopErrs = append(stopErrs, fmt.Errorf("write CPU profile: %w", closeErr))
+		}
+		session.cpuFile = nil
+	}
+	if session.traceFile != nil {
+		trace.Stop()
+		if closeErr := session.traceFile.Close(); closeErr != nil {
+			stopErrs = append(stopErrs, fmt.Errorf("write trace: %w", closeErr))
+		}
+		session.traceFile = nil
+	}
+	if memPath != "" {
+		stopErrs = append(stopErrs, writeHeapProfile(memPath))
+	}
+	return errors.Join(stopErrs...)
+}
+
+// writeHeapProfile writes the live heap after a collection, so the profile shows what the run still holds.
+func writeHeapProfile(path string) error {
+	memFile, createErr := os.Create(path)
+	if createErr != nil {
+		return fmt.Errorf("create heap profile: %w", createErr)
+	}
+	runtime.GC()
+	profileErr := pprof.WriteHeapProfile(memFile)
+	closeErr := memFile.Close()
+	if joined := errors.Join(profileErr, closeErr); joined != nil {
+		return fmt.Errorf("write heap profile: %w", joined)
+	}
+	return nil
+}
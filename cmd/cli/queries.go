@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	queriesKey      = "queries"
+	queryNameKey    = "name"
+	queryOutputKey  = "output"
+	queriesExamples = "queries: [{name: terraform, pattern: [terraform]}, {name: go, pattern: [golang], language: [go], output: code/go}]"
+)
+
+// loadQueries reads the --queries file: a YAML, JSON, or TOML list of named searches under "queries", each
+// setting selection flags by name (pattern, exclude, since, content-type, ...) over the ones given on the command
+// line, and naming with "output" the subfolder of -o its matches go to (the search name by default).
+func loadQueries(path string) ([]extract.NamedQuery, error) {
+	file := viper.New()
+	file.SetConfigFile(path)
+	if readErr := file.ReadInConfig(); readErr != nil {
+		return nil, fmt.Errorf("read --queries: %w", readErr)
+	}
+	entries, isList := file.Get(queriesKey).([]any)
+	if !isList || len(entries) == 0 {
+		return nil, fmt.Errorf("--queries %s lists no searches; expected %s", path, queriesExamples)
+	}
+	selectionFlags := pflag.NewFlagSet(queriesKey, pflag.ContinueOnError)
+	addSelectionFlags(selectionFlags)
+
+	queries := make([]extract.NamedQuery, 0, len(entries))
+	seenNames := make(map[string]struct{})
+	seenFolders := make(map[string]string)
+	for position, entry := range entries {
+		settings, isMap := entry.(map[string]any)
+		if !isMap {
+			return nil, fmt.Errorf("--queries search %d is not a map of settings", position+1)
+		}
+		name, _ := settings[queryNameKey].(string)
+		if name == "" {
+			return nil, fmt.Errorf("--queries search %d has no name", position+1)
+		}
+		if _, repeated := seenNames[name]; repeated {
+			return nil, fmt.Errorf("--queries names more than one search %q", name)
+		}
+		seenNames[name] = struct{}{}
+		named, buildErr := buildNamedQuery(name, settings, selectionFlags)
+		if buildErr != nil {
+			return nil, fmt.Errorf("--queries search %q: %w", name, buildErr)
+		}
+		if other, shared := seenFolders[named.Folder]; shared {
+			return nil, fmt.Errorf("--queries searches %q and %q write to the same folder %q", other, name, named.Folder)
+		}
+		seenFolders[named.Folder] = name
+		queries = append(queries, named)
+	}
+	return queries, nil
+}
+
+// buildNamedQuery layers the settings of one search over the selection flags bound to viper and builds its query.
+func buildNamedQuery(name string, settings map[string]any, selectionFlags *pflag.FlagSet) (extract.NamedQuery, error) {
+	layered := viper.New()
+	selectionFlags.VisitAll(func(flag *pflag.Flag) {
+		layered.SetDefault(flag.Name, viper.Get(flag.Name))
+	})
+	folder := name
+	for key, value := range settings {
+		switch {
+		case key == queryNameKey:
+		case key == queryOutputKey:
+			folder, _ = value.(string)
+		case selectionFlags.Lookup(key) != nil:
+			layered.Set(key, settingValue(value))
+		default:
+			return extract.NamedQuery{}, fmt.Errorf("unknown setting %q (settings are selection flag names, plus name and output)", key)
+		}
+	}
+	if !filepath.IsLocal(folder) {
+		return extract.NamedQuery{}, fmt.Errorf("output %q must be a folder inside -o", folder)
+	}
+	if len(layered.GetStringSlice("pattern")) == 0 && len(layered.GetStringSlice("id")) == 0 && layered.GetString("ids-file") == "" && layered.GetString("semantic") == "" {
+		return extract.NamedQuery{}, errors.New("set pattern, id, ids-file, or semantic")
+	}
+	query, queryErr := buildQuery(layered)
+	if queryErr != nil {
+		return extract.NamedQuery{}, queryErr
+	}
+	if validateErr := query.Validate(); validateErr != nil {
+		return extract.NamedQuery{}, validateErr
+	}
+	criteria, criteriaErr := buildCriteria(layered)
+	if criteriaErr != nil {
+		return extract.NamedQuery{}, criteriaErr
+	}
+	return extract.NamedQuery{Name: name, Query: query, Criteria: criteria, Folder: filepath.Clean(folder)}, nil
+}
+
+// settingValue turns the dates YAML parses on its own back into the text the date flags take, keeping a bare
+// date a bare date so --until still covers the whole day.
+func settingValue(value any) any {
+	timestamp, isTime := value.(time.Time)
+	if !isTime {
+		return value
+	}
+	if timestamp.Location() == time.UTC && timestamp.Equal(timestamp.Truncate(24*time.Hour)) {
+		return timestamp.Format(time.DateOnly)
+	}
+	return timestamp.Format(time.RFC3339)
+}
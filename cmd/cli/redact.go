@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/redact"
+
+	"github.com/spf13/cobra"
+)
+
+// newRedactCommand builds the redact subcommand, which writes a copy of the exports with sensitive text masked.
+func newRedactCommand() *cobra.Command {
+	var (
+		outputPath      string
+		kinds           []string
+		masks           []string
+		keepAttachments bool
+	)
+	redactCmd := &cobra.Command{
+		Use:   "redact -f <archive_file.zip|output_folder> -o <redacted.zip|folder> [--kind email,phone,...] [--mask <pattern>]",
+		Short: "Write a copy of an export or extraction output with emails, phone numbers, IPs, card numbers, and secrets masked, safe to share",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if outputPath == "" {
+				return errors.New("missing required flag: -o")
+			}
+			_, redactorErr := buildRedactor(kinds, masks)
+			return redactorErr
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			redactor, redactorErr := buildRedactor(kinds, masks)
+			if redactorErr != nil {
+				return redactorErr
+			}
+			return extract.RunRedact(cmd.Context(), inputSettings, outputPath, redactor, keepAttachments)
+		},
+	}
+	knownKinds := redactionKindNames()
+	redactCmd.Flags().StringVarP(&outputPath, "output", "o", "",
+		"Redacted copy to write: a ZIP archive when the name ends in .zip, otherwise an empty or new folder")
+	redactCmd.Flags().StringSliceVar(&kinds, "kind", knownKinds,
+		"Kinds of sensitive text to mask: "+strings.Join(knownKinds, ", ")+" (comma-separated or repeated flag)")
+	redactCmd.Flags().StringArrayVar(&masks, "mask", nil,
+		"Also mask matches of this pattern, such as a name or a project codename; plain text or a regex, as with -p (repeatable)")
+	redactCmd.Flags().BoolVar(&keepAttachments, "keep-attachments", false,
+		"Copy uploaded files and images unchanged instead of leaving them out; their content is not masked")
+	return redactCmd
+}
+
+func buildRedactor(kinds []string, masks []string) (*redact.Redactor, error) {
+	selected := make([]redact.Kind, 0, len(kinds))
+	for _, kind := range splitCommaValues(kinds) {
+		selected = append(selected, redact.Kind(kind))
+	}
+	redactor, redactorErr := redact.New(selected, masks)
+	if redactorErr != nil {
+		return nil, fmt.Errorf("%w (supported kinds: %s)", redactorErr, strings.Join(redactionKindNames(), ", "))
+	}
+	return redactor, nil
+}
+
+func redactionKindNames() []string {
+	names := make([]string, 0, len(redact.KnownKinds))
+	for _, kind := range redact.KnownKinds {
+		names = append(names, string(kind))
+	}
+	return names
+}
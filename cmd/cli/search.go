@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newSearchCommand builds the search subcommand, which runs the same matching as extraction but only prints
+// what matched, for iterating on patterns quickly.
+func newSearchCommand() *cobra.Command {
+	searchCmd := &cobra.Command{
+		Use:   "search -f <archive_file.zip> -p <pattern> [-p <pattern> ...] [--context N]",
+		Short: "Print the id and title of each matched conversation with snippets around its hits, without writing any output",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// The selection flags share their names with extraction's, so they are bound once it is known which command runs.
+			if bindErr := viper.BindPFlags(cmd.Flags()); bindErr != nil {
+				return bindErr
+			}
+			if viper.GetInt("context") < 0 {
+				return errors.New("--context must not be negative")
+			}
+			if viper.GetInt("jobs") < 1 {
+				return errors.New("--jobs must be at least 1")
+			}
+			return validateSelection()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			query, queryErr := buildQuery(viper.GetViper())
+			if queryErr != nil {
+				return queryErr
+			}
+			criteria, criteriaErr := buildCriteria(viper.GetViper())
+			if criteriaErr != nil {
+				return criteriaErr
+			}
+			outputSettings := extract.OutputSettings{ListMatches: true, ContextLines: viper.GetInt("context"), Jobs: viper.GetInt("jobs"), Progress: showProgress()}
+			return extract.Run(cmd.Context(), extract.Options{
+				Input:   inputSettings,
+				Queries: []extract.NamedQuery{{Query: query, Criteria: criteria}},
+				Output:  outputSettings,
+			})
+		},
+	}
+	addSelectionFlags(searchCmd.Flags())
+	registerFilterCompletions(searchCmd)
+	searchCmd.Flags().Int("context", 0,
+		"Lines of message text to print around each hit; 0 prints only the lines holding it")
+	addRunFlags(searchCmd.Flags())
+	return searchCmd
+}
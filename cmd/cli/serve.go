@@ -0,0 +1,32 @@
+package main
+
+import (
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultListenAddress = "127.0.0.1:8080"
+
+// newServeCommand builds the serve subcommand, which browses the exports given to -f in a local web UI.
+func newServeCommand() *cobra.Command {
+	var address string
+	var splitMessages bool
+	serveCmd := &cobra.Command{
+		Use:   "serve -f <archive_file.zip> [--listen 127.0.0.1:8080]",
+		Short: "Browse an export in a local web UI with search, date filters, a conversation viewer, and ZIP export of selected conversations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.Serve(cmd.Context(), inputSettings, address, extract.OutputSettings{SplitMessages: splitMessages})
+		},
+	}
+	serveCmd.Flags().StringVar(&address, "listen", defaultListenAddress,
+		"Address the web UI listens on; the default only accepts connections from this machine")
+	serveCmd.Flags().BoolVar(&splitMessages, "split-messages", true,
+		"Include one Markdown file per message in exported conversation folders")
+	return serveCmd
+}
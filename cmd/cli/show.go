@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/model"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newShowCommand builds the show subcommand, which prints one conversation as a transcript without extracting.
+func newShowCommand() *cobra.Command {
+	var allMessages bool
+	showCmd := &cobra.Command{
+		Use:   "show -f <archive_file.zip> <id or title>",
+		Short: "Print one conversation, found by id or by title, to the terminal as a readable transcript",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// --branches and --timezone share their names with extraction's, so they are bound once it is known which command runs.
+			if bindErr := viper.BindPFlags(cmd.Flags()); bindErr != nil {
+				return bindErr
+			}
+			if branches := model.BranchMode(viper.GetString("branches")); !slices.Contains(model.KnownBranchModes, branches) {
+				return fmt.Errorf("unknown branches mode %q (supported: %s, %s)", branches, model.BranchCurrent, model.BranchAll)
+			}
+			_, zoneErr := timeZone()
+			return zoneErr
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			location, zoneErr := timeZone()
+			if zoneErr != nil {
+				return zoneErr
+			}
+			outputSettings := extract.OutputSettings{
+				Location:    location,
+				Branches:    model.BranchMode(viper.GetString("branches")),
+				VisibleOnly: !allMessages,
+			}
+			return extract.RunShow(cmd.Context(), inputSettings, args[0], outputSettings)
+		},
+	}
+	showCmd.Flags().BoolVar(&allMessages, "all-messages", false,
+		"Also show hidden messages, tool calls and results, and system prompts, which the ChatGPT UI leaves out")
+	showCmd.Flags().String("branches", string(model.BranchCurrent),
+		"Which branches to show: current (the branch shown in ChatGPT) or all (also regenerated answers and edited prompts, marked as alternatives)")
+	showCmd.Flags().String("timezone", "",
+		"Time zone for message times, as an IANA name such as Europe/Berlin or UTC (default: the local zone)")
+	return showCmd
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newSplitCommand builds the split subcommand, which unpacks every conversation of an export into its own file.
+func newSplitCommand() *cobra.Command {
+	var outputFolder string
+	splitCmd := &cobra.Command{
+		Use:   "split -f <archive_file.zip> -o <output_folder>",
+		Short: "Write every conversation of an export into its own pretty-printed JSON file named by date and title, no pattern needed",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// --timezone shares its name with extraction's, so it is bound once it is known which command runs.
+			if bindErr := viper.BindPFlags(cmd.Flags()); bindErr != nil {
+				return bindErr
+			}
+			if outputFolder == "" {
+				return errors.New("missing required flag: -o")
+			}
+			_, zoneErr := timeZone()
+			return zoneErr
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			location, zoneErr := timeZone()
+			if zoneErr != nil {
+				return zoneErr
+			}
+			return extract.RunSplit(cmd.Context(), inputSettings, outputFolder, location)
+		},
+	}
+	splitCmd.Flags().StringVarP(&outputFolder, "output", "o", "",
+		"Folder the conversation files are written into")
+	splitCmd.Flags().String("timezone", "",
+		"Time zone for the dates in file names, as an IANA name such as Europe/Berlin or UTC (default: the local zone)")
+	return splitCmd
+}
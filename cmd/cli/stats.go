@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"openai_extract/internal/extract"
+	"openai_extract/internal/render"
+
+	"github.com/spf13/cobra"
+)
+
+// newStatsCommand builds the stats subcommand, which summarizes the exports given to -f instead of extracting.
+func newStatsCommand() *cobra.Command {
+	var format string
+	statsCmd := &cobra.Command{
+		Use:   "stats -f <archive_file.zip> [--format table|json]",
+		Short: "Print aggregate statistics for an export: conversations, messages, date range, per-month activity, models, and attachments",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if statsFormat := render.StatsFormat(format); !slices.Contains(render.KnownStatsFormats, statsFormat) {
+				return fmt.Errorf("unknown stats format %q (supported: %s, %s)", statsFormat, render.StatsTable, render.StatsJSON)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.RunStats(cmd.Context(), inputSettings, render.StatsFormat(format))
+		},
+	}
+	statsCmd.Flags().StringVar(&format, "format", string(render.StatsTable), "Output format: table or json")
+	return statsCmd
+}
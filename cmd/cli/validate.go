@@ -0,0 +1,23 @@
+package main
+
+import (
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+)
+
+// newValidateCommand builds the validate subcommand, which checks the health of the exports given to -f.
+func newValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate -f <archive_file.zip>",
+		Short: "Check an export's structure, schema version, conversation JSON, and attachment references, printing a JSON report",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inputSettings, inputErr := buildInputSettings()
+			if inputErr != nil {
+				return inputErr
+			}
+			return extract.RunValidate(cmd.Context(), inputSettings)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"openai_extract/internal/extract"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newWatchCommand builds the watch subcommand, which runs the same extraction as the export command on every
+// export ZIP that appears in a folder.
+func newWatchCommand() *cobra.Command {
+	var (
+		profilePath string
+		settings    extract.WatchSettings
+	)
+	watchCmd := &cobra.Command{
+		Use:   "watch <folder> --profile <profile.yaml> | -p <pattern> -o <output_folder> [export flags]",
+		Short: "Watch a folder, such as your downloads, and extract every new export ZIP that appears in it with the same flags as export",
+		Args:  cobra.ExactArgs(1),
+		// The exports come from the watched folder, so -f is not required.
+		Annotations: map[string]string{exportOptionalAnnotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// The export flags are defined on the root too, so they are bound once it is known which command runs.
+			if bindErr := viper.BindPFlags(cmd.Flags()); bindErr != nil {
+				return bindErr
+			}
+			if profilePath != "" {
+				viper.SetConfigFile(profilePath)
+				if readErr := viper.ReadInConfig(); readErr != nil {
+					return fmt.Errorf("read profile: %w", readErr)
+				}
+			}
+			if info, statErr := os.Stat(args[0]); statErr != nil || !info.IsDir() {
+				return fmt.Errorf("watched folder %q is not a directory", args[0])
+			}
+			if settings.Interval <= 0 {
+				return errors.New("--interval must be positive")
+			}
+			return validateExport()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return extract.Watch(cmd.Context(), args[0], settings, func(exportPath string) error {
+				viper.Set("file", []string{exportPath})
+				return runExport(cmd.Context())
+			})
+		},
+	}
+	watchCmd.Flags().StringVar(&profilePath, "profile", "",
+		"Extraction profile: a YAML, JSON, or TOML file setting export flags by name (pattern, output, format, state, ...); flags given on the command line override it")
+	watchCmd.Flags().DurationVar(&settings.Interval, "interval", extract.DefaultWatchInterval,
+		"How often the folder is checked for new exports")
+	watchCmd.Flags().BoolVar(&settings.ProcessExisting, "existing", false,
+		"Also extract the exports already in the folder when watching starts")
+	addExportFlags(watchCmd.Flags())
+	registerFilterCompletions(watchCmd)
+	return watchCmd
+}
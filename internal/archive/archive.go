@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const conversationsFileName = "conversations.json"
+
+var errNoSuchEntry = errors.New("no such entry")
+
+// Source names the service an archive was exported from.
+type Source string
+
+const (
+	// SourceOpenAI is a ChatGPT data export.
+	SourceOpenAI Source = "openai"
+	// SourceGemini is a Google Takeout archive holding Gemini Apps activity.
+	SourceGemini Source = "gemini"
+)
+
+// KnownSources lists the supported export sources.
+var KnownSources = []Source{SourceOpenAI, SourceGemini}
+
+// Archive is an opened export. Entry contents are read only when Open is called, so attachments are
+// never held in memory unless a matched conversation needs them.
+type Archive struct {
+	names    []string
+	open     func(name string) (io.ReadCloser, error)
+	closer   func() error
+	prefetch func(names []string) error
+	source   Source
+	metadata map[string]Metadata
+	sizes    map[string]int64
+	// records is the record cache of the export file, when one was asked for.
+	records *recordCache
+	// entryMutex serializes opening and prefetching entries, as formats that cannot seek share one
+	// decompression pass and encrypted ZIPs prompt for their password once, so conversations can be
+	// written concurrently.
+	entryMutex sync.Mutex
+	// metadataMutex guards the metadata cache.
+	metadataMutex sync.Mutex
+}
+
+// OpenOptions tunes how an export is opened.
+type OpenOptions struct {
+	// Password is consulted only for encrypted ZIP entries.
+	Password PasswordSource
+	// MemoryBudget caps the bytes of decompressed entries held in memory for formats that cannot be read
+	// lazily (tar.gz); beyond it entries spill to a temporary folder. Zero or less means no cap.
+	MemoryBudget int64
+	// Source selects how conversations are read; empty means SourceOpenAI.
+	Source Source
+	// RecordCacheDir is the folder where the positions of conversations in export files are cached, keyed by
+	// the file's size and modification time and checked against its SHA-256; empty disables the cache. Unzipped export folders are never cached.
+	RecordCacheDir string
+}
+
+// OpenArchive opens an export from a ZIP archive, a .tar.gz/.tgz tarball, a bare conversations.json or
+// chat.html, or a directory the archive was already expanded into.
+func OpenArchive(exportPath string, options OpenOptions) (*Archive, error) {
+	opened, openErr := openBySuffix(exportPath, options)
+	if openErr != nil {
+		return nil, openErr
+	}
+	opened.source = options.Source
+	if options.RecordCacheDir != "" {
+		if info, statErr := os.Stat(exportPath); statErr == nil && info.Mode().IsRegular() {
+			records, cacheErr := loadRecordCache(options.RecordCacheDir, exportPath)
+			if cacheErr != nil {
+				opened.Close()
+				return nil, cacheErr
+			}
+			opened.records = records
+		}
+	}
+	return opened, nil
+}
+
+func openBySuffix(exportPath string, options OpenOptions) (*Archive, error) {
+	info, statErr := os.Stat(exportPath)
+	if statErr != nil {
+		return nil, fmt.Errorf("open export: %w", statErr)
+	}
+	if info.IsDir() {
+		return openDirArchive(exportPath)
+	}
+	lowerPath := strings.ToLower(exportPath)
+	switch {
+	case strings.HasSuffix(lowerPath, ".tar.gz"), strings.HasSuffix(lowerPath, ".tgz"):
+		return openTarGzArchive(exportPath, options.MemoryBudget)
+	case strings.HasSuffix(lowerPath, ".json"):
+		return openSingleFileArchive(exportPath, conversationsFileName)
+	case strings.HasSuffix(lowerPath, ".html"), strings.HasSuffix(lowerPath, ".htm"):
+		return openSingleFileArchive(exportPath, chatHTMLFileName)
+	default:
+		return openZipArchive(exportPath, options.Password)
+	}
+}
+
+func newArchive(names []string, open func(name string) (io.ReadCloser, error), closer func() error) *Archive {
+	sort.Strings(names)
+	return &Archive{names: names, open: open, closer: closer}
+}
+
+// Names lists the slash-separated entry paths of every regular file in the export, sorted.
+func (archive *Archive) Names() []string {
+	return archive.names
+}
+
+// Size returns the uncompressed size of an entry, when the format records it without reading the entry.
+func (archive *Archive) Size(name string) (int64, bool) {
+	size, known := archive.sizes[name]
+	return size, known
+}
+
+// Open streams the content of one entry; the caller closes the reader. It is safe for concurrent use.
+func (archive *Archive) Open(name string) (io.ReadCloser, error) {
+	reader, openErr := archive.openEntry(name)
+	if openErr != nil {
+		return nil, fmt.Errorf("open export entry %q: %w", name, openErr)
+	}
+	return reader, nil
+}
+
+// Prefetch tells the archive that the named entries are about to be opened, so formats that cannot seek read
+// them together instead of once per Open. Formats with random access ignore it.
+func (archive *Archive) Prefetch(names []string) error {
+	if archive.prefetch == nil {
+		return nil
+	}
+	archive.entryMutex.Lock()
+	defer archive.entryMutex.Unlock()
+	return archive.prefetch(names)
+}
+
+func (archive *Archive) openEntry(name string) (io.ReadCloser, error) {
+	archive.entryMutex.Lock()
+	defer archive.entryMutex.Unlock()
+	return archive.open(name)
+}
+
+// Close releases the underlying file handles.
+func (archive *Archive) Close() error {
+	if archive.closer == nil {
+		return nil
+	}
+	return archive.closer()
+}
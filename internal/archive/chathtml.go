@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const chatHTMLFileName = "chat.html"
+
+// chatHTMLDataMarkers introduce the JSON array of conversations that chat.html embeds for its viewer script.
+var chatHTMLDataMarkers = [][]byte{[]byte("var jsonData = "), []byte("var jsonData="), []byte("jsonData = ")}
+
+// chatHTMLConversations locates the conversation array inside chat.html and returns a reader positioned at it.
+// The page is scanned as it is read, so only the records themselves are ever decoded, one at a time. The
+// records are the same mapping-tree objects conversations.json holds, so they decode identically.
+func chatHTMLConversations(page io.Reader) (io.Reader, error) {
+	longest := 0
+	for _, marker := range chatHTMLDataMarkers {
+		longest = max(longest, len(marker))
+	}
+	reader := bufio.NewReader(page)
+	window := make([]byte, 0, 2*longest)
+	for {
+		current, readErr := reader.ReadByte()
+		if errors.Is(readErr, io.EOF) {
+			return nil, errors.New(chatHTMLFileName + " does not embed conversation data")
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("read %s: %w", chatHTMLFileName, readErr)
+		}
+		if len(window) == cap(window) {
+			window = append(window[:0], window[len(window)-longest+1:]...)
+		}
+		window = append(window, current)
+		for _, marker := range chatHTMLDataMarkers {
+			if bytes.HasSuffix(window, marker) {
+				return reader, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"io"
+	"sort"
+)
+
+// Combine presents several archives as one for attachment and metadata lookups, so the parts of a split
+// export resolve files that live in a different part than their conversations.json. When parts share an
+// entry name, the earliest part wins. Closing the combined archive leaves the parts open.
+func Combine(parts []*Archive) *Archive {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	owners := make(map[string]*Archive)
+	sizes := make(map[string]int64)
+	for _, part := range parts {
+		for _, name := range part.names {
+			if _, owned := owners[name]; !owned {
+				owners[name] = part
+				if size, known := part.Size(name); known {
+					sizes[name] = size
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(owners))
+	for name := range owners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	open := func(name string) (io.ReadCloser, error) {
+		owner, found := owners[name]
+		if !found {
+			return nil, errNoSuchEntry
+		}
+		return owner.openEntry(name)
+	}
+	prefetch := func(requested []string) error {
+		byOwner := make(map[*Archive][]string)
+		for _, name := range requested {
+			if owner, found := owners[name]; found {
+				byOwner[owner] = append(byOwner[owner], name)
+			}
+		}
+		for owner, ownedNames := range byOwner {
+			if prefetchErr := owner.Prefetch(ownedNames); prefetchErr != nil {
+				return prefetchErr
+			}
+		}
+		return nil
+	}
+	return &Archive{names: names, open: open, prefetch: prefetch, sizes: sizes}
+}
+
+// HasConversations reports whether the archive holds conversations for its source, as opposed to being
+// an attachments-only part of a split export.
+func (archive *Archive) HasConversations() bool {
+	if archive.source == SourceGemini {
+		_, findErr := archive.findGeminiActivity()
+		return findErr == nil
+	}
+	return len(archive.findEntries(conversationsFileName)) > 0 || len(archive.findEntries(chatHTMLFileName)) > 0
+}
@@ -0,0 +1,274 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const userFileName = "user.json"
+
+// Origin identifies where in an export a conversation was read from. Personal exports have a single origin at
+// the archive root; Team/Enterprise workspace exports nest one conversations.json per member.
+type Origin struct {
+	// Root is the folder holding the conversations file: "" for the archive root, otherwise ending in "/".
+	Root string
+	// Member is the owning workspace member: the email from the folder's user.json, else the folder name.
+	Member string
+}
+
+// FolderName returns the last segment of the origin folder, or "" at the archive root.
+func (origin Origin) FolderName() string {
+	if origin.Root == "" {
+		return ""
+	}
+	return path.Base(origin.Root)
+}
+
+// FindConversationsJSON returns the entry names of every conversations.json in the export, sorted, so a
+// workspace export yields one per member folder.
+func (archive *Archive) FindConversationsJSON() ([]string, error) {
+	found := archive.findEntries(conversationsFileName)
+	if len(found) == 0 {
+		return nil, errors.New("conversations.json not found in archive")
+	}
+	return found, nil
+}
+
+func (archive *Archive) findEntries(baseName string) []string {
+	var found []string
+	for _, name := range archive.names {
+		lowerName := strings.ToLower(name)
+		if lowerName == baseName || strings.HasSuffix(lowerName, "/"+baseName) {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// EachConversation decodes each conversations.json one conversation at a time and hands each to visit with
+// its origin, so no whole array is ever held in memory. Each conversation is handed over serialized, for the
+// caller to decode. Exports without conversations.json fall back to the data embedded in chat.html, and Gemini
+// Takeout archives are converted to the same shape. A non-nil error from visit stops the iteration and is returned.
+// Archives opened with a record cache cut the conversations of a conversations.json read before straight out of
+// it by position, and remember the positions of one read for the first time. Once ctx is done the iteration
+// stops with ctx's error.
+func (archive *Archive) EachConversation(ctx context.Context, visitConversation func(serialized []byte, origin Origin) error) error {
+	visit := func(serialized []byte, origin Origin) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return visitConversation(serialized, origin)
+	}
+	if archive.source == SourceGemini {
+		return archive.eachGeminiConversation(visit)
+	}
+	entries, findErr := archive.FindConversationsJSON()
+	embedded := findErr != nil
+	if embedded {
+		entries = archive.findEntries(chatHTMLFileName)
+		if len(entries) == 0 {
+			return errors.New("neither conversations.json nor chat.html found in archive")
+		}
+	}
+	for _, name := range entries {
+		if cached, found := archive.cachedEntry(name, embedded); found {
+			visitOrigin := func(serialized []byte) error { return visit(serialized, cached.Origin) }
+			if replayErr := archive.replayEntry(name, cached.Records, visitOrigin); replayErr != nil {
+				return replayErr
+			}
+			continue
+		}
+		origin := archive.originOf(name)
+		var records []recordPosition
+		visitOrigin := func(serialized []byte, position recordPosition) error {
+			records = append(records, position)
+			return visit(serialized, origin)
+		}
+		if decodeErr := archive.decodeEntry(name, embedded, visitOrigin); decodeErr != nil {
+			return decodeErr
+		}
+		if archive.records != nil && !embedded {
+			archive.records.remember(name, origin, records)
+		}
+	}
+	if archive.records != nil {
+		return archive.records.save()
+	}
+	return nil
+}
+
+// cachedEntry returns what the record cache knows of a conversations.json; chat.html is never cached, as its
+// records are located by scanning the page.
+func (archive *Archive) cachedEntry(name string, embedded bool) (cachedEntry, bool) {
+	if archive.records == nil || embedded {
+		return cachedEntry{}, false
+	}
+	cached, found := archive.records.Entries[name]
+	return cached, found
+}
+
+func (archive *Archive) replayEntry(name string, records []recordPosition, visit func(serialized []byte) error) error {
+	reader, openErr := archive.Open(name)
+	if openErr != nil {
+		return openErr
+	}
+	defer reader.Close()
+	return replayRecords(reader, name, records, visit)
+}
+
+// ConversationBytes returns the size of the entries EachConversation reads, for reporting progress through
+// them; known is false when the format does not record an entry's size up front.
+func (archive *Archive) ConversationBytes() (int64, bool) {
+	var entries []string
+	if archive.source == SourceGemini {
+		name, findErr := archive.findGeminiActivity()
+		if findErr != nil {
+			return 0, false
+		}
+		entries = []string{name}
+	} else if found, findErr := archive.FindConversationsJSON(); findErr == nil {
+		entries = found
+	} else {
+		entries = archive.findEntries(chatHTMLFileName)
+	}
+	var total int64
+	for _, name := range entries {
+		size, known := archive.Size(name)
+		if !known {
+			return 0, false
+		}
+		total += size
+	}
+	return total, len(entries) > 0
+}
+
+// SupportingEntries returns the entries other than the ones conversations are read from: attachments, account
+// metadata, and the rest of the export. Gemini Takeout archives have none, as their conversations reference no files.
+func (archive *Archive) SupportingEntries() []string {
+	if archive.source == SourceGemini {
+		return nil
+	}
+	var entries []string
+	for _, name := range archive.names {
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		baseName := strings.ToLower(path.Base(name))
+		if baseName == conversationsFileName || baseName == chatHTMLFileName {
+			continue
+		}
+		entries = append(entries, name)
+	}
+	return entries
+}
+
+func (archive *Archive) decodeEntry(name string, embedded bool, visit func(serialized []byte, position recordPosition) error) error {
+	reader, openErr := archive.Open(name)
+	if openErr != nil {
+		return openErr
+	}
+	defer reader.Close()
+
+	var source io.Reader = reader
+	if embedded {
+		located, locateErr := chatHTMLConversations(reader)
+		if locateErr != nil {
+			return locateErr
+		}
+		source = located
+	}
+	return decodeConversations(source, name, visit)
+}
+
+// originOf derives the origin of a conversations file from its folder and that folder's user.json.
+func (archive *Archive) originOf(entryName string) Origin {
+	root := ""
+	if slash := strings.LastIndex(entryName, "/"); slash >= 0 {
+		root = entryName[:slash+1]
+	}
+	origin := Origin{Root: root}
+	origin.Member = origin.FolderName()
+	if email := archive.readUserEmail(root + userFileName); email != "" {
+		origin.Member = email
+	}
+	return origin
+}
+
+func (archive *Archive) readUserEmail(entryName string) string {
+	var user struct {
+		Email string `json:"email"`
+	}
+	if !archive.decodeOptional(entryName, &user) {
+		return ""
+	}
+	return user.Email
+}
+
+// decodeConversations streams the conversations of a JSON array, or of an object keyed by conversation id as
+// some export variants store them. Keyed conversations missing an id take it from their key. Records are
+// decoded and visited one at a time, so memory stays proportional to the largest conversation rather than
+// to the file, which can run to hundreds of megabytes. Each record is visited with its position in source; a
+// raw value carries no surrounding whitespace, so it ends where the decoder stopped reading.
+func decodeConversations(source io.Reader, name string, visit func(serialized []byte, position recordPosition) error) error {
+	decoder := json.NewDecoder(source)
+	opening, tokenErr := decoder.Token()
+	if tokenErr != nil || (opening != json.Delim('[') && opening != json.Delim('{')) {
+		return fmt.Errorf("parse %s: expected a JSON array or object of conversations", name)
+	}
+	keyed := opening == json.Delim('{')
+	for decoder.More() {
+		conversationKey := ""
+		if keyed {
+			keyToken, keyErr := decoder.Token()
+			if keyErr != nil {
+				return fmt.Errorf("parse %s: %w", name, keyErr)
+			}
+			conversationKey, _ = keyToken.(string)
+		}
+		var serialized json.RawMessage
+		if decodeErr := decoder.Decode(&serialized); decodeErr != nil {
+			return fmt.Errorf("parse %s: %w", name, decodeErr)
+		}
+		serialized = bytes.TrimSpace(serialized)
+		if len(serialized) == 0 || serialized[0] != '{' {
+			continue
+		}
+		position := recordPosition{Offset: decoder.InputOffset() - int64(len(serialized)), Length: int64(len(serialized)), Key: conversationKey}
+		if conversationKey != "" {
+			serialized = withConversationID(serialized, conversationKey)
+		}
+		if visitErr := visit(serialized, position); visitErr != nil {
+			return visitErr
+		}
+	}
+	if _, tokenErr := decoder.Token(); tokenErr != nil {
+		return fmt.Errorf("parse %s: %w", name, tokenErr)
+	}
+	return nil
+}
+
+// withConversationID adds conversation_id to a serialized conversation that carries neither it nor id.
+func withConversationID(serialized []byte, conversationID string) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(serialized, &fields) != nil {
+		return serialized
+	}
+	for _, key := range []string{"conversation_id", "id"} {
+		if value, present := fields[key]; present && string(value) != "null" {
+			return serialized
+		}
+	}
+	encodedID, _ := json.Marshal(conversationID)
+	fields["conversation_id"] = encodedID
+	encoded, encodeErr := json.Marshal(fields)
+	if encodeErr != nil {
+		return serialized
+	}
+	return encoded
+}
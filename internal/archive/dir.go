@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// openDirArchive lists every regular file below rootPath, keyed by its slash-separated path relative to rootPath,
+// so an unzipped export yields the same entry names as the ZIP it came from.
+func openDirArchive(rootPath string) (*Archive, error) {
+	var names []string
+	sizes := make(map[string]int64)
+	walkErr := filepath.WalkDir(rootPath, func(path string, entry fs.DirEntry, entryErr error) error {
+		if entryErr != nil {
+			return entryErr
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relativePath, relErr := filepath.Rel(rootPath, path)
+		if relErr != nil {
+			return relErr
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return infoErr
+		}
+		names = append(names, filepath.ToSlash(relativePath))
+		sizes[filepath.ToSlash(relativePath)] = info.Size()
+		return nil
+	})
+	if walkErr != nil {
+		return nil, fmt.Errorf("read export folder: %w", walkErr)
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(rootPath, filepath.FromSlash(name)))
+	}
+	archive := newArchive(names, open, nil)
+	archive.sizes = sizes
+	return archive, nil
+}
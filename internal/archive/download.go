@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	partialDownloadSuffix = ".part"
+	downloadKeyLength     = 16
+	defaultDownloadName   = "export.zip"
+	connectTimeout        = 30 * time.Second
+	responseHeaderTimeout = time.Minute
+	idleConnectionTimeout = 90 * time.Second
+)
+
+// IsRemote reports whether an export path is an http(s) URL rather than a local file.
+func IsRemote(exportPath string) bool {
+	lowerPath := strings.ToLower(exportPath)
+	return strings.HasPrefix(lowerPath, "https://") || strings.HasPrefix(lowerPath, "http://")
+}
+
+// newHTTPClient returns a client that gives up on servers that do not connect or start answering in time. It
+// sets no deadline on the whole transfer, as an export can rightly take long to download; callers bound that
+// through the request context.
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: connectTimeout}).DialContext,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		IdleConnTimeout:       idleConnectionTimeout,
+		ForceAttemptHTTP2:     true,
+	}}
+}
+
+// Download fetches an export URL into cacheDir and returns the local path. A finished download is reused,
+// and an interrupted one resumes from where it stopped when the server honours range requests. Cancelling ctx
+// stops the transfer, keeping what was received for the next attempt to resume from. A partial file the
+// server reports as already complete is taken as the whole export.
+func Download(ctx context.Context, exportURL string, cacheDir string) (string, error) {
+	parsedURL, parseErr := url.Parse(exportURL)
+	if parseErr != nil {
+		return "", fmt.Errorf("parse export url: %w", parseErr)
+	}
+	if cacheDir == "" {
+		return "", fmt.Errorf("no download cache folder for %s", exportURL)
+	}
+	if mkErr := os.MkdirAll(cacheDir, 0o755); mkErr != nil {
+		return "", fmt.Errorf("create download cache %q: %w", cacheDir, mkErr)
+	}
+	targetPath := filepath.Join(cacheDir, downloadFileName(exportURL, parsedURL))
+	if _, statErr := os.Stat(targetPath); statErr == nil {
+		return targetPath, nil
+	}
+
+	partialPath := targetPath + partialDownloadSuffix
+	var resumeOffset int64
+	if info, statErr := os.Stat(partialPath); statErr == nil {
+		resumeOffset = info.Size()
+	}
+	request, requestErr := http.NewRequestWithContext(ctx, http.MethodGet, exportURL, nil)
+	if requestErr != nil {
+		return "", fmt.Errorf("download export: %w", requestErr)
+	}
+	if resumeOffset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeOffset))
+	}
+	response, responseErr := newHTTPClient().Do(request)
+	if responseErr != nil {
+		return "", fmt.Errorf("download export: %w", responseErr)
+	}
+	defer response.Body.Close()
+
+	openFlags := os.O_WRONLY | os.O_CREATE
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		openFlags |= os.O_APPEND
+	case http.StatusOK:
+		openFlags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		return targetPath, finishDownload(partialPath, targetPath)
+	default:
+		return "", fmt.Errorf("download export: unexpected status %s", response.Status)
+	}
+	partialFile, openErr := os.OpenFile(partialPath, openFlags, 0o644)
+	if openErr != nil {
+		return "", fmt.Errorf("download export: %w", openErr)
+	}
+	if _, copyErr := io.Copy(partialFile, response.Body); copyErr != nil {
+		partialFile.Close()
+		return "", fmt.Errorf("download export (re-run to resume): %w", copyErr)
+	}
+	if closeErr := partialFile.Close(); closeErr != nil {
+		return "", fmt.Errorf("download export: %w", closeErr)
+	}
+	return targetPath, finishDownload(partialPath, targetPath)
+}
+
+// downloadFileName keys the cached file by a hash of the full URL, keeping the URL's base name so the
+// archive format can still be told from its extension.
+func downloadFileName(exportURL string, parsedURL *url.URL) string {
+	digest := sha256.Sum256([]byte(exportURL))
+	baseName := path.Base(parsedURL.Path)
+	if baseName == "" || baseName == "." || baseName == "/" {
+		baseName = defaultDownloadName
+	}
+	return hex.EncodeToString(digest[:])[:downloadKeyLength] + "-" + baseName
+}
+
+func finishDownload(partialPath string, targetPath string) error {
+	if renameErr := os.Rename(partialPath, targetPath); renameErr != nil {
+		return fmt.Errorf("download export: %w", renameErr)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testExportBody = "exported conversations"
+
+var serveModTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/missing.zip":
+			http.NotFound(writer, request)
+		default:
+			http.ServeContent(writer, request, "export.zip", serveModTime, strings.NewReader(testExportBody))
+		}
+	}))
+	defer server.Close()
+	testCases := []struct {
+		name            string
+		path            string
+		partial         string
+		finished        string
+		expectErr       bool
+		expectedContent string
+	}{
+		{name: "fresh download", path: "/export.zip", expectedContent: testExportBody},
+		{name: "resumes a partial download", path: "/export.zip", partial: testExportBody[:8], expectedContent: testExportBody},
+		{name: "complete partial download", path: "/export.zip", partial: testExportBody, expectedContent: testExportBody},
+		{name: "reuses a finished download", path: "/export.zip", finished: "cached", expectedContent: "cached"},
+		{name: "unexpected status", path: "/missing.zip", expectErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			exportURL := server.URL + testCase.path
+			parsedURL, _ := url.Parse(exportURL)
+			targetPath := filepath.Join(cacheDir, downloadFileName(exportURL, parsedURL))
+			if testCase.partial != "" {
+				writeTestFile(t, targetPath+partialDownloadSuffix, testCase.partial)
+			}
+			if testCase.finished != "" {
+				writeTestFile(t, targetPath, testCase.finished)
+			}
+			downloaded, downloadErr := Download(context.Background(), exportURL, cacheDir)
+			if testCase.expectErr {
+				if downloadErr == nil {
+					t.Fatal("Download succeeded, want an error")
+				}
+				return
+			}
+			if downloadErr != nil {
+				t.Fatalf("Download: %v", downloadErr)
+			}
+			content, readErr := os.ReadFile(downloaded)
+			if readErr != nil {
+				t.Fatalf("ReadFile: %v", readErr)
+			}
+			if string(content) != testCase.expectedContent {
+				t.Errorf("content = %q, want %q", content, testCase.expectedContent)
+			}
+		})
+	}
+}
+
+func TestDownloadStopsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-request.Context().Done()
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, downloadErr := Download(ctx, server.URL+"/export.zip", t.TempDir()); !errors.Is(downloadErr, context.Canceled) {
+		t.Fatalf("Download error = %v, want %v", downloadErr, context.Canceled)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if writeErr := os.WriteFile(path, []byte(content), 0o600); writeErr != nil {
+		t.Fatalf("WriteFile: %v", writeErr)
+	}
+}
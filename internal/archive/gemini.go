@@ -0,0 +1,169 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	geminiActivityFileName = "myactivity.json"
+	geminiActivityFolder   = "gemini apps/"
+	geminiPromptPrefix     = "Prompted "
+	geminiIDPrefix         = "gemini-"
+	geminiIDLength         = 16
+	geminiTitleLength      = 80
+	geminiModelSlug        = "gemini"
+)
+
+var (
+	reHTMLBreak = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6]|pre|tr)>`)
+	reHTMLTag   = regexp.MustCompile(`<[^>]+>`)
+	reBlankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// geminiActivity is one entry of the Gemini Apps MyActivity.json in a Google Takeout archive: a single prompt
+// and the HTML of the reply.
+type geminiActivity struct {
+	Title         string   `json:"title"`
+	Time          string   `json:"time"`
+	Products      []string `json:"products"`
+	SafeHTMLItems []struct {
+		HTML string `json:"html"`
+	} `json:"safeHtmlItem"`
+}
+
+// findGeminiActivity returns the Takeout entry holding Gemini Apps activity.
+func (archive *Archive) findGeminiActivity() (string, error) {
+	for _, name := range archive.names {
+		lowerName := strings.ToLower(name)
+		if strings.HasSuffix(lowerName, geminiActivityFolder+geminiActivityFileName) {
+			return name, nil
+		}
+	}
+	return "", errors.New("Gemini Apps/MyActivity.json not found in Takeout archive")
+}
+
+// eachGeminiConversation converts Gemini activity into conversation records shaped like the ChatGPT export, so
+// every filter and renderer applies unchanged. Takeout does not group prompts into chats, so each prompt and
+// its reply become one two-message conversation. Activity is decoded one entry at a time, as conversations are.
+func (archive *Archive) eachGeminiConversation(visit func(serialized []byte, origin Origin) error) error {
+	name, findErr := archive.findGeminiActivity()
+	if findErr != nil {
+		return findErr
+	}
+	reader, openErr := archive.Open(name)
+	if openErr != nil {
+		return openErr
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	if opening, tokenErr := decoder.Token(); tokenErr != nil || opening != json.Delim('[') {
+		return fmt.Errorf("parse %s: expected a JSON array of activity", name)
+	}
+	origin := archive.originOf(name)
+	for decoder.More() {
+		var activity geminiActivity
+		if decodeErr := decoder.Decode(&activity); decodeErr != nil {
+			return fmt.Errorf("parse %s: %w", name, decodeErr)
+		}
+		record, converted := geminiRecord(activity)
+		if !converted {
+			continue
+		}
+		serialized, encodeErr := json.Marshal(record)
+		if encodeErr != nil {
+			return fmt.Errorf("encode %s: %w", name, encodeErr)
+		}
+		if visitErr := visit(serialized, origin); visitErr != nil {
+			return visitErr
+		}
+	}
+	if _, tokenErr := decoder.Token(); tokenErr != nil {
+		return fmt.Errorf("parse %s: %w", name, tokenErr)
+	}
+	return nil
+}
+
+func geminiRecord(activity geminiActivity) (map[string]any, bool) {
+	prompt, prompted := strings.CutPrefix(activity.Title, geminiPromptPrefix)
+	if !prompted {
+		return nil, false
+	}
+	created, timeErr := time.Parse(time.RFC3339Nano, activity.Time)
+	if timeErr != nil {
+		return nil, false
+	}
+	var replies []string
+	for _, item := range activity.SafeHTMLItems {
+		if reply := htmlToText(item.HTML); reply != "" {
+			replies = append(replies, reply)
+		}
+	}
+	digest := sha256.Sum256([]byte(activity.Time + "\x00" + activity.Title))
+	conversationID := geminiIDPrefix + hex.EncodeToString(digest[:])[:geminiIDLength]
+	epoch := float64(created.UnixNano()) / float64(time.Second)
+
+	mapping := map[string]any{}
+	userNodeID, assistantNodeID := conversationID+"-1", conversationID+"-2"
+	userNode := geminiNode(userNodeID, "user", prompt, epoch, nil)
+	mapping[userNodeID] = userNode
+	currentNode := userNodeID
+	if len(replies) > 0 {
+		mapping[assistantNodeID] = geminiNode(assistantNodeID, "assistant", strings.Join(replies, "\n\n"), epoch, userNodeID)
+		userNode["children"] = []any{assistantNodeID}
+		currentNode = assistantNodeID
+	}
+	return map[string]any{
+		"conversation_id":    conversationID,
+		"title":              truncateTitle(prompt),
+		"create_time":        epoch,
+		"update_time":        epoch,
+		"current_node":       currentNode,
+		"default_model_slug": geminiModelSlug,
+		"mapping":            mapping,
+	}, true
+}
+
+func geminiNode(nodeID string, role string, text string, epoch float64, parent any) map[string]any {
+	metadata := map[string]any{}
+	if role == "assistant" {
+		metadata["model_slug"] = geminiModelSlug
+	}
+	return map[string]any{
+		"id":       nodeID,
+		"parent":   parent,
+		"children": []any{},
+		"message": map[string]any{
+			"id":          nodeID,
+			"author":      map[string]any{"role": role},
+			"create_time": epoch,
+			"content":     map[string]any{"content_type": "text", "parts": []any{text}},
+			"recipient":   "all",
+			"metadata":    metadata,
+		},
+	}
+}
+
+// htmlToText flattens Takeout's reply HTML into plain text, keeping paragraph breaks.
+func htmlToText(fragment string) string {
+	withBreaks := reHTMLBreak.ReplaceAllString(fragment, "\n")
+	plain := html.UnescapeString(reHTMLTag.ReplaceAllString(withBreaks, ""))
+	return strings.TrimSpace(reBlankRuns.ReplaceAllString(plain, "\n\n"))
+}
+
+func truncateTitle(prompt string) string {
+	title := strings.Join(strings.Fields(prompt), " ")
+	runes := []rune(title)
+	if len(runes) <= geminiTitleLength {
+		return title
+	}
+	return string(runes[:geminiTitleLength-1]) + "…"
+}
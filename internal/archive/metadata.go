@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"encoding/json"
+)
+
+const (
+	feedbackFileName = "message_feedback.json"
+	sharedFileName   = "shared_conversations.json"
+)
+
+// accountFields are the user.json fields copied into sidecars; everything else (phone number and the like)
+// stays out of the extracted folders.
+var accountFields = []string{"id", "email", "chatgpt_plus_user"}
+
+// Metadata holds the account-level files that sit next to a conversations.json, indexed by conversation id.
+type Metadata struct {
+	Account  map[string]any
+	Feedback map[string][]map[string]any
+	Shared   map[string][]map[string]any
+}
+
+// Metadata loads user.json, message_feedback.json, and shared_conversations.json from the origin folder
+// root, reading them once per archive. Missing or malformed files leave their part empty.
+func (archive *Archive) Metadata(root string) Metadata {
+	archive.metadataMutex.Lock()
+	defer archive.metadataMutex.Unlock()
+	if cached, loaded := archive.metadata[root]; loaded {
+		return cached
+	}
+	metadata := Metadata{
+		Account:  archive.readAccount(root + userFileName),
+		Feedback: archive.readByConversation(root + feedbackFileName),
+		Shared:   archive.readByConversation(root + sharedFileName),
+	}
+	if archive.metadata == nil {
+		archive.metadata = make(map[string]Metadata)
+	}
+	archive.metadata[root] = metadata
+	return metadata
+}
+
+// Sidecar returns the metadata relevant to one conversation, or nil when there is none.
+func (metadata Metadata) Sidecar(conversationID string) map[string]any {
+	sidecar := make(map[string]any)
+	if len(metadata.Account) > 0 {
+		sidecar["account"] = metadata.Account
+	}
+	if feedback := metadata.Feedback[conversationID]; len(feedback) > 0 {
+		sidecar["feedback"] = feedback
+	}
+	if shares := metadata.Shared[conversationID]; len(shares) > 0 {
+		sidecar["shared"] = shares
+	}
+	if len(sidecar) == 0 {
+		return nil
+	}
+	return sidecar
+}
+
+func (archive *Archive) readAccount(entryName string) map[string]any {
+	var user map[string]any
+	if !archive.decodeOptional(entryName, &user) {
+		return nil
+	}
+	account := make(map[string]any)
+	for _, field := range accountFields {
+		if value, present := user[field]; present {
+			account[field] = value
+		}
+	}
+	return account
+}
+
+func (archive *Archive) readByConversation(entryName string) map[string][]map[string]any {
+	var entries []map[string]any
+	if !archive.decodeOptional(entryName, &entries) {
+		return nil
+	}
+	byConversation := make(map[string][]map[string]any)
+	for _, entry := range entries {
+		conversationID, _ := entry["conversation_id"].(string)
+		if conversationID != "" {
+			byConversation[conversationID] = append(byConversation[conversationID], entry)
+		}
+	}
+	return byConversation
+}
+
+// decodeOptional decodes a JSON entry into target, reporting false when the entry is absent or malformed.
+func (archive *Archive) decodeOptional(entryName string, target any) bool {
+	reader, openErr := archive.openEntry(entryName)
+	if openErr != nil {
+		return false
+	}
+	defer reader.Close()
+	return json.NewDecoder(reader).Decode(target) == nil
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package archive
+
+import "os"
+
+// mapFile is unavailable on this platform, so stored entries are read through the file instead.
+func mapFile(*os.File, int64) ([]byte, error) {
+	return nil, errMapUnsupported
+}
+
+func unmapFile([]byte) error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package archive
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"openai_extract/internal/utils"
+)
+
+const (
+	recordCacheVersion = 2
+	// replayBufferSize is the read-ahead used when cutting cached records out of a conversations file.
+	replayBufferSize = 1 << 20
+)
+
+// recordPosition locates one conversation in a decompressed conversations.json.
+type recordPosition struct {
+	Offset int64
+	Length int64
+	// Key is the conversation id the record was stored under, in exports keyed by id rather than an array.
+	Key string
+}
+
+// cachedEntry is what a previous run learned about one conversations.json: its origin and where its records are.
+type cachedEntry struct {
+	Origin  Origin
+	Records []recordPosition
+}
+
+// recordCache remembers, for one export file, where every conversation sits in each of its conversations.json
+// entries, so later runs against the unchanged file cut the records out by position instead of parsing the JSON
+// again. It is kept in <cache folder>/<size>-<modification time>.gob and holds the SHA-256 of the export, which
+// must match before its positions are trusted.
+type recordCache struct {
+	path       string
+	exportPath string
+	Version    int
+	Checksum   string
+	Entries    map[string]cachedEntry
+	dirty      bool
+}
+
+// loadRecordCache reads the record cache kept in cacheDir for the export file at exportPath. The cache is found
+// by the size and modification time of the file, which is checksummed only when such a cache exists. A missing,
+// unreadable, outdated, or mismatched cache yields an empty one, filled in as conversations are read.
+func loadRecordCache(cacheDir string, exportPath string) (*recordCache, error) {
+	info, statErr := os.Stat(exportPath)
+	if statErr != nil {
+		return nil, fmt.Errorf("stat export: %w", statErr)
+	}
+	cacheName := fmt.Sprintf("%d-%d.gob", info.Size(), info.ModTime().UnixNano())
+	cache := &recordCache{path: filepath.Join(cacheDir, cacheName), exportPath: exportPath, Version: recordCacheVersion, Entries: make(map[string]cachedEntry)}
+	file, openErr := os.Open(cache.path)
+	if errors.Is(openErr, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if openErr != nil {
+		return nil, fmt.Errorf("open record cache %q: %w", cache.path, openErr)
+	}
+	defer file.Close()
+	var stored recordCache
+	if decodeErr := gob.NewDecoder(file).Decode(&stored); decodeErr != nil {
+		return cache, nil
+	}
+	if stored.Version != recordCacheVersion || stored.Entries == nil {
+		return cache, nil
+	}
+	checksum, checksumErr := fileChecksum(exportPath)
+	if checksumErr != nil {
+		return nil, checksumErr
+	}
+	cache.Checksum = checksum
+	if stored.Checksum == checksum {
+		cache.Entries = stored.Entries
+	}
+	return cache, nil
+}
+
+func fileChecksum(path string) (string, error) {
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		return "", fmt.Errorf("checksum export: %w", openErr)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, copyErr := io.Copy(hash, file); copyErr != nil {
+		return "", fmt.Errorf("checksum export: %w", copyErr)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// remember records the positions of every conversation of a fully read conversations.json.
+func (cache *recordCache) remember(name string, origin Origin, records []recordPosition) {
+	cache.Entries[name] = cachedEntry{Origin: origin, Records: records}
+	cache.dirty = true
+}
+
+// save writes the cache to disk when entries were added since it was loaded, checksumming the export first
+// when loading did not.
+func (cache *recordCache) save() error {
+	if !cache.dirty {
+		return nil
+	}
+	if cache.Checksum == "" {
+		checksum, checksumErr := fileChecksum(cache.exportPath)
+		if checksumErr != nil {
+			return checksumErr
+		}
+		cache.Checksum = checksum
+	}
+	if writeErr := utils.WriteFileAtomicStream(cache.path, func(destination io.Writer) error {
+		return gob.NewEncoder(destination).Encode(cache)
+	}); writeErr != nil {
+		return fmt.Errorf("save record cache: %w", writeErr)
+	}
+	cache.dirty = false
+	return nil
+}
+
+// replayRecords hands visit each record at the cached positions of source, without parsing the JSON around them.
+func replayRecords(source io.Reader, name string, records []recordPosition, visit func(serialized []byte) error) error {
+	reader := bufio.NewReaderSize(source, replayBufferSize)
+	position := int64(0)
+	for _, record := range records {
+		if record.Offset < position {
+			return fmt.Errorf("read %s: cached record positions overlap", name)
+		}
+		if _, skipErr := reader.Discard(int(record.Offset - position)); skipErr != nil {
+			return fmt.Errorf("read %s: %w", name, skipErr)
+		}
+		serialized := make([]byte, record.Length)
+		if _, readErr := io.ReadFull(reader, serialized); readErr != nil {
+			return fmt.Errorf("read %s: %w", name, readErr)
+		}
+		position = record.Offset + record.Length
+		if record.Key != "" {
+			serialized = withConversationID(serialized, record.Key)
+		}
+		if visitErr := visit(serialized); visitErr != nil {
+			return visitErr
+		}
+	}
+	return nil
+}
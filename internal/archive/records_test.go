@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadRecordCache(t *testing.T) {
+	const exportContent = "export bytes"
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name          string
+		change        func(t *testing.T, exportPath string)
+		expectEntries bool
+	}{
+		{name: "unchanged export", change: func(t *testing.T, exportPath string) {}, expectEntries: true},
+		{
+			name: "same size and time, other content",
+			change: func(t *testing.T, exportPath string) {
+				writeExport(t, exportPath, "EXPORT BYTES", modified)
+			},
+		},
+		{
+			name: "touched export",
+			change: func(t *testing.T, exportPath string) {
+				writeExport(t, exportPath, exportContent, modified.Add(time.Second))
+			},
+		},
+		{
+			name: "grown export",
+			change: func(t *testing.T, exportPath string) {
+				writeExport(t, exportPath, exportContent+"!", modified)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			folder := t.TempDir()
+			cacheDir := filepath.Join(folder, "cache")
+			exportPath := filepath.Join(folder, "export.zip")
+			writeExport(t, exportPath, exportContent, modified)
+			cache, loadErr := loadRecordCache(cacheDir, exportPath)
+			if loadErr != nil {
+				t.Fatalf("loadRecordCache: %v", loadErr)
+			}
+			cache.remember("conversations.json", Origin{}, []recordPosition{{Offset: 1, Length: 2}})
+			if saveErr := cache.save(); saveErr != nil {
+				t.Fatalf("save: %v", saveErr)
+			}
+			testCase.change(t, exportPath)
+			reloaded, reloadErr := loadRecordCache(cacheDir, exportPath)
+			if reloadErr != nil {
+				t.Fatalf("loadRecordCache: %v", reloadErr)
+			}
+			if _, found := reloaded.Entries["conversations.json"]; found != testCase.expectEntries {
+				t.Errorf("cached entries found = %v, want %v", found, testCase.expectEntries)
+			}
+		})
+	}
+}
+
+func writeExport(t *testing.T, exportPath string, content string, modified time.Time) {
+	t.Helper()
+	if writeErr := os.WriteFile(exportPath, []byte(content), 0o644); writeErr != nil {
+		t.Fatalf("WriteFile: %v", writeErr)
+	}
+	if timesErr := os.Chtimes(exportPath, modified, modified); timesErr != nil {
+		t.Fatalf("Chtimes: %v", timesErr)
+	}
+}
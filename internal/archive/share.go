@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	shareUserAgent    = "Mozilla/5.0 (compatible; openai_extract)"
+	maxShareNesting   = 12
+	sharePathSegment  = "share"
+	shareFetchTimeout = time.Minute
+)
+
+var (
+	shareHosts = map[string]struct{}{"chatgpt.com": {}, "www.chatgpt.com": {}, "chat.openai.com": {}}
+
+	// reJSONScript captures the bodies of <script type="application/json"> tags, where Next.js ships page data.
+	reJSONScript = regexp.MustCompile(`(?is)<script[^>]*type="application/json"[^>]*>(.*?)</script>`)
+)
+
+// IsShareURL reports whether exportPath is a public ChatGPT shared-conversation link.
+func IsShareURL(exportPath string) bool {
+	parsedURL, parseErr := url.Parse(exportPath)
+	if parseErr != nil {
+		return false
+	}
+	if _, known := shareHosts[strings.ToLower(parsedURL.Host)]; !known {
+		return false
+	}
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	return len(segments) >= 2 && segments[len(segments)-2] == sharePathSegment
+}
+
+// FetchShare downloads a shared-conversation page and wraps the conversation embedded in it as a one-conversation
+// archive, so it goes through the same pipeline as an export. The fetch gives up after a minute or once ctx
+// is done.
+func FetchShare(ctx context.Context, shareURL string) (*Archive, error) {
+	ctx, cancel := context.WithTimeout(ctx, shareFetchTimeout)
+	defer cancel()
+	request, requestErr := http.NewRequestWithContext(ctx, http.MethodGet, shareURL, nil)
+	if requestErr != nil {
+		return nil, fmt.Errorf("fetch share: %w", requestErr)
+	}
+	request.Header.Set("User-Agent", shareUserAgent)
+	response, responseErr := newHTTPClient().Do(request)
+	if responseErr != nil {
+		return nil, fmt.Errorf("fetch share: %w", responseErr)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch share: unexpected status %s", response.Status)
+	}
+	page, readErr := io.ReadAll(response.Body)
+	if readErr != nil {
+		return nil, fmt.Errorf("fetch share: %w", readErr)
+	}
+	record, parseErr := ParseSharePage(page)
+	if parseErr != nil {
+		return nil, fmt.Errorf("%s: %w", shareURL, parseErr)
+	}
+	if record["conversation_id"] == nil && record["id"] == nil {
+		record["conversation_id"] = path.Base(strings.TrimRight(shareURL, "/"))
+	}
+	encoded, encodeErr := json.Marshal([]any{record})
+	if encodeErr != nil {
+		return nil, fmt.Errorf("encode share: %w", encodeErr)
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		if name != conversationsFileName {
+			return nil, errNoSuchEntry
+		}
+		return io.NopCloser(bytes.NewReader(encoded)), nil
+	}
+	return newArchive([]string{conversationsFileName}, open, nil), nil
+}
+
+// ParseSharePage finds the conversation record embedded in a share page: the first JSON object in the page
+// data that carries a mapping tree.
+func ParseSharePage(page []byte) (map[string]any, error) {
+	for _, match := range reJSONScript.FindAllSubmatch(page, -1) {
+		var data any
+		if json.Unmarshal(bytes.TrimSpace(match[1]), &data) != nil {
+			continue
+		}
+		if record := findConversationObject(data, 0); record != nil {
+			return record, nil
+		}
+	}
+	return nil, errors.New("share page does not embed conversation data")
+}
+
+func findConversationObject(value any, depth int) map[string]any {
+	if depth > maxShareNesting {
+		return nil
+	}
+	switch typed := value.(type) {
+	case map[string]any:
+		if mapping, hasMapping := typed["mapping"].(map[string]any); hasMapping && len(mapping) > 0 {
+			return typed
+		}
+		for _, child := range typed {
+			if found := findConversationObject(child, depth+1); found != nil {
+				return found
+			}
+		}
+	case []any:
+		for _, child := range typed {
+			if found := findConversationObject(child, depth+1); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchShare(t *testing.T) {
+	record := `{"title":"shared","mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":["hello"]}}}},"current_node":"m1"}`
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprintf(writer, `<html><script type="application/json">{"props":{"pageProps":{"serverResponse":{"data":%s}}}}</script></html>`, record)
+	}))
+	defer server.Close()
+	shared, fetchErr := FetchShare(context.Background(), server.URL+"/share/abc")
+	if fetchErr != nil {
+		t.Fatalf("FetchShare: %v", fetchErr)
+	}
+	defer shared.Close()
+	var conversationIDs []string
+	scanErr := shared.EachConversation(context.Background(), func(serialized []byte, origin Origin) error {
+		var fields struct {
+			ConversationID string `json:"conversation_id"`
+		}
+		if decodeErr := json.Unmarshal(serialized, &fields); decodeErr != nil {
+			return decodeErr
+		}
+		conversationIDs = append(conversationIDs, fields.ConversationID)
+		return nil
+	})
+	if scanErr != nil {
+		t.Fatalf("EachConversation: %v", scanErr)
+	}
+	if len(conversationIDs) != 1 || conversationIDs[0] != "abc" {
+		t.Errorf("conversation ids = %q, want [abc]", conversationIDs)
+	}
+}
+
+func TestFetchShareStopsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-request.Context().Done()
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, fetchErr := FetchShare(ctx, server.URL+"/share/abc"); !errors.Is(fetchErr, context.Canceled) {
+		t.Fatalf("FetchShare error = %v, want %v", fetchErr, context.Canceled)
+	}
+}
@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const spillFolderPattern = "openai_extract-spill-*"
+
+// entryStore holds decompressed archive entries that cannot be re-read lazily from their source. Entries stay
+// in memory until the budget is used up; later ones are spilled to files in a temporary folder.
+// A budget of zero or less keeps everything in memory.
+type entryStore struct {
+	budget  int64
+	used    int64
+	memory  map[string][]byte
+	spilled map[string]string
+	tempDir string
+}
+
+func newEntryStore(budget int64) *entryStore {
+	return &entryStore{budget: budget, memory: make(map[string][]byte), spilled: make(map[string]string)}
+}
+
+// put stores the content of one entry; size is its expected length, used to decide where it goes.
+func (store *entryStore) put(name string, content io.Reader, size int64) error {
+	if store.budget <= 0 || store.used+size <= store.budget {
+		contentBytes, readErr := io.ReadAll(content)
+		if readErr != nil {
+			return readErr
+		}
+		store.used += int64(len(contentBytes))
+		store.memory[name] = contentBytes
+		return nil
+	}
+	return store.spill(name, content)
+}
+
+// spill copies the content of one entry into the temporary folder, whatever the budget, so it is never held
+// in memory as a whole.
+func (store *entryStore) spill(name string, content io.Reader) error {
+	if store.tempDir == "" {
+		tempDir, tempErr := os.MkdirTemp("", spillFolderPattern)
+		if tempErr != nil {
+			return fmt.Errorf("create spill folder: %w", tempErr)
+		}
+		store.tempDir = tempDir
+	}
+	spillPath := filepath.Join(store.tempDir, fmt.Sprintf("%06d", len(store.spilled)))
+	spillFile, createErr := os.Create(spillPath)
+	if createErr != nil {
+		return fmt.Errorf("spill entry: %w", createErr)
+	}
+	_, copyErr := io.Copy(spillFile, content)
+	closeErr := spillFile.Close()
+	if copyErr != nil {
+		return fmt.Errorf("spill entry: %w", copyErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("spill entry: %w", closeErr)
+	}
+	store.spilled[name] = spillPath
+	return nil
+}
+
+func (store *entryStore) open(name string) (io.ReadCloser, error) {
+	if contentBytes, inMemory := store.memory[name]; inMemory {
+		return io.NopCloser(bytes.NewReader(contentBytes)), nil
+	}
+	if spillPath, onDisk := store.spilled[name]; onDisk {
+		return os.Open(spillPath)
+	}
+	return nil, errNoSuchEntry
+}
+
+// close removes any spilled files.
+func (store *entryStore) close() error {
+	if store.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(store.tempDir)
+}
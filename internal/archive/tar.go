@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// eagerTarExtensions mark the entries read at open time: the conversation and metadata files. Everything else,
+// attachments above all, is decompressed only when a matched conversation asks for it.
+var eagerTarExtensions = map[string]struct{}{".json": {}, ".html": {}, ".htm": {}}
+
+// tarGzReader serves entries of a gzip-compressed tarball. Tar entries can only be read in order, so deferred
+// entries are fetched by another pass over the file, batched through prefetch.
+type tarGzReader struct {
+	path     string
+	store    *entryStore
+	deferred map[string]struct{}
+}
+
+// openTarGzArchive reads a gzip-compressed tarball. Conversation and metadata entries are decompressed up front,
+// into memory within memoryBudget bytes and into a temporary folder beyond it; attachments are deferred, and
+// once requested always go to the temporary folder, as they are copied out file by file anyway.
+func openTarGzArchive(tarFilePath string, memoryBudget int64) (*Archive, error) {
+	reader := &tarGzReader{path: tarFilePath, store: newEntryStore(memoryBudget), deferred: make(map[string]struct{})}
+	names := make([]string, 0)
+	sizes := make(map[string]int64)
+	scanErr := reader.scan(false, nil, func(name string, size int64) bool {
+		names = append(names, name)
+		sizes[name] = size
+		if _, eager := eagerTarExtensions[strings.ToLower(path.Ext(name))]; eager {
+			return true
+		}
+		reader.deferred[name] = struct{}{}
+		return false
+	})
+	if scanErr != nil {
+		reader.store.close()
+		return nil, scanErr
+	}
+	archive := newArchive(names, reader.open, reader.store.close)
+	archive.prefetch = reader.prefetch
+	archive.sizes = sizes
+	return archive, nil
+}
+
+// scan passes over every regular entry, storing those for which keep, given the entry's name and size,
+// returns true; with spill they are copied to the temporary folder whatever the memory budget. stored, when
+// not nil, is told the name of every entry once its content is stored.
+func (reader *tarGzReader) scan(spill bool, stored func(name string), keep func(name string, size int64) bool) error {
+	file, openErr := os.Open(reader.path)
+	if openErr != nil {
+		return fmt.Errorf("open tar.gz: %w", openErr)
+	}
+	defer file.Close()
+	gzipReader, gzipErr := gzip.NewReader(file)
+	if gzipErr != nil {
+		return fmt.Errorf("open tar.gz: %w", gzipErr)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, nextErr := tarReader.Next()
+		if errors.Is(nextErr, io.EOF) {
+			return nil
+		}
+		if nextErr != nil {
+			return fmt.Errorf("read tar.gz: %w", nextErr)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		normalizedName := strings.TrimPrefix(filepath.ToSlash(header.Name), "./")
+		if !keep(normalizedName, header.Size) {
+			continue
+		}
+		var putErr error
+		if spill {
+			putErr = reader.store.spill(normalizedName, tarReader)
+		} else {
+			putErr = reader.store.put(normalizedName, tarReader, header.Size)
+		}
+		if putErr != nil {
+			return fmt.Errorf("read tar entry %q: %w", header.Name, putErr)
+		}
+		if stored != nil {
+			stored(normalizedName)
+		}
+	}
+}
+
+// prefetch decompresses the requested deferred entries in a single pass. An entry stays deferred until its
+// content is stored, so when the pass fails the entries it did not reach are read again by the next request.
+func (reader *tarGzReader) prefetch(names []string) error {
+	wanted := make(map[string]struct{})
+	for _, name := range names {
+		if _, deferred := reader.deferred[name]; deferred {
+			wanted[name] = struct{}{}
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+	return reader.scan(true, func(name string) { delete(reader.deferred, name) }, func(name string, size int64) bool {
+		_, requested := wanted[name]
+		return requested
+	})
+}
+
+func (reader *tarGzReader) open(name string) (io.ReadCloser, error) {
+	if prefetchErr := reader.prefetch([]string{name}); prefetchErr != nil {
+		return nil, prefetchErr
+	}
+	return reader.store.open(name)
+}
+
+// openSingleFileArchive wraps a bare conversations.json or chat.html, which has no attachments, as entryName.
+func openSingleFileArchive(filePath string, entryName string) (*Archive, error) {
+	open := func(name string) (io.ReadCloser, error) {
+		if name != entryName {
+			return nil, os.ErrNotExist
+		}
+		return os.Open(filePath)
+	}
+	archive := newArchive([]string{entryName}, open, nil)
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		archive.sizes = map[string]int64{entryName: info.Size()}
+	}
+	return archive, nil
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarGzPrefetchKeepsUnreadEntriesDeferred(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	attachment := make([]byte, 1<<20)
+	for position := range attachment {
+		attachment[position] = byte(random.Uint32())
+	}
+	entries := []struct {
+		name    string
+		content []byte
+	}{
+		{name: conversationsFileName, content: []byte("[]")},
+		{name: "first.png", content: []byte("first attachment")},
+		{name: "second.png", content: attachment},
+	}
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, entry := range entries {
+		if headerErr := tarWriter.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}); headerErr != nil {
+			t.Fatalf("WriteHeader: %v", headerErr)
+		}
+		if _, writeErr := tarWriter.Write(entry.content); writeErr != nil {
+			t.Fatalf("Write: %v", writeErr)
+		}
+	}
+	if closeErr := errors.Join(tarWriter.Close(), gzipWriter.Close()); closeErr != nil {
+		t.Fatalf("close tar.gz: %v", closeErr)
+	}
+	tarPath := filepath.Join(t.TempDir(), "export.tar.gz")
+	writeTestFile(t, tarPath, compressed.String())
+	opened, openErr := OpenArchive(tarPath, OpenOptions{})
+	if openErr != nil {
+		t.Fatalf("OpenArchive: %v", openErr)
+	}
+	defer opened.Close()
+
+	writeTestFile(t, tarPath, compressed.String()[:compressed.Len()/2])
+	if prefetchErr := opened.Prefetch([]string{"first.png", "second.png"}); prefetchErr == nil {
+		t.Fatal("prefetch of a truncated tarball succeeded")
+	}
+	if _, openErr := opened.Open("second.png"); openErr == nil || errors.Is(openErr, errNoSuchEntry) {
+		t.Fatalf("Open of the unread entry = %v, want the read error", openErr)
+	}
+
+	writeTestFile(t, tarPath, compressed.String())
+	testCases := []struct {
+		name            string
+		expectedContent []byte
+	}{
+		{name: "first.png", expectedContent: entries[1].content},
+		{name: "second.png", expectedContent: attachment},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reader, openErr := opened.Open(testCase.name)
+			if openErr != nil {
+				t.Fatalf("Open: %v", openErr)
+			}
+			defer reader.Close()
+			content, readErr := io.ReadAll(reader)
+			if readErr != nil {
+				t.Fatalf("ReadAll: %v", readErr)
+			}
+			if !bytes.Equal(content, testCase.expectedContent) {
+				t.Errorf("content differs: got %d bytes, want %d", len(content), len(testCase.expectedContent))
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"io"
+	"strings"
+)
+
+// assetPointerSchemes prefix the file ids that messages use to reference uploaded and generated files.
+var assetPointerSchemes = []string{"file-service://", "sediment://"}
+
+// fileIDTerminators may follow the file id in an entry name; anything else means a longer, different id.
+const fileIDTerminators = "-_."
+
+// CorruptEntry is an archive entry that could not be read back intact.
+type CorruptEntry struct {
+	Name string
+	Err  error
+}
+
+// VerifyEntries reads every entry to the end so the underlying format checks it: ZIP CRC-32s, AES
+// authentication codes, and gzip trailers. It returns the entries that failed.
+func (archive *Archive) VerifyEntries() []CorruptEntry {
+	var corrupt []CorruptEntry
+	if prefetchErr := archive.Prefetch(archive.names); prefetchErr != nil {
+		return []CorruptEntry{{Name: "(archive)", Err: prefetchErr}}
+	}
+	for _, name := range archive.names {
+		reader, openErr := archive.Open(name)
+		if openErr != nil {
+			corrupt = append(corrupt, CorruptEntry{Name: name, Err: openErr})
+			continue
+		}
+		_, copyErr := io.Copy(io.Discard, reader)
+		reader.Close()
+		if copyErr != nil {
+			corrupt = append(corrupt, CorruptEntry{Name: name, Err: copyErr})
+		}
+	}
+	return corrupt
+}
+
+// AssetFileID returns the file id an asset pointer such as "file-service://file-AbC123" refers to.
+func AssetFileID(pointer string) (string, bool) {
+	for _, scheme := range assetPointerSchemes {
+		if fileID, found := strings.CutPrefix(pointer, scheme); found && fileID != "" {
+			return fileID, true
+		}
+	}
+	return "", false
+}
+
+// HasFile reports whether any entry below root is named after fileID.
+func (archive *Archive) HasFile(root string, fileID string) bool {
+	return len(archive.FileEntries(root, fileID)) > 0
+}
+
+// FileEntries returns the entries below root named after fileID, as exports store files as
+// "<file id>-<original name>" or "<file id>.<ext>", in files/, dalle-generations/, or the export root.
+func (archive *Archive) FileEntries(root string, fileID string) []string {
+	var entries []string
+	for _, name := range archive.names {
+		if !strings.HasPrefix(name, root) || strings.HasSuffix(name, "/") {
+			continue
+		}
+		baseName := name[strings.LastIndex(name, "/")+1:]
+		rest, named := strings.CutPrefix(baseName, fileID)
+		if named && (rest == "" || strings.ContainsRune(fileIDTerminators, rune(rest[0]))) {
+			entries = append(entries, name)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,155 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errMapUnsupported = errors.New("memory mapping is not supported")
+
+// openZipArchive reads the ZIP's directory and opens entries on demand. The file is also mapped into memory when
+// the platform allows, so stored (uncompressed) entries, which exports use for images and other attachments that
+// do not compress, are served straight from the mapping instead of being read through buffers.
+func openZipArchive(zipFilePath string, password PasswordSource) (*Archive, error) {
+	file, openErr := os.Open(zipFilePath)
+	if openErr != nil {
+		return nil, fmt.Errorf("open zip: %w", openErr)
+	}
+	info, statErr := file.Stat()
+	if statErr != nil {
+		file.Close()
+		return nil, fmt.Errorf("open zip: %w", statErr)
+	}
+	zipReader, readErr := zip.NewReader(file, info.Size())
+	if readErr != nil {
+		file.Close()
+		return nil, fmt.Errorf("open zip: %w", readErr)
+	}
+	mapped, mapErr := mapFile(file, info.Size())
+	if mapErr != nil {
+		mapped = nil
+	}
+	entries := make(map[string]*zip.File, len(zipReader.File))
+	names := make([]string, 0, len(zipReader.File))
+	sizes := make(map[string]int64, len(zipReader.File))
+	for _, zipFile := range zipReader.File {
+		normalizedName := filepath.ToSlash(zipFile.Name)
+		if strings.HasSuffix(normalizedName, "/") {
+			continue
+		}
+		entries[normalizedName] = zipFile
+		names = append(names, normalizedName)
+		sizes[normalizedName] = int64(zipFile.UncompressedSize64)
+	}
+	resolvePassword := memoizePassword(password)
+	open := func(name string) (io.ReadCloser, error) {
+		zipFile, found := entries[name]
+		if !found {
+			return nil, errNoSuchEntry
+		}
+		if !isEncrypted(zipFile) {
+			if entry, isMapped := openMappedEntry(mapped, zipFile); isMapped {
+				return entry, nil
+			}
+			return zipFile.Open()
+		}
+		resolved, passwordErr := resolvePassword()
+		if passwordErr != nil {
+			return nil, passwordErr
+		}
+		return openEncryptedEntry(zipFile, resolved)
+	}
+	closer := func() error {
+		var unmapErr error
+		if mapped != nil {
+			unmapErr = unmapFile(mapped)
+		}
+		return errors.Join(unmapErr, file.Close())
+	}
+	archive := newArchive(names, open, closer)
+	archive.sizes = sizes
+	return archive, nil
+}
+
+// mappedEntry reads a stored entry out of the mapped archive, checking its CRC-32 once the end is reached, as
+// zip.File.Open does. It writes to a file in one call, without a buffer in between.
+type mappedEntry struct {
+	content  []byte
+	position int
+	expected uint32
+	digest   uint32
+}
+
+// openMappedEntry slices a stored entry out of mapped, reporting false for compressed entries, entries the
+// mapping does not cover, or no mapping at all.
+func openMappedEntry(mapped []byte, zipFile *zip.File) (*mappedEntry, bool) {
+	if mapped == nil || zipFile.Method != zip.Store || zipFile.CompressedSize64 != zipFile.UncompressedSize64 {
+		return nil, false
+	}
+	offset, offsetErr := zipFile.DataOffset()
+	if offsetErr != nil || offset < 0 || uint64(offset)+zipFile.UncompressedSize64 > uint64(len(mapped)) {
+		return nil, false
+	}
+	content := mapped[offset : uint64(offset)+zipFile.UncompressedSize64]
+	return &mappedEntry{content: content, expected: zipFile.CRC32}, true
+}
+
+func (entry *mappedEntry) Read(buffer []byte) (int, error) {
+	if entry.position == len(entry.content) {
+		return 0, entry.verify()
+	}
+	count := copy(buffer, entry.content[entry.position:])
+	entry.digest = crc32.Update(entry.digest, crc32.IEEETable, entry.content[entry.position:entry.position+count])
+	entry.position += count
+	return count, nil
+}
+
+func (entry *mappedEntry) WriteTo(writer io.Writer) (int64, error) {
+	remaining := entry.content[entry.position:]
+	entry.digest = crc32.Update(entry.digest, crc32.IEEETable, remaining)
+	entry.position = len(entry.content)
+	if verifyErr := entry.verify(); !errors.Is(verifyErr, io.EOF) {
+		return 0, verifyErr
+	}
+	written, writeErr := writer.Write(remaining)
+	return int64(written), writeErr
+}
+
+// verify reports io.EOF for an intact entry and zip.ErrChecksum otherwise.
+func (entry *mappedEntry) verify() error {
+	if entry.expected != 0 && entry.digest != entry.expected {
+		return zip.ErrChecksum
+	}
+	return io.EOF
+}
+
+func (entry *mappedEntry) Close() error {
+	return nil
+}
+
+// memoizePassword asks source at most once, so a prompt is shown a single time per archive.
+func memoizePassword(source PasswordSource) PasswordSource {
+	var (
+		asked    bool
+		password string
+		askErr   error
+	)
+	return func() (string, error) {
+		if asked {
+			return password, askErr
+		}
+		asked = true
+		if source == nil {
+			askErr = errors.New("archive is encrypted: pass --zip-password")
+			return "", askErr
+		}
+		password, askErr = source()
+		return password, askErr
+	}
+}
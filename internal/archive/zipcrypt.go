@@ -0,0 +1,252 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	encryptedFlag        = 0x1
+	dataDescriptorFlag   = 0x8
+	winzipAESMethod      = 99
+	winzipAESExtraID     = 0x9901
+	winzipAESIterations  = 1000
+	winzipVerifierLength = 2
+	winzipAuthLength     = 10
+	zipCryptoHeaderSize  = 12
+)
+
+// ErrIncorrectPassword is returned when an encrypted entry does not decrypt with the given password.
+var ErrIncorrectPassword = errors.New("incorrect zip password")
+
+// PasswordSource supplies the password for encrypted ZIP entries. It is called at most once per archive,
+// and only when an encrypted entry is actually opened.
+type PasswordSource func() (string, error)
+
+var winzipKeyLengths = map[byte]int{1: 16, 2: 24, 3: 32}
+
+func isEncrypted(zipFile *zip.File) bool {
+	return zipFile.Flags&encryptedFlag != 0
+}
+
+// openEncryptedEntry decrypts a WinZip AES (method 99) or traditional PKWARE ZipCrypto entry and
+// returns its decompressed content.
+func openEncryptedEntry(zipFile *zip.File, password string) (io.ReadCloser, error) {
+	raw, rawErr := zipFile.OpenRaw()
+	if rawErr != nil {
+		return nil, rawErr
+	}
+	if zipFile.Method == winzipAESMethod {
+		return openWinzipAESEntry(zipFile, raw, password)
+	}
+	return openZipCryptoEntry(zipFile, raw, password)
+}
+
+func openWinzipAESEntry(zipFile *zip.File, raw io.Reader, password string) (io.ReadCloser, error) {
+	strength, actualMethod, extraErr := parseWinzipAESExtra(zipFile.Extra)
+	if extraErr != nil {
+		return nil, extraErr
+	}
+	keyLength := winzipKeyLengths[strength]
+	saltLength := keyLength / 2
+	header := make([]byte, saltLength+winzipVerifierLength)
+	if _, readErr := io.ReadFull(raw, header); readErr != nil {
+		return nil, fmt.Errorf("read aes header: %w", readErr)
+	}
+	derived, deriveErr := pbkdf2.Key(sha1.New, password, header[:saltLength], winzipAESIterations, 2*keyLength+winzipVerifierLength)
+	if deriveErr != nil {
+		return nil, deriveErr
+	}
+	if !bytes.Equal(derived[2*keyLength:], header[saltLength:]) {
+		return nil, ErrIncorrectPassword
+	}
+	block, cipherErr := aes.NewCipher(derived[:keyLength])
+	if cipherErr != nil {
+		return nil, cipherErr
+	}
+	overhead := uint64(len(header) + winzipAuthLength)
+	if zipFile.CompressedSize64 < overhead {
+		return nil, errors.New("truncated aes entry")
+	}
+	authenticator := hmac.New(sha1.New, derived[keyLength:2*keyLength])
+	encrypted := io.TeeReader(io.LimitReader(raw, int64(zipFile.CompressedSize64-overhead)), authenticator)
+	decrypted := &authenticatedReader{
+		source:        &winzipCTRReader{source: encrypted, block: block},
+		trailer:       raw,
+		authenticator: authenticator,
+	}
+	return decompress(actualMethod, decrypted)
+}
+
+func parseWinzipAESExtra(extra []byte) (byte, uint16, error) {
+	for len(extra) >= 4 {
+		fieldID := binary.LittleEndian.Uint16(extra[0:2])
+		fieldSize := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+fieldSize {
+			break
+		}
+		field := extra[4 : 4+fieldSize]
+		if fieldID == winzipAESExtraID && fieldSize >= 7 {
+			strength := field[4]
+			if _, known := winzipKeyLengths[strength]; !known {
+				return 0, 0, fmt.Errorf("unsupported aes strength %d", strength)
+			}
+			return strength, binary.LittleEndian.Uint16(field[5:7]), nil
+		}
+		extra = extra[4+fieldSize:]
+	}
+	return 0, 0, errors.New("missing aes extra field")
+}
+
+// winzipCTRReader applies WinZip's AES-CTR variant, whose 128-bit counter is little-endian and starts at 1.
+type winzipCTRReader struct {
+	source    io.Reader
+	block     cipher.Block
+	counter   [aes.BlockSize]byte
+	keystream [aes.BlockSize]byte
+	used      int
+}
+
+func (reader *winzipCTRReader) Read(buffer []byte) (int, error) {
+	count, readErr := reader.source.Read(buffer)
+	for index := 0; index < count; index++ {
+		if reader.used == 0 || reader.used == aes.BlockSize {
+			reader.nextKeystream()
+		}
+		buffer[index] ^= reader.keystream[reader.used]
+		reader.used++
+	}
+	return count, readErr
+}
+
+func (reader *winzipCTRReader) nextKeystream() {
+	for position := range reader.counter {
+		reader.counter[position]++
+		if reader.counter[position] != 0 {
+			break
+		}
+	}
+	reader.block.Encrypt(reader.keystream[:], reader.counter[:])
+	reader.used = 0
+}
+
+// authenticatedReader checks the WinZip HMAC-SHA1 trailer once the encrypted data is exhausted.
+type authenticatedReader struct {
+	source        io.Reader
+	trailer       io.Reader
+	authenticator hash.Hash
+}
+
+func (reader *authenticatedReader) Read(buffer []byte) (int, error) {
+	count, readErr := reader.source.Read(buffer)
+	if !errors.Is(readErr, io.EOF) {
+		return count, readErr
+	}
+	expected := make([]byte, winzipAuthLength)
+	if _, trailerErr := io.ReadFull(reader.trailer, expected); trailerErr != nil {
+		return count, fmt.Errorf("read aes authentication code: %w", trailerErr)
+	}
+	if !hmac.Equal(expected, reader.authenticator.Sum(nil)[:winzipAuthLength]) {
+		return count, errors.New("aes authentication failed: entry is corrupt")
+	}
+	return count, io.EOF
+}
+
+func openZipCryptoEntry(zipFile *zip.File, raw io.Reader, password string) (io.ReadCloser, error) {
+	if zipFile.CompressedSize64 < zipCryptoHeaderSize {
+		return nil, errors.New("truncated encrypted entry")
+	}
+	keys := newZipCryptoKeys(password)
+	decrypted := &zipCryptoReader{source: io.LimitReader(raw, int64(zipFile.CompressedSize64)), keys: keys}
+	header := make([]byte, zipCryptoHeaderSize)
+	if _, readErr := io.ReadFull(decrypted, header); readErr != nil {
+		return nil, fmt.Errorf("read encryption header: %w", readErr)
+	}
+	checkByte := byte(zipFile.CRC32 >> 24)
+	if zipFile.Flags&dataDescriptorFlag != 0 {
+		checkByte = byte(zipFile.ModifiedTime >> 8)
+	}
+	if header[zipCryptoHeaderSize-1] != checkByte {
+		return nil, ErrIncorrectPassword
+	}
+	content, decompressErr := decompress(zipFile.Method, decrypted)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	return &checksumReader{ReadCloser: content, expected: zipFile.CRC32, digest: crc32.NewIEEE()}, nil
+}
+
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) *zipCryptoKeys {
+	keys := &zipCryptoKeys{305419896, 591751049, 878082192}
+	for index := 0; index < len(password); index++ {
+		keys.update(password[index])
+	}
+	return keys
+}
+
+func (keys *zipCryptoKeys) update(plain byte) {
+	keys[0] = crc32.IEEETable[byte(keys[0])^plain] ^ (keys[0] >> 8)
+	keys[1] = (keys[1]+(keys[0]&0xff))*134775813 + 1
+	keys[2] = crc32.IEEETable[byte(keys[2])^byte(keys[1]>>24)] ^ (keys[2] >> 8)
+}
+
+func (keys *zipCryptoKeys) decrypt(cipherByte byte) byte {
+	temp := uint16(keys[2]) | 2
+	plain := cipherByte ^ byte((uint32(temp)*uint32(temp^1))>>8)
+	keys.update(plain)
+	return plain
+}
+
+type zipCryptoReader struct {
+	source io.Reader
+	keys   *zipCryptoKeys
+}
+
+func (reader *zipCryptoReader) Read(buffer []byte) (int, error) {
+	count, readErr := reader.source.Read(buffer)
+	for index := 0; index < count; index++ {
+		buffer[index] = reader.keys.decrypt(buffer[index])
+	}
+	return count, readErr
+}
+
+// checksumReader verifies the entry CRC-32 at end of stream, turning a wrong-but-lucky password into an error.
+type checksumReader struct {
+	io.ReadCloser
+	expected uint32
+	digest   hash.Hash32
+}
+
+func (reader *checksumReader) Read(buffer []byte) (int, error) {
+	count, readErr := reader.ReadCloser.Read(buffer)
+	reader.digest.Write(buffer[:count])
+	if errors.Is(readErr, io.EOF) && reader.digest.Sum32() != reader.expected {
+		return count, ErrIncorrectPassword
+	}
+	return count, readErr
+}
+
+func decompress(method uint16, source io.Reader) (io.ReadCloser, error) {
+	switch method {
+	case zip.Store:
+		return io.NopCloser(source), nil
+	case zip.Deflate:
+		return flate.NewReader(source), nil
+	default:
+		return nil, zip.ErrAlgorithm
+	}
+}
@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	testPassword      = "correct horse"
+	wrongTestPassword = "battery staple"
+)
+
+var testContent = []byte(strings.Repeat(`{"title":"encrypted conversation","parts":["hello"]}`, 40))
+
+func TestZipCryptoEntry(t *testing.T) {
+	crc := crc32.ChecksumIEEE(testContent)
+	testCases := []struct {
+		name        string
+		method      uint16
+		flags       uint16
+		storedCRC   uint32
+		password    string
+		expectedErr error
+	}{
+		{name: "stored", method: zip.Store, storedCRC: crc, password: testPassword},
+		{name: "deflated", method: zip.Deflate, storedCRC: crc, password: testPassword},
+		{name: "data descriptor check byte", method: zip.Deflate, flags: dataDescriptorFlag, storedCRC: crc, password: testPassword},
+		{name: "wrong password", method: zip.Store, storedCRC: crc, password: wrongTestPassword, expectedErr: ErrIncorrectPassword},
+		{name: "crc guard", method: zip.Store, storedCRC: crc ^ 0xff, password: testPassword, expectedErr: ErrIncorrectPassword},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			header := &zip.FileHeader{Name: "conversations.json", Method: testCase.method, Flags: encryptedFlag | testCase.flags, ModifiedTime: 0xa1b2, CRC32: testCase.storedCRC}
+			checkByte := byte(testCase.storedCRC >> 24)
+			if testCase.flags&dataDescriptorFlag != 0 {
+				checkByte = byte(header.ModifiedTime >> 8)
+			}
+			data := encryptZipCrypto(testPassword, checkByte, compressForTest(t, testCase.method, testContent))
+			content, readErr := readEncryptedEntry(t, header, data, testCase.password)
+			assertEntry(t, content, readErr, testCase.expectedErr)
+		})
+	}
+}
+
+func TestZipCryptoLuckyWrongPassword(t *testing.T) {
+	crc := crc32.ChecksumIEEE(testContent)
+	data := encryptZipCrypto(testPassword, byte(crc>>24), testContent)
+	luckyPassword := ""
+	for attempt := 0; attempt < 100000 && luckyPassword == ""; attempt++ {
+		candidate := wrongTestPassword + strconv.Itoa(attempt)
+		keys := newZipCryptoKeys(candidate)
+		var decrypted byte
+		for _, cipherByte := range data[:zipCryptoHeaderSize] {
+			decrypted = keys.decrypt(cipherByte)
+		}
+		if decrypted == byte(crc>>24) {
+			luckyPassword = candidate
+		}
+	}
+	if luckyPassword == "" {
+		t.Fatal("no wrong password passes the check byte")
+	}
+	header := &zip.FileHeader{Name: "conversations.json", Method: zip.Store, Flags: encryptedFlag, CRC32: crc}
+	content, readErr := readEncryptedEntry(t, header, data, luckyPassword)
+	assertEntry(t, content, readErr, ErrIncorrectPassword)
+}
+
+func TestWinzipAESEntry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		strength    byte
+		method      uint16
+		password    string
+		tamper      func(data []byte)
+		expectedErr string
+	}{
+		{name: "aes-128 stored", strength: 1, method: zip.Store, password: testPassword},
+		{name: "aes-192 deflated", strength: 2, method: zip.Deflate, password: testPassword},
+		{name: "aes-256 deflated", strength: 3, method: zip.Deflate, password: testPassword},
+		{name: "wrong password", strength: 3, method: zip.Deflate, password: wrongTestPassword, expectedErr: ErrIncorrectPassword.Error()},
+		{name: "hmac guards ciphertext", strength: 3, method: zip.Store, password: testPassword, tamper: func(data []byte) { data[len(data)/2] ^= 0x01 }, expectedErr: "aes authentication failed"},
+		{name: "hmac guards authentication code", strength: 1, method: zip.Store, password: testPassword, tamper: func(data []byte) { data[len(data)-1] ^= 0x01 }, expectedErr: "aes authentication failed"},
+		{name: "zeroed authentication code", strength: 1, method: zip.Store, password: testPassword, tamper: func(data []byte) { clear(data[len(data)-winzipAuthLength:]) }, expectedErr: "aes authentication failed"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data := encryptWinzipAES(t, testPassword, testCase.strength, compressForTest(t, testCase.method, testContent))
+			if testCase.tamper != nil {
+				testCase.tamper(data)
+			}
+			header := &zip.FileHeader{Name: "conversations.json", Method: winzipAESMethod, Flags: encryptedFlag, Extra: winzipAESExtra(testCase.strength, testCase.method)}
+			content, readErr := readEncryptedEntry(t, header, data, testCase.password)
+			if testCase.expectedErr == "" {
+				assertEntry(t, content, readErr, nil)
+				return
+			}
+			if readErr == nil || !strings.Contains(readErr.Error(), testCase.expectedErr) {
+				t.Fatalf("read error = %v, want one containing %q", readErr, testCase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestParseWinzipAESExtraRejectsUnknownStrength(t *testing.T) {
+	if _, _, parseErr := parseWinzipAESExtra(winzipAESExtra(4, zip.Store)); parseErr == nil {
+		t.Fatal("parseWinzipAESExtra accepted strength 4")
+	}
+	if _, _, parseErr := parseWinzipAESExtra(nil); parseErr == nil {
+		t.Fatal("parseWinzipAESExtra accepted a missing extra field")
+	}
+}
+
+// readEncryptedEntry writes data as the raw body of one entry described by header into an in-memory ZIP, then
+// decrypts it with password through openEncryptedEntry.
+func readEncryptedEntry(t *testing.T, header *zip.FileHeader, data []byte, password string) ([]byte, error) {
+	t.Helper()
+	header.CompressedSize64 = uint64(len(data))
+	header.UncompressedSize64 = uint64(len(testContent))
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	entryWriter, createErr := zipWriter.CreateRaw(header)
+	if createErr != nil {
+		t.Fatalf("CreateRaw: %v", createErr)
+	}
+	if _, writeErr := entryWriter.Write(data); writeErr != nil {
+		t.Fatalf("write entry: %v", writeErr)
+	}
+	if closeErr := zipWriter.Close(); closeErr != nil {
+		t.Fatalf("close zip: %v", closeErr)
+	}
+	zipReader, readerErr := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if readerErr != nil {
+		t.Fatalf("NewReader: %v", readerErr)
+	}
+	zipFile := zipReader.File[0]
+	if !isEncrypted(zipFile) {
+		t.Fatal("entry is not flagged as encrypted")
+	}
+	entry, openErr := openEncryptedEntry(zipFile, password)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer entry.Close()
+	return io.ReadAll(entry)
+}
+
+func assertEntry(t *testing.T, content []byte, readErr error, expectedErr error) {
+	t.Helper()
+	if expectedErr != nil {
+		if !errors.Is(readErr, expectedErr) {
+			t.Fatalf("read error = %v, want %v", readErr, expectedErr)
+		}
+		return
+	}
+	if readErr != nil {
+		t.Fatalf("read entry: %v", readErr)
+	}
+	if !bytes.Equal(content, testContent) {
+		t.Fatalf("decrypted content differs: got %d bytes, want %d", len(content), len(testContent))
+	}
+}
+
+func compressForTest(t *testing.T, method uint16, content []byte) []byte {
+	t.Helper()
+	if method == zip.Store {
+		return bytes.Clone(content)
+	}
+	var buffer bytes.Buffer
+	compressor, compressorErr := flate.NewWriter(&buffer, flate.BestCompression)
+	if compressorErr != nil {
+		t.Fatalf("flate writer: %v", compressorErr)
+	}
+	if _, writeErr := compressor.Write(content); writeErr != nil {
+		t.Fatalf("deflate: %v", writeErr)
+	}
+	if closeErr := compressor.Close(); closeErr != nil {
+		t.Fatalf("close deflate: %v", closeErr)
+	}
+	return buffer.Bytes()
+}
+
+// encryptZipCrypto encrypts compressed with traditional PKWARE encryption behind a fixed 12-byte header whose
+// last byte is checkByte.
+func encryptZipCrypto(password string, checkByte byte, compressed []byte) []byte {
+	plain := append([]byte("0123456789a"), checkByte)
+	plain = append(plain, compressed...)
+	keys := newZipCryptoKeys(password)
+	encrypted := make([]byte, len(plain))
+	for index, plainByte := range plain {
+		temp := uint16(keys[2]) | 2
+		encrypted[index] = plainByte ^ byte((uint32(temp)*uint32(temp^1))>>8)
+		keys.update(plainByte)
+	}
+	return encrypted
+}
+
+// encryptWinzipAES encrypts compressed as a WinZip AES entry body: salt, password verifier, ciphertext, and
+// the truncated HMAC-SHA1 of the ciphertext.
+func encryptWinzipAES(t *testing.T, password string, strength byte, compressed []byte) []byte {
+	t.Helper()
+	keyLength := winzipKeyLengths[strength]
+	salt := bytes.Repeat([]byte{0x5a}, keyLength/2)
+	derived, deriveErr := pbkdf2.Key(sha1.New, password, salt, winzipAESIterations, 2*keyLength+winzipVerifierLength)
+	if deriveErr != nil {
+		t.Fatalf("derive keys: %v", deriveErr)
+	}
+	block, cipherErr := aes.NewCipher(derived[:keyLength])
+	if cipherErr != nil {
+		t.Fatalf("aes cipher: %v", cipherErr)
+	}
+	ciphertext, encryptErr := io.ReadAll(&winzipCTRReader{source: bytes.NewReader(compressed), block: block})
+	if encryptErr != nil {
+		t.Fatalf("encrypt: %v", encryptErr)
+	}
+	authenticator := hmac.New(sha1.New, derived[keyLength:2*keyLength])
+	authenticator.Write(ciphertext)
+	data := append(bytes.Clone(salt), derived[2*keyLength:]...)
+	data = append(data, ciphertext...)
+	return append(data, authenticator.Sum(nil)[:winzipAuthLength]...)
+}
+
+// winzipAESExtra builds the AE-2 extra field naming the key strength and the method the content is compressed with.
+func winzipAESExtra(strength byte, method uint16) []byte {
+	extra := binary.LittleEndian.AppendUint16(nil, winzipAESExtraID)
+	extra = binary.LittleEndian.AppendUint16(extra, 7)
+	extra = binary.LittleEndian.AppendUint16(extra, 2)
+	extra = append(extra, 'A', 'E', strength)
+	return binary.LittleEndian.AppendUint16(extra, method)
+}
@@ -0,0 +1,236 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	uploadsFolderName     = "files"
+	generatedFolderName   = "dalle-generations"
+	attachmentsMetadata   = "attachments"
+	attachmentIDField     = "id"
+	attachmentNameField   = "name"
+	attachmentAssetField  = "asset_pointer"
+	fileIDNameSeparators  = "-_"
+	duplicateNameTemplate = "%s_%d%s"
+)
+
+// AttachmentFilter narrows an attachment dump to the files of some conversations. The zero value keeps every
+// file of the export.
+type AttachmentFilter struct {
+	// IDs keeps the conversations with these ids.
+	IDs []string
+	// Since and Until bound the conversations' creation time, inclusively; zero leaves that side open.
+	Since time.Time
+	Until time.Time
+}
+
+func (filter AttachmentFilter) active() bool {
+	return len(filter.IDs) > 0 || !filter.Since.IsZero() || !filter.Until.IsZero()
+}
+
+func (filter AttachmentFilter) matches(conversation model.Conversation) bool {
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, conversation.ID) {
+		return false
+	}
+	if !filter.Since.IsZero() && conversation.CreateTime.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && conversation.CreateTime.After(filter.Until) {
+		return false
+	}
+	return true
+}
+
+// RunAttachments copies the uploaded and generated files of the exports into outputFolder: everything under
+// files/ and dalle-generations/, plus files stored elsewhere that a conversation references by id. With an
+// active filter only the files of the matching conversations are copied. Files are renamed to the name they
+// were uploaded under, as recorded in the conversations, and keep their export name when none is recorded;
+// generated images go to a dalle-generations subfolder, everything else to files.
+func RunAttachments(ctx context.Context, inputSettings InputSettings, outputFolder string, filter AttachmentFilter) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+	files := archive.Combine(sources)
+
+	collected := newConversationSet()
+	for index, source := range sources {
+		if len(sources) > 1 && !source.HasConversations() {
+			continue
+		}
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				logger.Error("decode conversation", zap.Error(decodeErr))
+				return nil
+			}
+			collected.offer(filters.Candidate{Conversation: conversation, Serialized: serialized, Archive: files, Origin: origin}, true)
+			return nil
+		})
+		if scanErr != nil {
+			return fmt.Errorf("%s: %w", inputSettings.Paths[index], scanErr)
+		}
+	}
+	conversations := collected.candidates()
+
+	// Original names are gathered from every conversation, so a file keeps its name even when the
+	// conversation that names it is not the one selecting it.
+	originalNames := make(map[string]string)
+	selected := make(map[string]struct{})
+	for _, candidate := range conversations {
+		for fileID, name := range referencedFiles(candidate.Conversation) {
+			for _, entry := range files.FileEntries(candidate.Origin.Root, fileID) {
+				if originalNames[entry] == "" {
+					originalNames[entry] = originalFileName(entry, fileID, name)
+				}
+				if !filter.active() || filter.matches(candidate.Conversation) {
+					selected[entry] = struct{}{}
+				}
+			}
+		}
+		if filter.active() && filter.matches(candidate.Conversation) {
+			for _, entry := range filters.CollectLinkedFiles(candidate) {
+				selected[entry] = struct{}{}
+			}
+		}
+	}
+	if !filter.active() {
+		for _, entry := range files.SupportingEntries() {
+			if attachmentFolder(entry) != "" {
+				selected[entry] = struct{}{}
+			}
+		}
+	}
+
+	entries := make([]string, 0, len(selected))
+	for entry := range selected {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	if prefetchErr := files.Prefetch(entries); prefetchErr != nil {
+		return fmt.Errorf("read attachments: %w", prefetchErr)
+	}
+
+	usedNames := make(map[string]struct{})
+	written := 0
+	for _, entry := range entries {
+		subfolder := attachmentFolder(entry)
+		if subfolder == "" {
+			subfolder = uploadsFolderName
+		}
+		name := originalNames[entry]
+		if name == "" {
+			name = path.Base(entry)
+		}
+		fileName, nameErr := utils.SafeArchiveFileName(name)
+		if nameErr != nil {
+			fileName, nameErr = utils.SafeArchiveFileName(entry)
+		}
+		if nameErr != nil {
+			logger.Warn("skip attachment", zap.String("archivePath", entry), zap.Error(nameErr))
+			continue
+		}
+		folder := filepath.Join(outputFolder, subfolder)
+		if mkErr := utils.EnsureDir(folder); mkErr != nil {
+			return fmt.Errorf("create %s: %w", folder, mkErr)
+		}
+		fileName = uniqueFileName(usedNames, subfolder, fileName)
+		if writeErr := copyArchiveEntry(files, entry, filepath.Join(folder, fileName)); writeErr != nil {
+			logger.Error("write attachment", zap.String("archivePath", entry), zap.Error(writeErr))
+			continue
+		}
+		written++
+	}
+	utils.PrintLine(fmt.Sprintf("wrote %d attachments to %s", written, outputFolder))
+	return nil
+}
+
+// referencedFiles maps the id of every file the conversation's messages point at, on any branch, to the name
+// it was uploaded under, or "" when the conversation does not record one, as for generated images.
+func referencedFiles(conversation model.Conversation) map[string]string {
+	referenced := make(map[string]string)
+	for _, message := range model.AllMessages(conversation) {
+		for _, asset := range message.Assets {
+			pointer, _ := asset[attachmentAssetField].(string)
+			if fileID, isFile := archive.AssetFileID(pointer); isFile && referenced[fileID] == "" {
+				referenced[fileID] = ""
+			}
+		}
+		attachments, _ := message.Metadata[attachmentsMetadata].([]any)
+		for _, rawAttachment := range attachments {
+			attachment, _ := rawAttachment.(map[string]any)
+			fileID, _ := attachment[attachmentIDField].(string)
+			if fileID == "" {
+				continue
+			}
+			name, _ := attachment[attachmentNameField].(string)
+			if name = strings.TrimSpace(name); name != "" || referenced[fileID] == "" {
+				referenced[fileID] = name
+			}
+		}
+	}
+	return referenced
+}
+
+// originalFileName returns the name an entry stored under fileID was uploaded as: the recorded name when
+// there is one, else the entry name without its "<file id>-" prefix, else "" to keep the entry name.
+func originalFileName(entry string, fileID string, recorded string) string {
+	if recorded != "" {
+		return recorded
+	}
+	rest, _ := strings.CutPrefix(path.Base(entry), fileID)
+	if len(rest) > 1 && strings.ContainsRune(fileIDNameSeparators, rune(rest[0])) {
+		return rest[1:]
+	}
+	return ""
+}
+
+// attachmentFolder returns the export folder holding entry, files or dalle-generations, or "" for an entry
+// outside both.
+func attachmentFolder(entry string) string {
+	segments := strings.Split(entry, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if segment == uploadsFolderName || segment == generatedFolderName {
+			return segment
+		}
+	}
+	return ""
+}
+
+// uniqueFileName returns fileName, or fileName with a counter before its extension when an earlier file in
+// the same subfolder already took it.
+func uniqueFileName(used map[string]struct{}, subfolder string, fileName string) string {
+	extension := path.Ext(fileName)
+	stem := strings.TrimSuffix(fileName, extension)
+	candidate := fileName
+	for counter := 2; ; counter++ {
+		key := subfolder + "/" + strings.ToLower(candidate)
+		if _, taken := used[key]; !taken {
+			used[key] = struct{}{}
+			return candidate
+		}
+		candidate = fmt.Sprintf(duplicateNameTemplate, stem, counter, extension)
+	}
+}
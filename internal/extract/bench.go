@@ -0,0 +1,145 @@
+package extract
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/render"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// heapObjectsMetric is the live heap, sampled during a run for its peak.
+	heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+	heapSampleEvery   = 10 * time.Millisecond
+)
+
+// RunBench times the matching pipeline over the exports runs times, from opening them to the last match, and
+// prints each run with the median. Nothing is written: it measures reading, decoding, and matching alone.
+func RunBench(ctx context.Context, inputSettings InputSettings, query filters.Query, criteria filters.Criteria, jobs int, runs int, format render.StatsFormat) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	report := render.NewBenchReport(inputSettings.Paths, query.Patterns, jobs)
+	for range runs {
+		run, runErr := benchOnce(ctx, logger, inputSettings, query, criteria, jobs)
+		if runErr != nil {
+			return runErr
+		}
+		report.Runs = append(report.Runs, run)
+	}
+	report.Median = medianRun(report.Runs)
+	rendered, renderErr := render.RenderBench(report, format)
+	if renderErr != nil {
+		return renderErr
+	}
+	_, writeErr := os.Stdout.Write(rendered)
+	return writeErr
+}
+
+func benchOnce(ctx context.Context, logger *zap.Logger, inputSettings InputSettings, query filters.Query, criteria filters.Criteria, jobs int) (render.BenchRun, error) {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	sampler := startHeapSampler()
+	start := time.Now()
+
+	var inputs []openedInput
+	var sources []*archive.Archive
+	for _, archiveFilePath := range inputSettings.Paths {
+		source, openErr := openInput(ctx, archiveFilePath, inputSettings)
+		if openErr != nil {
+			sampler.stop()
+			return render.BenchRun{}, openErr
+		}
+		defer source.Close()
+		inputs = append(inputs, openedInput{path: archiveFilePath, source: source})
+		sources = append(sources, source)
+	}
+	search, prepareErr := prepareSearch(logger, NamedQuery{Query: query, Criteria: criteria}, "", OutputSettings{})
+	if prepareErr != nil {
+		sampler.stop()
+		return render.BenchRun{}, prepareErr
+	}
+	totals, scanErr := matchSearches(ctx, logger, inputSettings, inputs, archive.Combine(sources), []*batchSearch{search}, jobs, nil)
+	matched := len(search.collected.candidates())
+
+	elapsed := time.Since(start)
+	peak := sampler.stop()
+	runtime.ReadMemStats(&after)
+	if scanErr != nil {
+		return render.BenchRun{}, scanErr
+	}
+	seconds := max(elapsed.Seconds(), 1e-9)
+	return render.BenchRun{
+		Duration:        elapsed,
+		Conversations:   totals.conversations,
+		Bytes:           totals.bytes,
+		Matched:         matched,
+		AllocatedBytes:  after.TotalAlloc - before.TotalAlloc,
+		Allocations:     after.Mallocs - before.Mallocs,
+		PeakHeapInUse:   peak,
+		ConversationsPS: float64(totals.conversations) / seconds,
+		BytesPS:         float64(totals.bytes) / seconds,
+	}, nil
+}
+
+// medianRun returns the run of median duration, the figure least swayed by a cold cache or a busy machine.
+func medianRun(runs []render.BenchRun) render.BenchRun {
+	if len(runs) == 0 {
+		return render.BenchRun{}
+	}
+	sorted := slices.Clone(runs)
+	slices.SortFunc(sorted, func(left, right render.BenchRun) int {
+		return cmp.Compare(left.Duration, right.Duration)
+	})
+	return sorted[len(sorted)/2]
+}
+
+// heapSampler tracks the largest live heap seen while it runs.
+type heapSampler struct {
+	done chan struct{}
+	wait sync.WaitGroup
+	peak uint64
+}
+
+func startHeapSampler() *heapSampler {
+	sampler := &heapSampler{done: make(chan struct{})}
+	sampler.wait.Go(func() {
+		samples := []metrics.Sample{{Name: heapObjectsMetric}}
+		ticker := time.NewTicker(heapSampleEvery)
+		defer ticker.Stop()
+		for {
+			metrics.Read(samples)
+			if samples[0].Value.Kind() == metrics.KindUint64 {
+				sampler.peak = max(sampler.peak, samples[0].Value.Uint64())
+			}
+			select {
+			case <-sampler.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+	return sampler
+}
+
+// stop ends sampling and returns the peak.
+func (sampler *heapSampler) stop() uint64 {
+	close(sampler.done)
+	sampler.wait.Wait()
+	return sampler.peak
+}
@@ -0,0 +1,79 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+// RunCat writes the JSON of conversations to stdout, for piping into jq: the conversation whose id equals
+// reference or, when none does, every conversation whose JSON matches reference as a search pattern. Each
+// conversation is written compacted on its own line (JSON Lines), or indented when pretty is set; both forms
+// are a stream of JSON values jq reads one at a time.
+func RunCat(ctx context.Context, inputSettings InputSettings, reference string, pretty bool) error {
+	pattern, patternErr := utils.CompileUserPattern(reference, utils.PatternOptions{})
+	if patternErr != nil {
+		return fmt.Errorf("invalid pattern %q: %w", reference, patternErr)
+	}
+
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+
+	byID := newConversationSet()
+	byPattern := newConversationSet()
+	for index, source := range sources {
+		if len(sources) > 1 && !source.HasConversations() {
+			continue
+		}
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				return nil
+			}
+			candidate := filters.Candidate{Conversation: conversation, Serialized: serialized, Origin: origin}
+			byID.offer(candidate, conversation.ID == reference)
+			byPattern.offer(candidate, pattern.Match(serialized))
+			return nil
+		})
+		if scanErr != nil {
+			return fmt.Errorf("%s: %w", inputSettings.Paths[index], scanErr)
+		}
+	}
+
+	matches := byID.candidates()
+	if len(matches) == 0 {
+		matches = byPattern.candidates()
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no conversation has id %q or matches it as a pattern", reference)
+	}
+	output := bufio.NewWriter(os.Stdout)
+	for _, candidate := range matches {
+		var formatted bytes.Buffer
+		var formatErr error
+		if pretty {
+			formatErr = json.Indent(&formatted, candidate.Serialized, "", "  ")
+		} else {
+			formatErr = json.Compact(&formatted, candidate.Serialized)
+		}
+		if formatErr != nil {
+			return fmt.Errorf("format conversation %s: %w", candidate.Conversation.ID, formatErr)
+		}
+		formatted.WriteByte('\n')
+		if _, writeErr := output.Write(formatted.Bytes()); writeErr != nil {
+			return writeErr
+		}
+	}
+	return output.Flush()
+}
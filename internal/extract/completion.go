@@ -0,0 +1,42 @@
+package extract
+
+import (
+	"context"
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+)
+
+// FilterValues returns the content types and languages present in the exports, sorted: the values that
+// --content-type and --language can usefully be given, for shell completion.
+func FilterValues(ctx context.Context, inputSettings InputSettings) ([]string, []string, error) {
+	contentTypes := make(map[string]struct{})
+	languages := make(map[string]struct{})
+	for _, archiveFilePath := range inputSettings.Paths {
+		source, openErr := openInput(ctx, archiveFilePath, inputSettings)
+		if openErr != nil {
+			return nil, nil, openErr
+		}
+		defer source.Close()
+		if !source.HasConversations() {
+			continue
+		}
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				return nil
+			}
+			for contentType := range filters.EnumerateContentTypes(conversation) {
+				contentTypes[contentType] = struct{}{}
+			}
+			for language := range filters.EnumerateLanguages(conversation) {
+				languages[language] = struct{}{}
+			}
+			return nil
+		})
+		if scanErr != nil {
+			return nil, nil, scanErr
+		}
+	}
+	return sortedNames(contentTypes), sortedNames(languages), nil
+}
@@ -0,0 +1,100 @@
+package extract
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	sqliteDriver       = "sqlite"
+	databaseTimeLayout = time.RFC3339
+)
+
+// databaseSchema creates the tables of the sqlite format: one row per conversation, holding its JSON as
+// exported, and one row per transcript message, numbered from 1 in transcript order.
+const databaseSchema = `
+CREATE TABLE IF NOT EXISTS conversations (
+	id          TEXT PRIMARY KEY,
+	title       TEXT NOT NULL,
+	create_time TEXT NOT NULL,
+	update_time TEXT NOT NULL,
+	model       TEXT NOT NULL,
+	folder      TEXT NOT NULL,
+	json        TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS messages (
+	conversation_id TEXT NOT NULL,
+	position        INTEGER NOT NULL,
+	role            TEXT NOT NULL,
+	branch          TEXT NOT NULL,
+	create_time     TEXT NOT NULL,
+	text            TEXT NOT NULL,
+	PRIMARY KEY (conversation_id, position)
+);`
+
+// conversationDatabase stores conversations as rows of a SQLite database. Storing a conversation again
+// replaces its rows, so re-running an extraction into the same output updates the database in place.
+type conversationDatabase struct {
+	handle *sql.DB
+	// mutex serializes stores, as SQLite takes one writer at a time.
+	mutex sync.Mutex
+}
+
+func openConversationDatabase(databasePath string) (*conversationDatabase, error) {
+	handle, openErr := sql.Open(sqliteDriver, databasePath)
+	if openErr != nil {
+		return nil, fmt.Errorf("open database %q: %w", databasePath, openErr)
+	}
+	if _, schemaErr := handle.Exec(databaseSchema); schemaErr != nil {
+		handle.Close()
+		return nil, fmt.Errorf("create database schema in %q: %w", databasePath, schemaErr)
+	}
+	return &conversationDatabase{handle: handle}, nil
+}
+
+func (database *conversationDatabase) store(conversation model.Conversation, messages []utils.Message, serialized []byte, folder string) error {
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	transaction, beginErr := database.handle.Begin()
+	if beginErr != nil {
+		return fmt.Errorf("store conversation %s: %w", conversation.ID, beginErr)
+	}
+	defer transaction.Rollback()
+
+	if _, deleteErr := transaction.Exec(`DELETE FROM messages WHERE conversation_id = ?`, conversation.ID); deleteErr != nil {
+		return fmt.Errorf("store conversation %s: %w", conversation.ID, deleteErr)
+	}
+	_, insertErr := transaction.Exec(`INSERT OR REPLACE INTO conversations (id, title, create_time, update_time, model, folder, json) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		conversation.ID, conversation.Title, formatDatabaseTime(conversation.CreateTime), formatDatabaseTime(conversation.UpdateTime),
+		model.Model(conversation), folder, string(serialized))
+	if insertErr != nil {
+		return fmt.Errorf("store conversation %s: %w", conversation.ID, insertErr)
+	}
+	for index, message := range messages {
+		_, messageErr := transaction.Exec(`INSERT INTO messages (conversation_id, position, role, branch, create_time, text) VALUES (?, ?, ?, ?, ?, ?)`,
+			conversation.ID, index+1, message.Role, message.Branch, formatDatabaseTime(message.CreateTime), message.Text)
+		if messageErr != nil {
+			return fmt.Errorf("store conversation %s: %w", conversation.ID, messageErr)
+		}
+	}
+	return transaction.Commit()
+}
+
+func (database *conversationDatabase) close() error {
+	return database.handle.Close()
+}
+
+// formatDatabaseTime renders a time as RFC 3339 so it sorts as text, or "" when it is unknown.
+func formatDatabaseTime(moment time.Time) string {
+	if moment.IsZero() {
+		return ""
+	}
+	return moment.Format(databaseTimeLayout)
+}
@@ -0,0 +1,85 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// conversationSet collects matched conversations across several archives, keeping only the most recently
+// updated copy of each conversation id. A newer copy that no longer matches evicts an older matching one.
+type conversationSet struct {
+	latest  map[string]time.Time
+	matched map[string]filters.Candidate
+	order   []string
+}
+
+func newConversationSet() *conversationSet {
+	return &conversationSet{latest: make(map[string]time.Time), matched: make(map[string]filters.Candidate)}
+}
+
+// offer records that a copy of the candidate's conversation was seen and adds it when matches is true
+// and no newer copy exists.
+func (set *conversationSet) offer(candidate filters.Candidate, matches bool) {
+	key := candidate.Conversation.ID
+	if key == "" {
+		key = fmt.Sprintf("#%d", len(set.order))
+	} else {
+		updated := candidate.Conversation.UpdateTime
+		if previous, seen := set.latest[key]; seen && !updated.After(previous) {
+			return
+		}
+		set.latest[key] = updated
+		delete(set.matched, key)
+	}
+	if !matches {
+		return
+	}
+	if _, listed := set.matched[key]; !listed {
+		set.order = append(set.order, key)
+	}
+	set.matched[key] = candidate
+}
+
+// candidates returns the surviving matches in the order they were first seen.
+func (set *conversationSet) candidates() []filters.Candidate {
+	result := make([]filters.Candidate, 0, len(set.matched))
+	for _, key := range set.order {
+		if candidate, kept := set.matched[key]; kept {
+			result = append(result, candidate)
+			delete(set.matched, key)
+		}
+	}
+	return result
+}
+
+// newestConversations returns every conversation of the sources, opened from paths, keeping the most recently
+// updated copy of a conversation present in several of them, in the order they were first seen. Records that
+// do not decode are logged and skipped; with several sources, those without conversations are passed over.
+func newestConversations(ctx context.Context, logger *zap.Logger, sources []*archive.Archive, paths []string) ([]filters.Candidate, error) {
+	collected := newConversationSet()
+	for index, source := range sources {
+		if len(sources) > 1 && !source.HasConversations() {
+			continue
+		}
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				logger.Error("decode conversation", zap.Error(decodeErr))
+				return nil
+			}
+			collected.offer(filters.Candidate{Conversation: conversation, Serialized: serialized, Origin: origin}, true)
+			return nil
+		})
+		if scanErr != nil {
+			return nil, fmt.Errorf("%s: %w", paths[index], scanErr)
+		}
+	}
+	return collected.candidates(), nil
+}
@@ -0,0 +1,93 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func conversationRecord(conversationID string, title string, updateUnix int) string {
+	return fmt.Sprintf(`{"id":%q,"title":%q,"update_time":%d,"mapping":{},"current_node":""}`, conversationID, title, updateUnix)
+}
+
+func TestNewestConversations(t *testing.T) {
+	testCases := []struct {
+		name           string
+		exports        [][]string
+		expectedTitles []string
+	}{
+		{
+			name:           "one export keeps its order",
+			exports:        [][]string{{conversationRecord("c1", "first", 100), conversationRecord("c2", "second", 100)}},
+			expectedTitles: []string{"first", "second"},
+		},
+		{
+			name: "newest copy wins across exports",
+			exports: [][]string{
+				{conversationRecord("c1", "old", 100), conversationRecord("c2", "only", 100)},
+				{conversationRecord("c1", "new", 200)},
+			},
+			expectedTitles: []string{"new", "only"},
+		},
+		{
+			name: "older copy in a later export is ignored",
+			exports: [][]string{
+				{conversationRecord("c1", "new", 200)},
+				{conversationRecord("c1", "old", 100)},
+			},
+			expectedTitles: []string{"new"},
+		},
+		{
+			name:           "conversations without an id are all kept",
+			exports:        [][]string{{conversationRecord("", "first", 100)}, {conversationRecord("", "second", 100)}},
+			expectedTitles: []string{"first", "second"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			folder := t.TempDir()
+			var inputSettings InputSettings
+			for position, records := range testCase.exports {
+				exportPath := filepath.Join(folder, fmt.Sprintf("export%d.json", position))
+				if writeErr := os.WriteFile(exportPath, []byte("["+strings.Join(records, ",")+"]"), 0o600); writeErr != nil {
+					t.Fatalf("WriteFile: %v", writeErr)
+				}
+				inputSettings.Paths = append(inputSettings.Paths, exportPath)
+			}
+			sources, openErr := openSources(context.Background(), inputSettings)
+			if openErr != nil {
+				t.Fatalf("openSources: %v", openErr)
+			}
+			defer closeSources(sources)
+			conversations, collectErr := newestConversations(context.Background(), zap.NewNop(), sources, inputSettings.Paths)
+			if collectErr != nil {
+				t.Fatalf("newestConversations: %v", collectErr)
+			}
+			var titles []string
+			for _, candidate := range conversations {
+				titles = append(titles, candidate.Conversation.Title)
+			}
+			if !slices.Equal(titles, testCase.expectedTitles) {
+				t.Errorf("titles = %q, want %q", titles, testCase.expectedTitles)
+			}
+		})
+	}
+}
+
+func TestOpenSourcesFailsOnMissingExport(t *testing.T) {
+	exportPath := filepath.Join(t.TempDir(), "export.json")
+	if writeErr := os.WriteFile(exportPath, []byte("[]"), 0o600); writeErr != nil {
+		t.Fatalf("WriteFile: %v", writeErr)
+	}
+	inputSettings := InputSettings{Paths: []string{exportPath, filepath.Join(t.TempDir(), "missing.zip")}}
+	if sources, openErr := openSources(context.Background(), inputSettings); openErr == nil {
+		closeSources(sources)
+		t.Fatal("openSources succeeded with a missing export")
+	}
+}
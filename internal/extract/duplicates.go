@@ -0,0 +1,213 @@
+package extract
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+	"openai_extract/internal/semantic"
+	"openai_extract/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDuplicateThreshold is the transcript similarity from which two conversations count as near duplicates.
+const DefaultDuplicateThreshold = 0.9
+
+// DedupeSettings configures duplicate detection.
+type DedupeSettings struct {
+	// Threshold is the cosine similarity of two transcripts from which they count as near duplicates;
+	// identical transcripts are always duplicates.
+	Threshold float64
+	// Format selects how the duplicate report is printed.
+	Format render.StatsFormat
+	// OutputPath, when set, receives the exports without the duplicates, as merge writes them.
+	OutputPath string
+}
+
+// dedupeEntry is a conversation considered for duplicate detection.
+type dedupeEntry struct {
+	candidate filters.Candidate
+	digest    [sha256.Size]byte
+	vector    semantic.Vector
+	messages  int
+}
+
+// RunDedupe finds conversations holding the same or nearly the same transcript, such as a prompt retried in
+// a new chat, within and across the exports, and prints them in groups. Copies of one conversation id across
+// exports are reduced to the newest first, as everywhere else. In each group the conversation with the most
+// messages is kept, the most recently updated on a tie; with an output path the exports are written without
+// the others.
+func RunDedupe(ctx context.Context, inputSettings InputSettings, settings DedupeSettings) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+
+	conversations, collectErr := newestConversations(ctx, logger, sources, inputSettings.Paths)
+	if collectErr != nil {
+		return collectErr
+	}
+
+	groups, duplicateIndexes := findDuplicates(conversations, settings.Threshold)
+	rendered, renderErr := render.RenderDuplicates(groups, settings.Format)
+	if renderErr != nil {
+		return renderErr
+	}
+	if _, writeErr := os.Stdout.Write(rendered); writeErr != nil {
+		return writeErr
+	}
+	if settings.OutputPath == "" {
+		return nil
+	}
+
+	kept := make([]filters.Candidate, 0, len(conversations)-len(duplicateIndexes))
+	for index, candidate := range conversations {
+		if _, duplicate := duplicateIndexes[index]; !duplicate {
+			kept = append(kept, candidate)
+		}
+	}
+	if writeErr := writeMergedExport(settings.OutputPath, sources, kept); writeErr != nil {
+		return writeErr
+	}
+	logger.Info("wrote deduplicated export",
+		zap.String("output", settings.OutputPath), zap.Int("conversations", len(kept)), zap.Int("removed", len(duplicateIndexes)))
+	return nil
+}
+
+// findDuplicates groups the conversations whose transcripts are identical or at least threshold similar and
+// returns the groups with the indexes of the conversations not kept. Conversations linked only through a chain
+// of similar ones are considered together, but a group holds just the members within threshold of the one it
+// keeps; the others form groups of their own around the best of them. Conversations without any dialogue are
+// left alone.
+func findDuplicates(conversations []filters.Candidate, threshold float64) ([]render.DuplicateGroup, map[int]struct{}) {
+	embedder := semantic.HashingEmbedder{}
+	entries := make([]dedupeEntry, len(conversations))
+	parents := make([]int, len(conversations))
+	for index, candidate := range conversations {
+		parents[index] = index
+		messages := model.ActiveBranch(candidate.Conversation)
+		text := utils.DialogueText(messages)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		entries[index] = dedupeEntry{
+			candidate: candidate,
+			digest:    sha256.Sum256([]byte(strings.Join(strings.Fields(text), " "))),
+			vector:    embedder.Embed(text),
+			messages:  utils.CountDialogueMessages(messages),
+		}
+	}
+
+	var find func(index int) int
+	find = func(index int) int {
+		if parents[index] != index {
+			parents[index] = find(parents[index])
+		}
+		return parents[index]
+	}
+	union := func(left int, right int) {
+		if leftRoot, rightRoot := find(left), find(right); leftRoot != rightRoot {
+			parents[max(leftRoot, rightRoot)] = min(leftRoot, rightRoot)
+		}
+	}
+	vectors := make([]semantic.Vector, len(entries))
+	firstByDigest := make(map[[sha256.Size]byte]int)
+	for index, entry := range entries {
+		if entry.vector == nil {
+			continue
+		}
+		vectors[index] = entry.vector
+		if first, seen := firstByDigest[entry.digest]; seen {
+			union(first, index)
+		} else {
+			firstByDigest[entry.digest] = index
+		}
+	}
+	for _, pair := range semantic.SimilarPairs(vectors, threshold) {
+		union(pair.Left, pair.Right)
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for index := range entries {
+		if entries[index].vector == nil {
+			continue
+		}
+		root := find(index)
+		if _, seen := members[root]; !seen {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], index)
+	}
+
+	var groups []render.DuplicateGroup
+	duplicateIndexes := make(map[int]struct{})
+	for _, root := range roots {
+		remaining := members[root]
+		for len(remaining) >= 2 {
+			keep := remaining[0]
+			for _, index := range remaining[1:] {
+				if preferredDuplicate(entries[index], entries[keep]) {
+					keep = index
+				}
+			}
+			report := render.DuplicateGroup{Keep: duplicateConversation(entries[keep], entries[keep])}
+			var distant []int
+			for _, index := range remaining {
+				if index == keep {
+					continue
+				}
+				if !duplicateOf(entries[index], entries[keep], threshold) {
+					distant = append(distant, index)
+					continue
+				}
+				report.Duplicates = append(report.Duplicates, duplicateConversation(entries[index], entries[keep]))
+				duplicateIndexes[index] = struct{}{}
+			}
+			if len(report.Duplicates) > 0 {
+				groups = append(groups, report)
+			}
+			remaining = distant
+		}
+	}
+	return groups, duplicateIndexes
+}
+
+// duplicateOf reports whether entry duplicates kept: an identical transcript or one at least threshold similar.
+func duplicateOf(entry dedupeEntry, kept dedupeEntry, threshold float64) bool {
+	return entry.digest == kept.digest || semantic.Cosine(entry.vector, kept.vector) >= threshold
+}
+
+// preferredDuplicate reports whether candidate is the better copy to keep than current: more messages, or as
+// many and updated later.
+func preferredDuplicate(candidate dedupeEntry, current dedupeEntry) bool {
+	if candidate.messages != current.messages {
+		return candidate.messages > current.messages
+	}
+	return candidate.candidate.Conversation.UpdateTime.After(current.candidate.Conversation.UpdateTime)
+}
+
+func duplicateConversation(entry dedupeEntry, kept dedupeEntry) render.DuplicateConversation {
+	conversation := entry.candidate.Conversation
+	return render.DuplicateConversation{
+		ID:         conversation.ID,
+		Title:      conversation.Title,
+		CreateTime: conversation.CreateTime,
+		Messages:   entry.messages,
+		Similarity: min(semantic.Cosine(entry.vector, kept.vector), 1),
+		Exact:      entry.digest == kept.digest,
+	}
+}
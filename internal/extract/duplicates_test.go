@@ -0,0 +1,121 @@
+package extract
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/semantic"
+)
+
+// chainTranscript returns thirty numbered words starting at first, so transcripts starting a few words apart
+// overlap less the further apart they start.
+func chainTranscript(first int) string {
+	words := make([]string, 0, 30)
+	for number := first; number < first+30; number++ {
+		words = append(words, fmt.Sprintf("word%02d", number))
+	}
+	return strings.Join(words, " ")
+}
+
+func duplicateCandidate(t *testing.T, conversationID string, updateUnix int, transcript string) filters.Candidate {
+	t.Helper()
+	record := fmt.Sprintf(`{"id":%q,"title":%q,"update_time":%d,"mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":[%q]}}}},"current_node":"m1"}`,
+		conversationID, conversationID, updateUnix, transcript)
+	conversation, decodeErr := model.Decode([]byte(record))
+	if decodeErr != nil {
+		t.Fatalf("Decode: %v", decodeErr)
+	}
+	return filters.Candidate{Conversation: conversation, Serialized: []byte(record)}
+}
+
+func TestFindDuplicatesRemovesOnlyMembersNearTheKeptOne(t *testing.T) {
+	embedder := semantic.HashingEmbedder{}
+	near := semantic.Cosine(embedder.Embed(chainTranscript(1)), embedder.Embed(chainTranscript(4)))
+	far := semantic.Cosine(embedder.Embed(chainTranscript(1)), embedder.Embed(chainTranscript(7)))
+	if far >= near {
+		t.Fatalf("chain transcripts are not ordered by similarity: near %.3f, far %.3f", near, far)
+	}
+	threshold := (near + far) / 2
+	testCases := []struct {
+		name               string
+		conversations      []filters.Candidate
+		expectedGroups     [][]string
+		expectedDuplicates []string
+	}{
+		{
+			name: "chain kept at its end",
+			conversations: []filters.Candidate{
+				duplicateCandidate(t, "a", 300, chainTranscript(1)),
+				duplicateCandidate(t, "b", 200, chainTranscript(4)),
+				duplicateCandidate(t, "c", 100, chainTranscript(7)),
+			},
+			expectedGroups:     [][]string{{"a", "b"}},
+			expectedDuplicates: []string{"b"},
+		},
+		{
+			name: "chain kept in its middle",
+			conversations: []filters.Candidate{
+				duplicateCandidate(t, "a", 100, chainTranscript(1)),
+				duplicateCandidate(t, "b", 300, chainTranscript(4)),
+				duplicateCandidate(t, "c", 200, chainTranscript(7)),
+			},
+			expectedGroups:     [][]string{{"b", "a", "c"}},
+			expectedDuplicates: []string{"a", "c"},
+		},
+		{
+			name: "distant members form their own group",
+			conversations: []filters.Candidate{
+				duplicateCandidate(t, "a", 400, chainTranscript(1)),
+				duplicateCandidate(t, "b", 100, chainTranscript(4)),
+				duplicateCandidate(t, "c", 300, chainTranscript(7)),
+				duplicateCandidate(t, "d", 200, chainTranscript(7)),
+			},
+			expectedGroups:     [][]string{{"a", "b"}, {"c", "d"}},
+			expectedDuplicates: []string{"b", "d"},
+		},
+		{
+			name: "exact copies",
+			conversations: []filters.Candidate{
+				duplicateCandidate(t, "a", 100, chainTranscript(1)),
+				duplicateCandidate(t, "b", 200, chainTranscript(1)),
+			},
+			expectedGroups:     [][]string{{"b", "a"}},
+			expectedDuplicates: []string{"a"},
+		},
+		{
+			name: "unrelated conversations",
+			conversations: []filters.Candidate{
+				duplicateCandidate(t, "a", 100, chainTranscript(1)),
+				duplicateCandidate(t, "b", 200, chainTranscript(40)),
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			groups, duplicateIndexes := findDuplicates(testCase.conversations, threshold)
+			var groupIDs [][]string
+			for _, group := range groups {
+				ids := []string{group.Keep.ID}
+				for _, duplicate := range group.Duplicates {
+					ids = append(ids, duplicate.ID)
+				}
+				groupIDs = append(groupIDs, ids)
+			}
+			if !slices.EqualFunc(groupIDs, testCase.expectedGroups, slices.Equal) {
+				t.Errorf("groups = %v, want %v", groupIDs, testCase.expectedGroups)
+			}
+			var duplicateIDs []string
+			for index := range duplicateIndexes {
+				duplicateIDs = append(duplicateIDs, testCase.conversations[index].Conversation.ID)
+			}
+			slices.Sort(duplicateIDs)
+			if !slices.Equal(duplicateIDs, testCase.expectedDuplicates) {
+				t.Errorf("removed = %v, want %v", duplicateIDs, testCase.expectedDuplicates)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+	"openai_extract/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	filesFolderName          = "files"
+	messagesFolderName       = "messages"
+	codeFolderName           = "code"
+	dalleFolderName          = "dalle"
+	canvasFolderName         = "canvas"
+	analysisFolderName       = "analysis"
+	voiceFolderName          = "voice"
+	dallePromptsName         = "prompts.json"
+	folderDateLayout         = "2006-01-02"
+	untitledFolderTimeLayout = "1504"
+	// linkedFileJobs bounds how many linked files of one conversation are copied at once.
+	linkedFileJobs = 4
+)
+
+type folderWriter struct {
+	logger           *zap.Logger
+	outputRoot       string
+	outputSettings   OutputSettings
+	templateRenderer *render.TemplateRenderer
+	format           render.ConversationFormat
+	usedFolderNames  map[string]int
+	// database receives the conversations when the format is a database; nil otherwise.
+	database *conversationDatabase
+}
+
+func newFolderWriter(logger *zap.Logger, outputRoot string, outputSettings OutputSettings) (*folderWriter, error) {
+	writer := &folderWriter{
+		logger:          logger,
+		outputRoot:      outputRoot,
+		outputSettings:  outputSettings,
+		usedFolderNames: make(map[string]int),
+	}
+	formatName := outputSettings.Format
+	if formatName == "" {
+		formatName = render.DefaultFormat
+	}
+	format, formatErr := render.LookupFormat(formatName)
+	if formatErr != nil {
+		return nil, formatErr
+	}
+	writer.format = format
+	if format.Database {
+		opened, openErr := openConversationDatabase(filepath.Join(outputRoot, format.FileName))
+		if openErr != nil {
+			return nil, openErr
+		}
+		writer.database = opened
+	}
+	if outputSettings.TemplatePath != "" {
+		loaded, templateErr := render.LoadTemplate(outputSettings.TemplatePath)
+		if templateErr != nil {
+			return nil, templateErr
+		}
+		writer.templateRenderer = loaded
+	}
+	return writer, nil
+}
+
+// close releases the database of database formats; folder documents need no closing.
+func (writer *folderWriter) close() error {
+	if writer.database == nil {
+		return nil
+	}
+	return writer.database.close()
+}
+
+// write writes one conversation into the folder folderName, named by nextFolderName beforehand so names do not
+// depend on the order concurrent writes finish in. It is safe for concurrent use with distinct folder names.
+func (writer *folderWriter) write(candidate filters.Candidate, folderName string, hits []filters.Hit) (string, error) {
+	conversation, serialized, source := candidate.Conversation, candidate.Serialized, candidate.Archive
+	targetFolder := filepath.Join(writer.outputRoot, folderName)
+	if mkErr := utils.EnsureDir(targetFolder); mkErr != nil {
+		return "", fmt.Errorf("create output subfolder %q: %w", targetFolder, mkErr)
+	}
+
+	messages := writer.outputSettings.renderedMessages(conversation)
+	spans := highlightSpans(hits)
+	if writer.database != nil {
+		if storeErr := writer.database.store(conversation, messages, serialized, filepath.Base(targetFolder)); storeErr != nil {
+			return "", storeErr
+		}
+	} else {
+		document, renderErr := writer.format.Render(render.DocumentInput{
+			Conversation: conversation,
+			Messages:     messages,
+			Serialized:   serialized,
+			Highlights:   spans,
+			Highlight:    writer.outputSettings.Highlight,
+			Timestamps:   writer.outputSettings.Timestamps,
+		})
+		if renderErr != nil {
+			return "", fmt.Errorf("render %s: %w", writer.format.FileName, renderErr)
+		}
+		if writeErr := utils.WriteFile(filepath.Join(targetFolder, writer.format.FileName), document); writeErr != nil {
+			return "", writeErr
+		}
+	}
+
+	attachmentNames := writer.writeLinkedFiles(targetFolder, source, filters.CollectLinkedFiles(candidate))
+	writer.writeSidecar(filepath.Join(targetFolder, sidecarFileName), conversationSidecar(candidate, attachmentNames))
+	transcript := render.HighlightMessages(messages, spans, writer.outputSettings.Highlight)
+
+	if writer.templateRenderer != nil {
+		rendered, renderErr := writer.templateRenderer.Render(render.NewTemplateData(conversation, transcript, attachmentNames))
+		renderedPath := filepath.Join(targetFolder, writer.templateRenderer.OutputName())
+		if renderErr != nil {
+			writer.logger.Error("render template", zap.String("path", renderedPath), zap.Error(renderErr))
+		} else if writeErr := utils.WriteFile(renderedPath, rendered); writeErr != nil {
+			writer.logger.Error("write rendered template", zap.String("path", renderedPath), zap.Error(writeErr))
+		}
+	}
+
+	if writer.outputSettings.SplitMessages {
+		writer.writeRenderedFiles(filepath.Join(targetFolder, messagesFolderName), render.SplitMessages(transcript, writer.outputSettings.Timestamps))
+	}
+	if writer.outputSettings.ExtractCode {
+		writer.writeRenderedFiles(filepath.Join(targetFolder, codeFolderName), render.ExtractCodeFiles(messages))
+	}
+
+	// Tool artifacts come from every message on the rendered branches, whatever transcripts are limited to.
+	branchMessages := model.Messages(conversation, writer.outputSettings.Branches)
+	canvasDocuments := render.ExtractCanvasDocuments(branchMessages, writer.outputSettings.CanvasRevisions)
+	writer.writeRenderedFiles(filepath.Join(targetFolder, canvasFolderName), canvasDocuments)
+	if citations := render.CollectCitations(branchMessages); len(citations) > 0 {
+		sourcesPath := filepath.Join(targetFolder, render.SourcesFileName)
+		if writeErr := utils.WriteFile(sourcesPath, render.RenderSources(conversation.Title, citations)); writeErr != nil {
+			writer.logger.Error("write sources", zap.String("path", sourcesPath), zap.Error(writeErr))
+		}
+	}
+	if writer.outputSettings.Analysis != render.AnalysisNone {
+		writer.writeAnalysis(targetFolder, branchMessages)
+	}
+	if writer.outputSettings.ExtractDalle {
+		images := render.DalleImages(branchMessages)
+		writer.writeDalleImages(filepath.Join(targetFolder, dalleFolderName), source, candidate.Origin.Root, images)
+	}
+	if writer.outputSettings.ExtractVoice {
+		writer.writeVoice(filepath.Join(targetFolder, voiceFolderName), source, candidate.Origin.Root, conversation.Title, branchMessages)
+	}
+	return targetFolder, nil
+}
+
+// writeVoice copies the audio clips of a voice conversation under voice/ and writes the merged transcript beside them.
+func (writer *folderWriter) writeVoice(voiceFolder string, source *archive.Archive, root string, title string, messages []utils.Message) {
+	transcript := render.RenderVoiceTranscript(title, messages)
+	var clipEntries []string
+	for _, clip := range render.VoiceClips(messages) {
+		entries := source.FileEntries(root, clip.FileID)
+		if len(entries) == 0 {
+			writer.logger.Warn("voice clip not in export", zap.String("file", clip.FileID), zap.String("message", clip.MessageID))
+			continue
+		}
+		clipEntries = append(clipEntries, entries[0])
+	}
+	if transcript == nil && len(clipEntries) == 0 {
+		return
+	}
+	if mkErr := utils.EnsureDir(voiceFolder); mkErr != nil {
+		writer.logger.Error("create voice subfolder", zap.String("folder", voiceFolder), zap.Error(mkErr))
+		return
+	}
+	if transcript != nil {
+		transcriptPath := filepath.Join(voiceFolder, render.VoiceTranscriptName)
+		if writeErr := utils.WriteFile(transcriptPath, transcript); writeErr != nil {
+			writer.logger.Error("write voice transcript", zap.String("path", transcriptPath), zap.Error(writeErr))
+		}
+	}
+	if prefetchErr := source.Prefetch(clipEntries); prefetchErr != nil {
+		writer.logger.Error("read voice clips", zap.Error(prefetchErr))
+	}
+	for _, archivePath := range clipEntries {
+		_, targetPath, pathErr := writer.attachmentTarget(voiceFolder, archivePath)
+		if pathErr != nil {
+			writer.logger.Warn("skip voice clip", zap.String("archivePath", archivePath), zap.Error(pathErr))
+			continue
+		}
+		if writeErr := copyArchiveEntry(source, archivePath, targetPath); writeErr != nil {
+			writer.logger.Error("write voice clip", zap.String("archivePath", archivePath), zap.String("targetPath", targetPath), zap.Error(writeErr))
+		}
+	}
+}
+
+// writeAnalysis writes the code interpreter session as analysis.ipynb in the conversation folder, or as numbered
+// script and output files under analysis/.
+func (writer *folderWriter) writeAnalysis(targetFolder string, messages []utils.Message) {
+	rendered, renderErr := render.RenderAnalysis(render.ExtractAnalysisCells(messages), writer.outputSettings.Analysis)
+	if renderErr != nil {
+		writer.logger.Error("render analysis", zap.String("folder", targetFolder), zap.Error(renderErr))
+		return
+	}
+	if writer.outputSettings.Analysis == render.AnalysisScripts {
+		targetFolder = filepath.Join(targetFolder, analysisFolderName)
+	}
+	writer.writeRenderedFiles(targetFolder, rendered)
+}
+
+// writeDalleImages copies each generated image out of the archive and lists it with its prompt and seed in
+// prompts.json. Images missing from the export are still listed, with an empty file name.
+func (writer *folderWriter) writeDalleImages(dalleFolder string, source *archive.Archive, root string, images []render.DalleImage) {
+	if len(images) == 0 {
+		return
+	}
+	if mkErr := utils.EnsureDir(dalleFolder); mkErr != nil {
+		writer.logger.Error("create dalle subfolder", zap.String("folder", dalleFolder), zap.Error(mkErr))
+		return
+	}
+	for index, image := range images {
+		entries := source.FileEntries(root, image.FileID)
+		if len(entries) == 0 {
+			writer.logger.Warn("generated image not in export", zap.String("file", image.FileID))
+			continue
+		}
+		if prefetchErr := source.Prefetch(entries[:1]); prefetchErr != nil {
+			writer.logger.Error("read generated image", zap.Error(prefetchErr))
+		}
+		fileName, targetPath, pathErr := writer.attachmentTarget(dalleFolder, entries[0])
+		if pathErr != nil {
+			writer.logger.Warn("skip generated image", zap.String("archivePath", entries[0]), zap.Error(pathErr))
+			continue
+		}
+		if writeErr := copyArchiveEntry(source, entries[0], targetPath); writeErr != nil {
+			writer.logger.Error("write generated image", zap.String("archivePath", entries[0]), zap.String("targetPath", targetPath), zap.Error(writeErr))
+			continue
+		}
+		images[index].File = fileName
+	}
+	encoded, encodeErr := json.MarshalIndent(images, "", "  ")
+	if encodeErr != nil {
+		writer.logger.Error("encode dalle prompts", zap.Error(encodeErr))
+		return
+	}
+	promptsPath := filepath.Join(dalleFolder, dallePromptsName)
+	if writeErr := utils.WriteFile(promptsPath, encoded); writeErr != nil {
+		writer.logger.Error("write dalle prompts", zap.String("path", promptsPath), zap.Error(writeErr))
+	}
+}
+
+func (writer *folderWriter) writeSidecar(sidecarPath string, sidecar map[string]any) {
+	encoded, encodeErr := json.MarshalIndent(sidecar, "", "  ")
+	if encodeErr != nil {
+		writer.logger.Error("encode metadata sidecar", zap.String("path", sidecarPath), zap.Error(encodeErr))
+		return
+	}
+	if writeErr := utils.WriteFile(sidecarPath, encoded); writeErr != nil {
+		writer.logger.Error("write metadata sidecar", zap.String("path", sidecarPath), zap.Error(writeErr))
+	}
+}
+
+// nextFolderName names a conversation folder after its start date and slugified title, falling back to the start
+// time for untitled conversations; repeated names get a numeric suffix.
+func (writer *folderWriter) nextFolderName(conversation model.Conversation) string {
+	return nextConversationName(writer.usedFolderNames, conversation)
+}
+
+// nextConversationName returns the date and title name of a conversation, suffixed with a number when used
+// already holds it, and records it in used.
+func nextConversationName(used map[string]int, conversation model.Conversation) string {
+	label := utils.Slugify(conversation.Title)
+	if label == "" {
+		label = conversation.CreateTime.Format(untitledFolderTimeLayout)
+	}
+	baseName := conversation.CreateTime.Format(folderDateLayout) + "_" + label
+	if used[baseName] > 0 {
+		used[baseName]++
+		return fmt.Sprintf("%s_%d", baseName, used[baseName])
+	}
+	used[baseName] = 1
+	return baseName
+}
+
+// writeLinkedFiles copies the linked entries into the files folder of targetFolder, up to linkedFileJobs at
+// once, each streamed from the archive rather than read whole, and returns the names of those written.
+func (writer *folderWriter) writeLinkedFiles(targetFolder string, source *archive.Archive, linked []string) []string {
+	attachmentNames := make([]string, 0, len(linked))
+	if len(linked) == 0 {
+		return attachmentNames
+	}
+	filesFolder := filepath.Join(targetFolder, filesFolderName)
+	if mkErr := utils.EnsureDir(filesFolder); mkErr != nil {
+		writer.logger.Error("create files subfolder", zap.String("folder", filesFolder), zap.Error(mkErr))
+		return attachmentNames
+	}
+	if prefetchErr := source.Prefetch(linked); prefetchErr != nil {
+		writer.logger.Error("read linked files", zap.Error(prefetchErr))
+	}
+	var planned []linkedFile
+	plannedTargets := make(map[string]int)
+	for _, archivePath := range linked {
+		fileName, targetPath, pathErr := writer.attachmentTarget(filesFolder, archivePath)
+		if pathErr != nil {
+			writer.logger.Warn("skip linked file", zap.String("archivePath", archivePath), zap.Error(pathErr))
+			continue
+		}
+		// Entries of the same base name land on one file, and the last one wins, so only that one is copied.
+		if index, taken := plannedTargets[targetPath]; taken {
+			planned[index].archivePath = archivePath
+			continue
+		}
+		plannedTargets[targetPath] = len(planned)
+		planned = append(planned, linkedFile{archivePath: archivePath, fileName: fileName, targetPath: targetPath})
+	}
+	forEachIndex(len(planned), linkedFileJobs, func(index int) {
+		file := &planned[index]
+		if writeErr := copyArchiveEntry(source, file.archivePath, file.targetPath); writeErr != nil {
+			writer.logger.Error("write linked file", zap.String("archivePath", file.archivePath), zap.String("targetPath", file.targetPath), zap.Error(writeErr))
+			return
+		}
+		file.written = true
+	})
+	for _, file := range planned {
+		if file.written {
+			attachmentNames = append(attachmentNames, file.fileName)
+		}
+	}
+	sort.Strings(attachmentNames)
+	return attachmentNames
+}
+
+// linkedFile is an archive entry planned to be copied into a conversation's files folder.
+type linkedFile struct {
+	archivePath string
+	fileName    string
+	targetPath  string
+	written     bool
+}
+
+// attachmentTarget picks where a linked archive entry is written. Unless the archive is trusted, names that
+// could climb out of the files folder are rejected rather than cleaned up; a trusted entry is written under its
+// base name, which still may not resolve outside the files folder.
+func (writer *folderWriter) attachmentTarget(filesFolder string, archivePath string) (string, string, error) {
+	fileName := filepath.Base(archivePath)
+	if !writer.outputSettings.TrustArchive {
+		safeName, nameErr := utils.SafeArchiveFileName(archivePath)
+		if nameErr != nil {
+			return "", "", nameErr
+		}
+		fileName = safeName
+	}
+	targetPath, containErr := utils.ContainedPath(filesFolder, fileName)
+	if containErr != nil {
+		return "", "", containErr
+	}
+	return fileName, targetPath, nil
+}
+
+func copyArchiveEntry(source *archive.Archive, archivePath string, targetPath string) error {
+	reader, openErr := source.Open(archivePath)
+	if openErr != nil {
+		return openErr
+	}
+	defer reader.Close()
+	return utils.CopyToFile(targetPath, reader)
+}
+
+// writeRenderedFiles writes each rendered file into folder, skipping and logging any whose name is not a local
+// path or would resolve outside folder, since names can carry text from the export.
+func (writer *folderWriter) writeRenderedFiles(folder string, renderedFiles []render.RenderedFile) {
+	if len(renderedFiles) == 0 {
+		return
+	}
+	if mkErr := utils.EnsureDir(folder); mkErr != nil {
+		writer.logger.Error("create output subfolder", zap.String("folder", folder), zap.Error(mkErr))
+		return
+	}
+	for _, renderedFile := range renderedFiles {
+		renderedPath, containErr := utils.ContainedPath(folder, renderedFile.Name)
+		if containErr == nil && !filepath.IsLocal(renderedFile.Name) {
+			containErr = fmt.Errorf("rendered file name %q is not local", renderedFile.Name)
+		}
+		if containErr != nil {
+			writer.logger.Error("skip rendered file", zap.String("folder", folder), zap.Error(containErr))
+			continue
+		}
+		if writeErr := utils.WriteFile(renderedPath, renderedFile.Content); writeErr != nil {
+			writer.logger.Error("write rendered file", zap.String("path", renderedPath), zap.Error(writeErr))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"openai_extract/internal/render"
+
+	"go.uber.org/zap"
+)
+
+func TestAttachmentTarget(t *testing.T) {
+	filesFolder := filepath.Join(t.TempDir(), "files")
+	testCases := []struct {
+		name         string
+		trustArchive bool
+		archivePath  string
+		expectedName string
+		expectErr    bool
+	}{
+		{name: "safe name", archivePath: "file-abc-photo.png", expectedName: "file-abc-photo.png"},
+		{name: "climbing path rejected", archivePath: "files/../../evil", expectErr: true},
+		{name: "absolute path rejected", archivePath: "/etc/passwd", expectErr: true},
+		{name: "drive path rejected", archivePath: `C:\evil.dll`, expectErr: true},
+		{name: "backslash name rejected", archivePath: `files\evil.png`, expectErr: true},
+		{name: "nul name rejected", archivePath: "evil\x00.png", expectErr: true},
+		{name: "parent name rejected", archivePath: "files/..", expectErr: true},
+		{name: "trusted climbing path keeps base name", trustArchive: true, archivePath: "files/../../evil", expectedName: "evil"},
+		{name: "trusted absolute path keeps base name", trustArchive: true, archivePath: "/etc/passwd", expectedName: "passwd"},
+		{name: "trusted backslash name", trustArchive: true, archivePath: `files\evil.png`, expectedName: `files\evil.png`},
+		{name: "trusted parent name still contained", trustArchive: true, archivePath: "files/..", expectErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			writer := &folderWriter{outputSettings: OutputSettings{TrustArchive: testCase.trustArchive}}
+			fileName, targetPath, targetErr := writer.attachmentTarget(filesFolder, testCase.archivePath)
+			if testCase.expectErr {
+				if targetErr == nil {
+					t.Fatalf("attachmentTarget(%q) = %q, want an error", testCase.archivePath, targetPath)
+				}
+				return
+			}
+			if targetErr != nil {
+				t.Fatalf("attachmentTarget(%q): %v", testCase.archivePath, targetErr)
+			}
+			if fileName != testCase.expectedName {
+				t.Errorf("file name = %q, want %q", fileName, testCase.expectedName)
+			}
+			if expectedPath := filepath.Join(filesFolder, testCase.expectedName); targetPath != expectedPath {
+				t.Errorf("target path = %q, want %q", targetPath, expectedPath)
+			}
+		})
+	}
+}
+
+func TestWriteRenderedFilesStaysInFolder(t *testing.T) {
+	testCases := []struct {
+		name        string
+		fileName    string
+		expectWrite bool
+	}{
+		{name: "plain name", fileName: "001-user.md", expectWrite: true},
+		{name: "climbing name", fileName: "../escaped.md"},
+		{name: "nested climbing name", fileName: "a/../../escaped.md"},
+		{name: "absolute name", fileName: "/tmp/escaped.md"},
+		{name: "empty name", fileName: ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root := t.TempDir()
+			folder := filepath.Join(root, "messages")
+			writer := &folderWriter{logger: zap.NewNop()}
+			writer.writeRenderedFiles(folder, []render.RenderedFile{{Name: testCase.fileName, Content: []byte("text")}})
+			if _, statErr := os.Stat(filepath.Join(root, "escaped.md")); statErr == nil {
+				t.Fatal("a rendered file was written outside its folder")
+			}
+			entries, _ := os.ReadDir(folder)
+			if written := len(entries) == 1 && !entries[0].IsDir(); written != testCase.expectWrite {
+				t.Errorf("written = %v, want %v", written, testCase.expectWrite)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/render"
+	"openai_extract/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	matchesFileName    = "matches.txt"
+	hitLineFormat      = "%s\t%s\t%d\t%s"
+	hitHeadingFormat   = "# %s %s"
+	contextSeparator   = "--"
+	contextFieldFormat = "%s:%s:%s: %s"
+)
+
+// reportHits lists where the patterns hit in a conversation, one tab-separated line per hit: message id, role,
+// character offset, and pattern. The list goes to matches.txt in the conversation folder, or to stdout under a
+// heading naming the conversation when no folder was written.
+func reportHits(candidate filters.Candidate, hits []filters.Hit, targetFolder string, logger *zap.Logger) {
+	lines := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		lines = append(lines, fmt.Sprintf(hitLineFormat, hit.MessageID, hit.Role, hit.Offset, hit.Pattern))
+	}
+	if targetFolder == "" {
+		utils.PrintLine(fmt.Sprintf(hitHeadingFormat, candidate.Conversation.ID, candidate.Conversation.Title))
+		for _, line := range lines {
+			utils.PrintLine(line)
+		}
+		return
+	}
+	matchesPath := filepath.Join(targetFolder, matchesFileName)
+	if writeErr := utils.WriteFile(matchesPath, []byte(strings.Join(lines, "\n")+"\n")); writeErr != nil {
+		logger.Error("write matches", zap.String("path", matchesPath), zap.Error(writeErr))
+	}
+}
+
+// highlightSpans groups the byte spans of the message hits by message id, for highlighting rendered transcripts.
+func highlightSpans(hits []filters.Hit) map[string][]render.TextSpan {
+	spans := make(map[string][]render.TextSpan)
+	for _, hit := range hits {
+		if hit.MessageID != "" {
+			spans[hit.MessageID] = append(spans[hit.MessageID], render.TextSpan{Start: hit.Start, End: hit.End})
+		}
+	}
+	return spans
+}
+
+type lineSpan struct {
+	first int
+	last  int
+}
+
+// printContext prints the lines around each hit, grep style: every line is prefixed with the conversation id,
+// role, and message id, overlapping snippets within a text are merged, and snippets are separated by "--".
+func printContext(candidate filters.Candidate, hits []filters.Hit, context int) {
+	type hitText struct {
+		messageID string
+		role      string
+		text      string
+	}
+	var order []hitText
+	spans := make(map[hitText][]lineSpan)
+	for _, hit := range hits {
+		key := hitText{messageID: hit.MessageID, role: hit.Role, text: hit.Text}
+		if _, seen := spans[key]; !seen {
+			order = append(order, key)
+		}
+		first, last := hit.LineSpan(context)
+		spans[key] = append(spans[key], lineSpan{first: first, last: last})
+	}
+	for _, key := range order {
+		lines := strings.Split(key.text, "\n")
+		for _, span := range mergeLineSpans(spans[key]) {
+			utils.PrintLine(contextSeparator)
+			for _, line := range lines[span.first : span.last+1] {
+				utils.PrintLine(fmt.Sprintf(contextFieldFormat, candidate.Conversation.ID, key.role, key.messageID, line))
+			}
+		}
+	}
+}
+
+func mergeLineSpans(spans []lineSpan) []lineSpan {
+	sort.Slice(spans, func(left, right int) bool { return spans[left].first < spans[right].first })
+	var merged []lineSpan
+	for _, span := range spans {
+		if count := len(merged); count > 0 && span.first <= merged[count-1].last+1 {
+			merged[count-1].last = max(merged[count-1].last, span.last)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/index"
+	"openai_extract/internal/utils"
+)
+
+// RunIndex builds the full-text index at inputSettings.IndexPath from the exports, replacing any previous one.
+func RunIndex(ctx context.Context, inputSettings InputSettings) error {
+	sources, fingerprintErr := indexSources(inputSettings)
+	if fingerprintErr != nil {
+		return fingerprintErr
+	}
+	archives, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(archives)
+	indexed, buildErr := index.Build(ctx, inputSettings.IndexPath, sources, archives)
+	if buildErr != nil {
+		return buildErr
+	}
+	utils.PrintLine(fmt.Sprintf("indexed %d conversations into %s", indexed, inputSettings.IndexPath))
+	return nil
+}
+
+// scanIndex hands the conversations of the index that the prefilter admits to visit, after checking that the
+// index was built from the exports being searched.
+func scanIndex(inputSettings InputSettings, prefilter filters.Prefilter, visit func(serialized []byte, origin archive.Origin) error) error {
+	sources, fingerprintErr := indexSources(inputSettings)
+	if fingerprintErr != nil {
+		return fingerprintErr
+	}
+	opened, openErr := index.Open(inputSettings.IndexPath)
+	if openErr != nil {
+		return openErr
+	}
+	defer opened.Close()
+	if staleErr := opened.CheckSources(sources); staleErr != nil {
+		return fmt.Errorf("%s: %w", inputSettings.IndexPath, staleErr)
+	}
+	return opened.EachCandidate(prefilter, visit)
+}
+
+func indexSources(inputSettings InputSettings) ([]index.Source, error) {
+	for _, archiveFilePath := range inputSettings.Paths {
+		if archive.IsRemote(archiveFilePath) || archive.IsShareURL(archiveFilePath) {
+			return nil, errors.New("--index works with local exports only; download the export first")
+		}
+	}
+	return index.Fingerprint(inputSettings.Paths)
+}
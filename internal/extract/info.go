@@ -0,0 +1,130 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+)
+
+const (
+	exportStampLayout  = "2006-01-02-15-04-05"
+	dateFromFileName   = "file name"
+	dateFromLastUpdate = "latest conversation update"
+	accountEmailField  = "email"
+)
+
+// metadataExtensions mark the export files that are not attachments: conversation and account metadata.
+var metadataExtensions = map[string]struct{}{".json": {}, ".html": {}, ".htm": {}}
+
+// reExportStamp finds the creation stamp ChatGPT puts in export file names, e.g. "<hash>-2024-03-05-18-22-11.zip".
+var reExportStamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}`)
+
+// RunInfo prints one summary per export, each export on its own rather than merged, so several exports can
+// be told apart: export date, account email, conversation count, attachment size, and schema version.
+func RunInfo(ctx context.Context, inputSettings InputSettings, format render.StatsFormat) error {
+	var infos []render.ExportInfo
+	for _, archiveFilePath := range inputSettings.Paths {
+		info, infoErr := exportInfo(ctx, archiveFilePath, inputSettings)
+		if infoErr != nil {
+			return infoErr
+		}
+		infos = append(infos, info)
+	}
+	rendered, renderErr := render.RenderInfo(infos, format)
+	if renderErr != nil {
+		return renderErr
+	}
+	_, writeErr := os.Stdout.Write(rendered)
+	return writeErr
+}
+
+func exportInfo(ctx context.Context, archiveFilePath string, inputSettings InputSettings) (render.ExportInfo, error) {
+	source, openErr := openInput(ctx, archiveFilePath, inputSettings)
+	if openErr != nil {
+		return render.ExportInfo{}, openErr
+	}
+	defer source.Close()
+
+	info := render.ExportInfo{Path: archiveFilePath, AccountEmails: []string{}, SchemaVersions: make(map[string]int)}
+	var lastUpdate time.Time
+	roots := make(map[string]struct{})
+	if source.HasConversations() {
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			info.Conversations++
+			info.SchemaVersions[string(model.DetectSchema(serialized))]++
+			roots[origin.Root] = struct{}{}
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				return nil
+			}
+			updated := conversation.UpdateTime
+			if updated.IsZero() {
+				updated = conversation.CreateTime
+			}
+			if updated.After(lastUpdate) {
+				lastUpdate = updated
+			}
+			return nil
+		})
+		if scanErr != nil {
+			return render.ExportInfo{}, fmt.Errorf("%s: %w", archiveFilePath, scanErr)
+		}
+	}
+
+	if stamp := reExportStamp.FindString(filepath.Base(archiveFilePath)); stamp != "" {
+		if parsed, parseErr := time.Parse(exportStampLayout, stamp); parseErr == nil {
+			info.ExportDate, info.ExportDateSource = parsed, dateFromFileName
+		}
+	}
+	if info.ExportDate.IsZero() && !lastUpdate.IsZero() {
+		info.ExportDate, info.ExportDateSource = lastUpdate, dateFromLastUpdate
+	}
+
+	emails := make(map[string]struct{})
+	for root := range roots {
+		if email, _ := source.Metadata(root).Account[accountEmailField].(string); email != "" {
+			emails[email] = struct{}{}
+		}
+	}
+	for email := range emails {
+		info.AccountEmails = append(info.AccountEmails, email)
+	}
+	sort.Strings(info.AccountEmails)
+
+	for _, name := range source.SupportingEntries() {
+		if _, metadata := metadataExtensions[strings.ToLower(path.Ext(name))]; metadata {
+			continue
+		}
+		info.AttachmentFiles++
+		if size, known := source.Size(name); known {
+			info.AttachmentBytes += size
+		}
+	}
+	info.SchemaVersion = predominantSchema(info.SchemaVersions)
+	return info, nil
+}
+
+// predominantSchema returns the schema version most conversations use, preferring the newer on a tie, or
+// "unknown" for an export without conversations.
+func predominantSchema(counts map[string]int) string {
+	predominant, best := string(model.SchemaUnknown), 0
+	for _, version := range model.KnownSchemaVersions {
+		if count := counts[string(version)]; count > 0 && count >= best {
+			predominant, best = string(version), count
+		}
+	}
+	if counts[string(model.SchemaUnknown)] > best {
+		predominant = string(model.SchemaUnknown)
+	}
+	return predominant
+}
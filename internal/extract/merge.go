@@ -0,0 +1,189 @@
+package extract
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	mergedConversationsFileName = "conversations.json"
+	zipSuffix                   = ".zip"
+)
+
+// entryWriter stores one entry of the merged export, handing write the destination to fill.
+type entryWriter func(name string, write func(destination io.Writer) error) error
+
+// RunMerge combines the exports into one at outputPath, a ZIP archive when the path ends in .zip and a folder
+// otherwise. Each conversation is kept once, by its most recently updated copy, in a conversations.json at the
+// folder of the export it came from, so workspace exports keep one per member. Every other entry is copied
+// from the first export holding it.
+func RunMerge(ctx context.Context, inputSettings InputSettings, outputPath string) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+
+	conversations, collectErr := newestConversations(ctx, logger, sources, inputSettings.Paths)
+	if collectErr != nil {
+		return collectErr
+	}
+
+	if mergeErr := writeMergedExport(outputPath, sources, conversations); mergeErr != nil {
+		return mergeErr
+	}
+	utils.PrintLine(fmt.Sprintf("merged %d conversations from %d exports into %s", len(conversations), len(sources), outputPath))
+	return nil
+}
+
+// writeMergedExport writes the conversations and the supporting entries of the sources as one export at
+// outputPath, a ZIP archive when the path ends in .zip and a folder otherwise.
+func writeMergedExport(outputPath string, sources []*archive.Archive, conversations []filters.Candidate) error {
+	write := func(writeEntry entryWriter) error {
+		return writeMerged(writeEntry, sources, conversations)
+	}
+	if strings.EqualFold(filepath.Ext(outputPath), zipSuffix) {
+		return mergeToZip(outputPath, write)
+	}
+	return mergeToFolder(outputPath, write)
+}
+
+// writeMerged stores the conversations, grouped into one conversations.json per origin folder, followed by
+// the supporting entries of the exports, skipping names an earlier export already provided.
+func writeMerged(writeEntry entryWriter, sources []*archive.Archive, conversations []filters.Candidate) error {
+	byRoot := make(map[string][][]byte)
+	for _, candidate := range conversations {
+		byRoot[candidate.Origin.Root] = append(byRoot[candidate.Origin.Root], candidate.Serialized)
+	}
+	roots := make([]string, 0, len(byRoot))
+	for root := range byRoot {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	written := make(map[string]struct{})
+	for _, root := range roots {
+		name := root + mergedConversationsFileName
+		writeErr := writeEntry(name, func(destination io.Writer) error {
+			return writeConversationArray(destination, byRoot[root])
+		})
+		if writeErr != nil {
+			return fmt.Errorf("write %s: %w", name, writeErr)
+		}
+		written[name] = struct{}{}
+	}
+
+	for _, source := range sources {
+		for _, name := range source.SupportingEntries() {
+			if _, done := written[name]; done {
+				continue
+			}
+			written[name] = struct{}{}
+			writeErr := writeEntry(name, func(destination io.Writer) error {
+				reader, openErr := source.Open(name)
+				if openErr != nil {
+					return openErr
+				}
+				defer reader.Close()
+				_, copyErr := io.Copy(destination, reader)
+				return copyErr
+			})
+			if writeErr != nil {
+				return fmt.Errorf("copy %s: %w", name, writeErr)
+			}
+		}
+	}
+	return nil
+}
+
+func writeConversationArray(destination io.Writer, serialized [][]byte) error {
+	if _, writeErr := io.WriteString(destination, "["); writeErr != nil {
+		return writeErr
+	}
+	for index, conversation := range serialized {
+		separator := "\n"
+		if index > 0 {
+			separator = ",\n"
+		}
+		if _, writeErr := io.WriteString(destination, separator); writeErr != nil {
+			return writeErr
+		}
+		if _, writeErr := destination.Write(conversation); writeErr != nil {
+			return writeErr
+		}
+	}
+	_, writeErr := io.WriteString(destination, "\n]\n")
+	return writeErr
+}
+
+// mergeToZip writes the merged export to a temporary file beside outputPath and renames it into place once
+// complete, so a failed merge never leaves a truncated archive behind.
+func mergeToZip(outputPath string, write func(writeEntry entryWriter) error) error {
+	return utils.WriteFileAtomicStream(outputPath, func(archiveFile io.Writer) error {
+		zipped := zip.NewWriter(archiveFile)
+		writeErr := write(func(name string, fill func(destination io.Writer) error) error {
+			destination, entryErr := zipped.Create(name)
+			if entryErr != nil {
+				return entryErr
+			}
+			return fill(destination)
+		})
+		if writeErr != nil {
+			return writeErr
+		}
+		return zipped.Close()
+	})
+}
+
+// mergeToFolder writes the merged export into outputPath, which must be missing or empty so no stale files
+// mix with the merged ones.
+func mergeToFolder(outputPath string, write func(writeEntry entryWriter) error) error {
+	existing, readErr := os.ReadDir(outputPath)
+	if readErr == nil && len(existing) > 0 {
+		return fmt.Errorf("output folder %s is not empty", outputPath)
+	}
+	if readErr != nil && !errors.Is(readErr, os.ErrNotExist) {
+		return fmt.Errorf("read output folder: %w", readErr)
+	}
+	if mkdirErr := os.MkdirAll(outputPath, 0o755); mkdirErr != nil {
+		return fmt.Errorf("create output folder: %w", mkdirErr)
+	}
+	return write(func(name string, fill func(destination io.Writer) error) error {
+		relative := filepath.FromSlash(path.Clean(name))
+		if !filepath.IsLocal(relative) {
+			return fmt.Errorf("entry name escapes the output folder: %q", name)
+		}
+		target := filepath.Join(outputPath, relative)
+		if mkdirErr := os.MkdirAll(filepath.Dir(target), 0o755); mkdirErr != nil {
+			return mkdirErr
+		}
+		file, createErr := os.Create(target)
+		if createErr != nil {
+			return createErr
+		}
+		fillErr := fill(file)
+		if closeErr := file.Close(); fillErr == nil {
+			fillErr = closeErr
+		}
+		return fillErr
+	})
+}
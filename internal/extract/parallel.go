@@ -0,0 +1,121 @@
+package extract
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+)
+
+// forEachIndex calls work for every index below count on up to jobs goroutines and returns once all calls
+// are done; with one job it calls work in index order on the calling goroutine.
+func forEachIndex(count int, jobs int, work func(index int)) {
+	if jobs <= 1 || count <= 1 {
+		for index := range count {
+			work(index)
+		}
+		return
+	}
+	var next atomic.Int64
+	var group sync.WaitGroup
+	for range min(jobs, count) {
+		group.Go(func() {
+			for {
+				index := int(next.Add(1) - 1)
+				if index >= count {
+					return
+				}
+				work(index)
+			}
+		})
+	}
+	group.Wait()
+}
+
+// evaluation is the outcome of decoding and matching one conversation read from an export against each search
+// of a run.
+type evaluation struct {
+	candidate filters.Candidate
+	// matches tells, by search, whether the conversation matched; nil when it matched none.
+	matches []bool
+	decoded bool
+}
+
+// offerTo offers the conversation to the set of each search, in the order of the searches.
+func (result evaluation) offerTo(sets []*conversationSet) {
+	for index, set := range sets {
+		set.offer(result.candidate, result.matches != nil && result.matches[index])
+	}
+}
+
+// evaluator decodes one serialized conversation and decides whether it matches.
+type evaluator func(serialized []byte, origin archive.Origin) evaluation
+
+// parallelScan decodes and matches conversations on several goroutines while the exports are still being
+// read, then offers them to a conversation set in the order they were read, so the set ends up exactly as a
+// sequential scan would leave it.
+type parallelScan struct {
+	evaluate evaluator
+	pending  chan scanItem
+	group    sync.WaitGroup
+	mutex    sync.Mutex
+	results  []evaluation
+	read     int
+	closing  sync.Once
+}
+
+type scanItem struct {
+	sequence   int
+	serialized []byte
+	origin     archive.Origin
+}
+
+func newParallelScan(jobs int, evaluate evaluator) *parallelScan {
+	scan := &parallelScan{evaluate: evaluate, pending: make(chan scanItem, jobs*2)}
+	for range jobs {
+		scan.group.Go(func() {
+			for item := range scan.pending {
+				result := evaluate(item.serialized, item.origin)
+				if result.matches == nil {
+					// Only the id and update time of a non-matching copy matter, to evict older matching copies.
+					result.candidate = filters.Candidate{Conversation: model.Conversation{
+						ID:         result.candidate.Conversation.ID,
+						UpdateTime: result.candidate.Conversation.UpdateTime,
+					}}
+				}
+				scan.mutex.Lock()
+				scan.results[item.sequence] = result
+				scan.mutex.Unlock()
+			}
+		})
+	}
+	return scan
+}
+
+// visit hands one conversation to the workers; it has the signature archive.EachConversation expects.
+func (scan *parallelScan) visit(serialized []byte, origin archive.Origin) error {
+	scan.mutex.Lock()
+	scan.results = append(scan.results, evaluation{})
+	scan.mutex.Unlock()
+	scan.pending <- scanItem{sequence: scan.read, serialized: serialized, origin: origin}
+	scan.read++
+	return nil
+}
+
+// close stops the workers once they drained what was handed to them; it may be called more than once.
+func (scan *parallelScan) close() {
+	scan.closing.Do(func() { close(scan.pending) })
+	scan.group.Wait()
+}
+
+// finish waits for the workers and offers every decoded conversation to the sets in reading order.
+func (scan *parallelScan) finish(sets []*conversationSet) {
+	scan.close()
+	for _, result := range scan.results {
+		if result.decoded {
+			result.offerTo(sets)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/utils"
+)
+
+const (
+	// progressInterval is how often the progress line is redrawn.
+	progressInterval = 250 * time.Millisecond
+	// eraseLine returns to the start of the terminal line and clears it.
+	eraseLine = "\r\033[K"
+)
+
+// progressReporter keeps one line on a terminal up to date while exports are scanned and matches written: how
+// many conversations were scanned and matched and how much of the exports was read, then how many matches
+// were written, each with an estimate of the time left. A nil reporter reports nothing, so callers need not
+// check whether progress was asked for.
+type progressReporter struct {
+	output     io.Writer
+	totalBytes int64
+	scanned    atomic.Int64
+	matched    atomic.Int64
+	readBytes  atomic.Int64
+	written    atomic.Int64
+	// mutex guards the fields below and the terminal line itself.
+	mutex      sync.Mutex
+	phaseStart time.Time
+	toWrite    int
+	drawn      bool
+	stop       chan struct{}
+	done       chan struct{}
+	stopping   sync.Once
+}
+
+// newProgressReporter starts redrawing the progress line on output; totalBytes is the size of the
+// conversation entries about to be read, or zero when unknown. Call finish to stop it.
+func newProgressReporter(output io.Writer, totalBytes int64) *progressReporter {
+	reporter := &progressReporter{
+		output:     output,
+		totalBytes: totalBytes,
+		phaseStart: time.Now(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go reporter.run()
+	return reporter
+}
+
+func (reporter *progressReporter) run() {
+	defer close(reporter.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-reporter.stop:
+			return
+		case <-ticker.C:
+			reporter.mutex.Lock()
+			fmt.Fprint(reporter.output, eraseLine+reporter.line())
+			reporter.drawn = true
+			reporter.mutex.Unlock()
+		}
+	}
+}
+
+// line describes the current phase; the caller holds the mutex.
+func (reporter *progressReporter) line() string {
+	elapsed := time.Since(reporter.phaseStart)
+	if reporter.toWrite > 0 {
+		written := reporter.written.Load()
+		return fmt.Sprintf("writing: %d of %d conversations%s", written, reporter.toWrite,
+			remainingTime(elapsed, float64(written), float64(reporter.toWrite)))
+	}
+	readBytes := reporter.readBytes.Load()
+	line := fmt.Sprintf("scanning: %d conversations, %d matched, %s", reporter.scanned.Load(), reporter.matched.Load(), utils.FormatByteSize(readBytes))
+	if reporter.totalBytes > 0 {
+		line += fmt.Sprintf(" of %s (%d%%)%s", utils.FormatByteSize(reporter.totalBytes), min(readBytes*100/reporter.totalBytes, 100),
+			remainingTime(elapsed, float64(readBytes), float64(reporter.totalBytes)))
+	}
+	return line
+}
+
+// conversationBytes returns how many bytes of conversations the sources hold, or zero when a format does not
+// tell. Attachment-only parts of a split export are not read for conversations and count for nothing.
+func conversationBytes(sources []*archive.Archive) int64 {
+	var total int64
+	for _, source := range sources {
+		if len(sources) > 1 && !source.HasConversations() {
+			continue
+		}
+		size, known := source.ConversationBytes()
+		if !known {
+			return 0
+		}
+		total += size
+	}
+	return total
+}
+
+// remainingTime estimates from the pace so far when the rest of the work is done, as ", ETA 1m5s".
+func remainingTime(elapsed time.Duration, done float64, total float64) string {
+	if done <= 0 || done >= total {
+		return ""
+	}
+	remaining := time.Duration(float64(elapsed) * (total - done) / done)
+	return ", ETA " + remaining.Round(time.Second).String()
+}
+
+// read counts one conversation read from an export, serialized in size bytes.
+func (reporter *progressReporter) read(size int) {
+	if reporter == nil {
+		return
+	}
+	reporter.scanned.Add(1)
+	reporter.readBytes.Add(int64(size))
+}
+
+// match counts one conversation that matched.
+func (reporter *progressReporter) match() {
+	if reporter != nil {
+		reporter.matched.Add(1)
+	}
+}
+
+// startWriting switches to the writing phase, with count conversations to write.
+func (reporter *progressReporter) startWriting(count int) {
+	if reporter == nil {
+		return
+	}
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.phaseStart = time.Now()
+	reporter.toWrite = count
+}
+
+// wrote counts one conversation written.
+func (reporter *progressReporter) wrote() {
+	if reporter != nil {
+		reporter.written.Add(1)
+	}
+}
+
+// pause erases the progress line and keeps it from being redrawn until resume, so lines printed in between
+// are not broken up by it.
+func (reporter *progressReporter) pause() {
+	if reporter == nil {
+		return
+	}
+	reporter.mutex.Lock()
+	if reporter.drawn {
+		fmt.Fprint(reporter.output, eraseLine)
+		reporter.drawn = false
+	}
+}
+
+func (reporter *progressReporter) resume() {
+	if reporter != nil {
+		reporter.mutex.Unlock()
+	}
+}
+
+// finish stops redrawing and erases the progress line; it may be called more than once.
+func (reporter *progressReporter) finish() {
+	if reporter == nil {
+		return
+	}
+	reporter.stopping.Do(func() {
+		close(reporter.stop)
+		<-reporter.done
+		reporter.pause()
+		reporter.resume()
+	})
+}
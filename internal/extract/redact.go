@@ -0,0 +1,99 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"openai_extract/internal/redact"
+	"openai_extract/internal/utils"
+)
+
+// redactedJSONExtensions and redactedTextExtensions mark the entries whose content is masked; every other
+// entry is treated as an attachment.
+var (
+	redactedJSONExtensions = map[string]struct{}{".json": {}, ".ipynb": {}}
+	redactedTextExtensions = map[string]struct{}{
+		".md": {}, ".txt": {}, ".html": {}, ".htm": {}, ".csv": {}, ".tsv": {}, ".xml": {}, ".yaml": {}, ".yml": {},
+		".log": {}, ".py": {}, ".js": {}, ".ts": {}, ".go": {}, ".sh": {}, ".sql": {}, ".atom": {}, ".out": {},
+	}
+)
+
+// RunRedact rewrites the exports into outputPath, a ZIP archive when the path ends in .zip and a folder
+// otherwise, with sensitive text masked by redactor. Any folder of files works as input, so the output of an
+// extraction can be redacted as well as an export. JSON entries have only their string values masked, so
+// they stay valid; other text entries are masked line by line. Attachments cannot be masked and are left out
+// unless keepAttachments is set. When several exports hold an entry of the same name, the first one wins.
+func RunRedact(ctx context.Context, inputSettings InputSettings, outputPath string, redactor *redact.Redactor, keepAttachments bool) error {
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+
+	masked, copied, skipped := 0, 0, 0
+	write := func(writeEntry entryWriter) error {
+		written := make(map[string]struct{})
+		for _, source := range sources {
+			for _, name := range source.Names() {
+				if _, done := written[name]; done || strings.HasSuffix(name, "/") {
+					continue
+				}
+				extension := strings.ToLower(path.Ext(name))
+				_, isJSON := redactedJSONExtensions[extension]
+				_, isText := redactedTextExtensions[extension]
+				if !isJSON && !isText && !keepAttachments {
+					skipped++
+					continue
+				}
+				written[name] = struct{}{}
+				writeErr := writeEntry(name, func(destination io.Writer) error {
+					reader, openErr := source.Open(name)
+					if openErr != nil {
+						return openErr
+					}
+					defer reader.Close()
+					switch {
+					case isJSON:
+						return redactor.CopyJSON(destination, reader)
+					case isText:
+						return redactor.CopyText(destination, reader)
+					default:
+						_, copyErr := io.Copy(destination, reader)
+						return copyErr
+					}
+				})
+				if writeErr != nil {
+					return fmt.Errorf("redact %s: %w", name, writeErr)
+				}
+				if isJSON || isText {
+					masked++
+				} else {
+					copied++
+				}
+			}
+		}
+		return nil
+	}
+
+	var redactErr error
+	if strings.EqualFold(filepath.Ext(outputPath), zipSuffix) {
+		redactErr = mergeToZip(outputPath, write)
+	} else {
+		redactErr = mergeToFolder(outputPath, write)
+	}
+	if redactErr != nil {
+		return redactErr
+	}
+	summary := fmt.Sprintf("masked %d matches in %d files into %s", redactor.Replaced, masked, outputPath)
+	if keepAttachments {
+		summary += fmt.Sprintf("; copied %d attachments unchanged", copied)
+	} else if skipped > 0 {
+		summary += fmt.Sprintf("; left out %d attachments", skipped)
+	}
+	utils.PrintLine(summary)
+	return nil
+}
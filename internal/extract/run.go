@@ -1,129 +1,570 @@
 package extract
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
+	"time"
 
 	"openai_extract/internal/archive"
 	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
 	"openai_extract/internal/utils"
 
 	"go.uber.org/zap"
 )
 
-type conversationRecord map[string]any
+// InputSettings lists the exports to search, how remote ones are fetched, and how encrypted ones are unlocked.
+type InputSettings struct {
+	Paths            []string
+	DownloadCacheDir string
+	// RecordCacheDir caches where conversations sit in each export file, so unchanged exports are not parsed again.
+	RecordCacheDir string
+	Password       archive.PasswordSource
+	MaxMemory      int64
+	Source         archive.Source
+	Verify         bool
+	// IndexPath is a full-text index built from the exports; when set, only the conversations it admits are read.
+	IndexPath string
+}
+
+// OutputSettings configures the per-conversation document format and the optional renderings written alongside it.
+type OutputSettings struct {
+	Format        string
+	TemplatePath  string
+	SplitMessages bool
+	ExtractCode   bool
+	ExtractDalle  bool
+	// CanvasRevisions also writes every intermediate version of each canvas document, not only the final one.
+	CanvasRevisions bool
+	Analysis        render.AnalysisFormat
+	ExtractVoice    bool
+	// IncludeReasoning renders the reasoning summaries of reasoning models as collapsible sections.
+	IncludeReasoning bool
+	// ShowMatches reports where each pattern hit: in matches.txt in each conversation folder, or on stdout
+	// when no folders are written.
+	ShowMatches bool
+	// ContextLines prints each pattern hit with this many lines around it to stdout; 0 disables it.
+	ContextLines int
+	// ListMatches prints the id and title of each matched conversation followed by its hits, with ContextLines
+	// lines around each, instead of only the hits.
+	ListMatches bool
+	// Highlight marks pattern hits in Markdown transcripts, the digest, and template output; HighlightNone disables it.
+	Highlight render.HighlightStyle
+	// Timestamps annotates each message in Markdown transcripts with the time it was written.
+	Timestamps bool
+	// Location is the time zone rendered times are shown in; nil keeps the zone they were decoded in.
+	Location     *time.Location
+	DigestPath   string
+	FeedPath     string
+	TrustArchive bool
+	StatePath    string
+	Branches     model.BranchMode
+	// MessagesFrom limits rendered transcripts to the messages of one author role; empty keeps every role.
+	MessagesFrom string
+	// VisibleOnly drops hidden, system, and tool messages from rendered transcripts, as the ChatGPT UI does.
+	VisibleOnly bool
+	// Jobs is how many conversations are decoded, matched, and written at once; below 2 they are processed
+	// one at a time.
+	Jobs int
+	// Progress keeps a progress line on stderr up to date while conversations are scanned and written.
+	Progress bool
+}
+
+// renderedMessages returns the messages of a conversation that transcript outputs include.
+func (outputSettings OutputSettings) renderedMessages(conversation model.Conversation) []utils.Message {
+	messages := model.Messages(conversation, outputSettings.Branches)
+	if outputSettings.MessagesFrom != "" {
+		messages = utils.MessagesFrom(messages, outputSettings.MessagesFrom)
+	}
+	if outputSettings.VisibleOnly {
+		messages = utils.VisibleMessages(messages)
+	}
+	if outputSettings.IncludeReasoning {
+		messages = render.ReasoningAsText(messages)
+	}
+	return messages
+}
+
+// openSources opens every -f value of inputSettings, in order. When one fails, those already opened are closed.
+func openSources(ctx context.Context, inputSettings InputSettings) ([]*archive.Archive, error) {
+	sources := make([]*archive.Archive, 0, len(inputSettings.Paths))
+	for _, archiveFilePath := range inputSettings.Paths {
+		source, openErr := openInput(ctx, archiveFilePath, inputSettings)
+		if openErr != nil {
+			closeSources(sources)
+			return nil, openErr
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// closeSources closes every export openSources opened.
+func closeSources(sources []*archive.Archive) {
+	for _, source := range sources {
+		source.Close()
+	}
+}
+
+// openInput opens one -f value: a ChatGPT share link is fetched directly, other URLs are downloaded to the
+// cache first, and local paths are opened in place.
+func openInput(ctx context.Context, archiveFilePath string, inputSettings InputSettings) (*archive.Archive, error) {
+	if archive.IsShareURL(archiveFilePath) {
+		return archive.FetchShare(ctx, archiveFilePath)
+	}
+	if archive.IsRemote(archiveFilePath) {
+		downloaded, downloadErr := archive.Download(ctx, archiveFilePath, inputSettings.DownloadCacheDir)
+		if downloadErr != nil {
+			return nil, downloadErr
+		}
+		archiveFilePath = downloaded
+	}
+	return archive.OpenArchive(archiveFilePath, archive.OpenOptions{
+		Password:       inputSettings.Password,
+		MemoryBudget:   inputSettings.MaxMemory,
+		Source:         inputSettings.Source,
+		RecordCacheDir: inputSettings.RecordCacheDir,
+	})
+}
+
+// outputBatchPerJob is how many conversations each job writes before the batch is reported.
+const outputBatchPerJob = 8
+
+// pageResult is what writing one matched conversation produced, kept until its batch is reported in order.
+type pageResult struct {
+	candidate    filters.Candidate
+	folderName   string
+	hits         []filters.Hit
+	targetFolder string
+	writeErr     error
+	entry        render.ConversationEntry
+}
+
+// NamedQuery is one search of a batch: what it matches and where under the output folder its matches go.
+type NamedQuery struct {
+	// Name identifies the search in messages.
+	Name     string
+	Query    filters.Query
+	Criteria filters.Criteria
+	// Folder is the subfolder of the output folder the matches are written to; empty writes into the output
+	// folder itself.
+	Folder string
+}
+
+// batchSearch is a NamedQuery prepared for a run: compiled, with its writer and the conversations it matched.
+type batchSearch struct {
+	named      NamedQuery
+	matcher    *filters.Matcher
+	predicates []filters.Predicate
+	writer     *folderWriter
+	collected  *conversationSet
+	// state is the extraction state loaded when one is kept, recording what this run writes.
+	state *extractionState
+	// scannedAll reports that every conversation of the exports was read, not only those an index offered, so
+	// collected knows every conversation still present.
+	scannedAll bool
+}
+
+// Options is everything a run needs: the exports read, the searches matched over them, and where and how their
+// matches are written. Capabilities are added as fields, so callers naming the fields they set keep compiling.
+type Options struct {
+	Input InputSettings
+	// Queries are the searches of the run, each with its patterns, filters, paging, and output subfolder.
+	Queries []NamedQuery
+	// OutputRoot is the folder matches are written under; empty writes no conversation folders, as when
+	// matches are only listed.
+	OutputRoot string
+	// Output selects the document format, the extra outputs, and how many conversations are handled at once.
+	Output OutputSettings
+}
+
+// Run runs the searches of options over a single scan of the exports, so each conversation is read and decoded
+// once however many searches there are, and writes the matches of each search into its own folder. When there
+// are several searches, one that matches nothing is reported and the others still written; a single search
+// matching nothing fails with filters.ErrNoMatch. Once ctx is done the scan or writing stops, the extraction
+// state records what was already written, and ctx's error is returned.
+func Run(ctx context.Context, options Options) error {
+	return run(ctx, options, nil)
+}
+
+// RunArchives runs the searches of options as Run does, over exports the caller already opened: sources[i] is
+// options.Input.Paths[i], opened. They are left open.
+func RunArchives(ctx context.Context, options Options, sources []*archive.Archive) error {
+	if len(sources) != len(options.Input.Paths) {
+		return fmt.Errorf("run: %d opened exports for %d paths", len(sources), len(options.Input.Paths))
+	}
+	return run(ctx, options, sources)
+}
 
-func Run(archiveFilePath string, searchPatterns []string, outputRoot string, desiredContentTypes []string, desiredLanguages []string) error {
+// run runs the searches of options over sources, or over the exports of options.Input, opened and closed here,
+// when sources is nil.
+func run(ctx context.Context, options Options, sources []*archive.Archive) error {
+	inputSettings, queries, outputRoot, outputSettings := options.Input, options.Queries, options.OutputRoot, options.Output
 	logger, loggerErr := zap.NewProduction()
 	if loggerErr != nil {
 		return fmt.Errorf("init logger: %w", loggerErr)
 	}
 	defer logger.Sync()
 
-	absoluteOutputRoot, absErr := filepath.Abs(outputRoot)
-	if absErr != nil {
-		return fmt.Errorf("resolve output folder: %w", absErr)
-	}
-	if mkErr := utils.EnsureDir(absoluteOutputRoot); mkErr != nil {
-		return fmt.Errorf("create output folder %q: %w", absoluteOutputRoot, mkErr)
+	searches := make([]*batchSearch, 0, len(queries))
+	for _, named := range queries {
+		search, prepareErr := prepareSearch(logger, named, outputRoot, outputSettings)
+		if prepareErr != nil {
+			return prepareErr
+		}
+		if search.writer != nil {
+			defer search.writer.close()
+		}
+		searches = append(searches, search)
 	}
 
-	fileContentMap, loadErr := archive.LoadZipFileMap(archiveFilePath)
-	if loadErr != nil {
-		return loadErr
+	if sources == nil {
+		opened, openErr := openSources(ctx, inputSettings)
+		if openErr != nil {
+			return openErr
+		}
+		defer closeSources(opened)
+		sources = opened
 	}
+	inputs := make([]openedInput, 0, len(sources))
+	for index, source := range sources {
+		inputs = append(inputs, openedInput{path: inputSettings.Paths[index], source: source})
+	}
+	// Split exports keep attachments in other parts than conversations.json, so files resolve across all inputs.
+	files := archive.Combine(sources)
 
-	conversations, convoErr := archive.FindConversationsJSON(fileContentMap)
-	if convoErr != nil {
-		return convoErr
+	if inputSettings.Verify {
+		for _, input := range inputs {
+			if verifyErr := verifyArchive(ctx, logger, input.path, input.source, files); verifyErr != nil {
+				return verifyErr
+			}
+		}
 	}
 
-	compiled := make([]*regexp.Regexp, 0, len(searchPatterns))
-	for _, patternText := range searchPatterns {
-		re, reErr := utils.CompileUserPattern(patternText)
-		if reErr != nil {
-			return fmt.Errorf("invalid pattern %q: %w", patternText, reErr)
+	var progress *progressReporter
+	if outputSettings.Progress {
+		totalBytes := int64(0)
+		if inputSettings.IndexPath == "" {
+			totalBytes = conversationBytes(sources)
 		}
-		compiled = append(compiled, re)
+		progress = newProgressReporter(os.Stderr, totalBytes)
+		defer progress.finish()
 	}
 
-	matchedCount := 0
-	usedFolderNames := make(map[string]int)
+	if _, scanErr := matchSearches(ctx, logger, inputSettings, inputs, files, searches, outputSettings.Jobs, progress); scanErr != nil {
+		return scanErr
+	}
 
-	for _, record := range conversations {
-		serialized, serErr := json.Marshal(record)
-		if serErr != nil {
-			logger.Error("serialize conversation", zap.Error(serErr))
+	pages := make([][]filters.Candidate, len(searches))
+	pending := 0
+	for index, search := range searches {
+		page, selectErr := search.selectPage(logger, outputSettings)
+		if selectErr != nil && len(searches) > 1 && errors.Is(selectErr, filters.ErrNoMatch) {
+			logger.Info("no conversations matched", zap.String("query", search.named.Name), zap.String("reason", selectErr.Error()))
 			continue
 		}
-		lower := utils.BytesToLower(serialized)
+		if selectErr != nil {
+			return selectErr
+		}
+		pages[index] = page
+		pending += len(page)
+	}
+	progress.startWriting(pending)
+	for index, search := range searches {
+		if writeErr := search.writeMatches(ctx, logger, pages[index], outputSettings, progress); writeErr != nil {
+			return writeErr
+		}
+	}
+	progress.finish()
+	return nil
+}
+
+// openedInput is one export given to -f, opened.
+type openedInput struct {
+	path   string
+	source *archive.Archive
+}
 
-		allMatch := true
-		for _, re := range compiled {
-			if !re.Match(lower) {
-				allMatch = false
-				break
+// scanTotals counts what a scan read: conversations, and the bytes of their JSON.
+type scanTotals struct {
+	conversations int
+	bytes         int64
+}
+
+// matchSearches reads every conversation of the inputs once, or of the index when one is given, and collects
+// in each search the conversations it matches, deciding on up to jobs conversations at once.
+func matchSearches(ctx context.Context, logger *zap.Logger, inputSettings InputSettings, inputs []openedInput, files *archive.Archive, searches []*batchSearch, jobs int, progress *progressReporter) (scanTotals, error) {
+	evaluate := func(serialized []byte, origin archive.Origin) evaluation {
+		if searchesRuleOut(searches, serialized) {
+			// A conversation that cannot match is only needed to evict older matching copies of it.
+			identity, decodeErr := model.DecodeIdentity(serialized)
+			if decodeErr != nil {
+				logger.Error("decode conversation", zap.Error(decodeErr))
+				return evaluation{}
 			}
+			return evaluation{candidate: filters.Candidate{Conversation: identity}, decoded: true}
 		}
-		if !allMatch {
-			continue
+		conversation, decodeErr := model.Decode(serialized)
+		if decodeErr != nil {
+			logger.Error("decode conversation", zap.Error(decodeErr))
+			return evaluation{}
+		}
+		candidate := filters.Candidate{Conversation: conversation, Serialized: serialized, Archive: files, Origin: origin}
+		result := evaluation{candidate: candidate, decoded: true}
+		for index, search := range searches {
+			if search.matcher.Matches(candidate) && filters.MatchesAll(search.predicates, candidate) {
+				if result.matches == nil {
+					result.matches = make([]bool, len(searches))
+					progress.match()
+				}
+				result.matches[index] = true
+			}
+		}
+		return result
+	}
+	sets := make([]*conversationSet, len(searches))
+	for index, search := range searches {
+		sets[index] = search.collected
+	}
+	visit := func(serialized []byte, origin archive.Origin) error {
+		if result := evaluate(serialized, origin); result.decoded {
+			result.offerTo(sets)
+		}
+		return nil
+	}
+	var scan *parallelScan
+	if jobs > 1 {
+		scan = newParallelScan(jobs, evaluate)
+		defer scan.close()
+		visit = scan.visit
+	}
+	var totals scanTotals
+	scanVisit := visit
+	visit = func(serialized []byte, origin archive.Origin) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
 		}
+		totals.conversations++
+		totals.bytes += int64(len(serialized))
+		progress.read(len(serialized))
+		return scanVisit(serialized, origin)
+	}
+	for _, search := range searches {
+		search.scannedAll = inputSettings.IndexPath == ""
+	}
+	if inputSettings.IndexPath != "" {
+		if scanErr := scanIndex(inputSettings, indexPrefilter(searches), visit); scanErr != nil {
+			return totals, scanErr
+		}
+	} else {
+		for _, input := range inputs {
+			if len(inputs) > 1 && !input.source.HasConversations() {
+				logger.Info("no conversations in export part, using it for attachments only", zap.String("archive", input.path))
+				continue
+			}
+			if scanErr := input.source.EachConversation(ctx, visit); scanErr != nil {
+				return totals, fmt.Errorf("%s: %w", input.path, scanErr)
+			}
+		}
+	}
+	if scan != nil {
+		scan.finish(sets)
+	}
+	return totals, nil
+}
 
-		contentTypes := filters.EnumerateContentTypes(serialized)
-		if !filters.HasAllDesired(contentTypes, desiredContentTypes, utils.ToLowerTrim) {
-			continue
+// prepareSearch compiles a search and creates its output folder.
+func prepareSearch(logger *zap.Logger, named NamedQuery, outputRoot string, outputSettings OutputSettings) (*batchSearch, error) {
+	matcher, compileErr := named.Query.Compile()
+	if compileErr != nil {
+		if named.Name != "" {
+			return nil, fmt.Errorf("query %q: %w", named.Name, compileErr)
 		}
+		return nil, compileErr
+	}
+	search := &batchSearch{named: named, matcher: matcher, predicates: named.Criteria.Predicates(), collected: newConversationSet()}
+	if outputRoot == "" {
+		return search, nil
+	}
+	absoluteOutputRoot, absErr := filepath.Abs(filepath.Join(outputRoot, named.Folder))
+	if absErr != nil {
+		return nil, fmt.Errorf("resolve output folder: %w", absErr)
+	}
+	if mkErr := utils.EnsureDir(absoluteOutputRoot); mkErr != nil {
+		return nil, fmt.Errorf("create output folder %q: %w", absoluteOutputRoot, mkErr)
+	}
+	writer, writerErr := newFolderWriter(logger, absoluteOutputRoot, outputSettings)
+	if writerErr != nil {
+		return nil, writerErr
+	}
+	search.writer = writer
+	return search, nil
+}
 
-		languages := filters.EnumerateLanguages(serialized)
-		if !filters.HasAllDesired(languages, desiredLanguages, filters.NormalizeLanguageName) {
-			continue
+// searchesRuleOut reports whether no search can match the serialized conversation, so it need not be decoded.
+func searchesRuleOut(searches []*batchSearch, serialized []byte) bool {
+	for _, search := range searches {
+		if !search.matcher.RulesOut(serialized) {
+			return false
 		}
+	}
+	return true
+}
 
-		startTime := utils.ExtractCreateTime(record)
-		baseFolder := utils.FormatDatestamp(startTime)
+// indexPrefilter narrows an index scan for a single search; several searches read every indexed conversation,
+// as each would admit different ones.
+func indexPrefilter(searches []*batchSearch) filters.Prefilter {
+	if len(searches) != 1 {
+		return filters.Prefilter{}
+	}
+	return searches[0].matcher.Prefilter()
+}
 
-		if usedFolderNames[baseFolder] > 0 {
-			usedFolderNames[baseFolder]++
-			baseFolder = fmt.Sprintf("%s_%d", baseFolder, usedFolderNames[baseFolder])
-		} else {
-			usedFolderNames[baseFolder] = 1
+// selectPage orders the conversations the search matched, drops those an earlier extraction already wrote,
+// and applies its paging; it fails with filters.ErrNoMatch when nothing is left to write.
+func (search *batchSearch) selectPage(logger *zap.Logger, outputSettings OutputSettings) ([]filters.Candidate, error) {
+	query, criteria := search.named.Query, search.named.Criteria
+	matched := search.collected.candidates()
+	if len(matched) == 0 {
+		return nil, filters.BuildNoMatchError(query.Subject(), criteria.Qualifiers())
+	}
+	if outputSettings.StatePath != "" {
+		state, stateErr := loadExtractionState(outputSettings.StatePath)
+		if stateErr != nil {
+			return nil, stateErr
 		}
-
-		targetFolder := filepath.Join(absoluteOutputRoot, baseFolder)
-		if mkErr := utils.EnsureDir(targetFolder); mkErr != nil {
-			logger.Error("create output subfolder", zap.String("folder", targetFolder), zap.Error(mkErr))
-			continue
+		search.state = state
+		matched = state.pending(matched)
+		if len(matched) == 0 {
+			logger.Info("no new or changed conversations since the last extraction", zap.String("state", outputSettings.StatePath))
+			return nil, nil
 		}
-
-		conversationJSONPath := filepath.Join(targetFolder, "conversation.json")
-		if writeErr := utils.WritePrettyJSON(conversationJSONPath, serialized); writeErr != nil {
-			logger.Error("write conversation.json", zap.String("path", conversationJSONPath), zap.Error(writeErr))
-			continue
+	}
+	if query.Semantic.Text != "" {
+		var present map[string]time.Time
+		if search.scannedAll {
+			present = search.collected.latest
+		}
+		ranked, rankErr := rankSemantically(query.Semantic, matched, present)
+		if rankErr != nil {
+			return nil, rankErr
 		}
+		if len(ranked) == 0 {
+			return nil, filters.BuildNoMatchError(query.Subject(), append(criteria.Qualifiers(), fmt.Sprintf("similarity of at least %.2f", query.Semantic.MinScore)))
+		}
+		matched = ranked
+	} else {
+		sort.SliceStable(matched, func(left, right int) bool {
+			return matched[left].Conversation.CreateTime.Before(matched[right].Conversation.CreateTime)
+		})
+	}
+	return criteria.Page.Apply(matched)
+}
 
-		linked := filters.CollectLinkedFiles(serialized, fileContentMap)
-		if len(linked) > 0 {
-			filesFolder := filepath.Join(targetFolder, "files")
-			if mkErr := utils.EnsureDir(filesFolder); mkErr != nil {
-				logger.Error("create files subfolder", zap.String("folder", filesFolder), zap.Error(mkErr))
-			} else {
-				for archivePath, content := range linked {
-					targetPath := filepath.Join(filesFolder, filepath.Base(archivePath))
-					if writeErr := utils.WriteFile(targetPath, content); writeErr != nil {
-						logger.Error("write linked file", zap.String("archivePath", archivePath), zap.String("targetPath", targetPath), zap.Error(writeErr))
-					}
-				}
+// writeMatches writes the selected conversations of the search and the extra outputs asked for.
+func (search *batchSearch) writeMatches(ctx context.Context, logger *zap.Logger, page []filters.Candidate, outputSettings OutputSettings, progress *progressReporter) error {
+	if len(page) == 0 {
+		return nil
+	}
+	writer, matcher, state := search.writer, search.matcher, search.state
+
+	var matchedEntries []render.ConversationEntry
+	collectEntries := outputSettings.DigestPath != "" || outputSettings.FeedPath != ""
+	wantHits := outputSettings.ListMatches || outputSettings.ContextLines > 0 || outputSettings.ShowMatches || outputSettings.Highlight != render.HighlightNone
+	// Conversations are written in batches, several at once, and reported in order after each batch.
+	batchSize := max(outputSettings.Jobs, 1) * outputBatchPerJob
+	var stopErr error
+	for batchStart := 0; batchStart < len(page); batchStart += batchSize {
+		if stopErr = ctx.Err(); stopErr != nil {
+			break
+		}
+		batch := page[batchStart:min(batchStart+batchSize, len(page))]
+		results := make([]pageResult, len(batch))
+		for index, candidate := range batch {
+			if outputSettings.Location != nil {
+				candidate.Conversation = candidate.Conversation.In(outputSettings.Location)
+			}
+			results[index].candidate = candidate
+			if writer != nil {
+				results[index].folderName = writer.nextFolderName(candidate.Conversation)
 			}
 		}
+		forEachIndex(len(results), outputSettings.Jobs, func(index int) {
+			result := &results[index]
+			if wantHits {
+				result.hits = matcher.Hits(result.candidate)
+			}
+			if writer != nil {
+				result.targetFolder, result.writeErr = writer.write(result.candidate, result.folderName, result.hits)
+			}
+			if collectEntries && result.writeErr == nil {
+				conversation := result.candidate.Conversation
+				entry := render.NewConversationEntry(conversation, outputSettings.renderedMessages(conversation), result.targetFolder)
+				if outputSettings.Highlight != render.HighlightNone {
+					entry.Highlighted = render.HighlightMessages(entry.Messages, highlightSpans(result.hits), outputSettings.Highlight)
+				}
+				result.entry = entry
+			}
+			progress.wrote()
+		})
 
-		utils.PrintLine(targetFolder + string(filepath.Separator))
-		matchedCount++
+		progress.pause()
+		for _, result := range results {
+			candidate, hits, targetFolder := result.candidate, result.hits, result.targetFolder
+			if result.writeErr != nil {
+				logger.Error("write conversation folder", zap.Error(result.writeErr))
+				continue
+			}
+			if writer != nil {
+				utils.PrintLine(targetFolder + string(filepath.Separator))
+			}
+			if outputSettings.ListMatches {
+				utils.PrintLine(fmt.Sprintf(hitHeadingFormat, candidate.Conversation.ID, candidate.Conversation.Title))
+				printContext(candidate, hits, outputSettings.ContextLines)
+			} else if outputSettings.ContextLines > 0 {
+				printContext(candidate, hits, outputSettings.ContextLines)
+			}
+			if outputSettings.ShowMatches {
+				reportHits(candidate, hits, targetFolder, logger)
+			}
+			if collectEntries {
+				matchedEntries = append(matchedEntries, result.entry)
+			}
+			if state != nil {
+				state.record(candidate, targetFolder)
+			}
+		}
+		progress.resume()
+	}
+	if state != nil {
+		if saveErr := state.save(); saveErr != nil {
+			return saveErr
+		}
+	}
+	if stopErr != nil {
+		return stopErr
 	}
 
-	if matchedCount == 0 {
-		return filters.BuildNoMatchError(utils.StringsJoinComma(searchPatterns), desiredContentTypes, desiredLanguages)
+	if outputSettings.DigestPath != "" {
+		if writeErr := utils.WriteFile(outputSettings.DigestPath, render.RenderDigest(matchedEntries, outputSettings.Timestamps)); writeErr != nil {
+			return fmt.Errorf("write digest: %w", writeErr)
+		}
+		utils.PrintLine(outputSettings.DigestPath)
+	}
+	if outputSettings.FeedPath != "" {
+		feed, feedErr := render.RenderFeed(matchedEntries)
+		if feedErr != nil {
+			return feedErr
+		}
+		if writeErr := utils.WriteFile(outputSettings.FeedPath, feed); writeErr != nil {
+			return fmt.Errorf("write feed: %w", writeErr)
+		}
+		utils.PrintLine(outputSettings.FeedPath)
 	}
 	return nil
 }
@@ -0,0 +1,34 @@
+package extract
+
+import (
+	"context"
+	"errors"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+
+	"go.uber.org/zap"
+)
+
+// Search reads every conversation of the opened exports once and returns those the query and criteria match,
+// the newest copy of each, ordered and paged as Run would write them. Nothing is written or logged, and
+// matching nothing yields no candidates rather than an error. paths name the sources in errors.
+func Search(ctx context.Context, paths []string, sources []*archive.Archive, query filters.Query, criteria filters.Criteria, jobs int) ([]filters.Candidate, error) {
+	logger := zap.NewNop()
+	search, prepareErr := prepareSearch(logger, NamedQuery{Query: query, Criteria: criteria}, "", OutputSettings{})
+	if prepareErr != nil {
+		return nil, prepareErr
+	}
+	inputs := make([]openedInput, 0, len(sources))
+	for index, source := range sources {
+		inputs = append(inputs, openedInput{path: paths[index], source: source})
+	}
+	if _, scanErr := matchSearches(ctx, logger, InputSettings{}, inputs, archive.Combine(sources), []*batchSearch{search}, jobs, nil); scanErr != nil {
+		return nil, scanErr
+	}
+	page, selectErr := search.selectPage(logger, OutputSettings{})
+	if errors.Is(selectErr, filters.ErrNoMatch) {
+		return nil, nil
+	}
+	return page, selectErr
+}
@@ -0,0 +1,53 @@
+package extract
+
+import (
+	"strings"
+	"time"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/semantic"
+	"openai_extract/internal/utils"
+)
+
+// rankSemantically orders the matched conversations by their similarity to the semantic query, dropping those
+// below its minimum score. When present holds the update time of every conversation still in the exports, the
+// embedding cache forgets the vectors of all others.
+func rankSemantically(semanticQuery filters.SemanticQuery, matched []filters.Candidate, present map[string]time.Time) ([]filters.Candidate, error) {
+	ranker := semantic.Ranker{Embedder: semantic.HashingEmbedder{}}
+	if semanticQuery.CachePath != "" {
+		cache, cacheErr := semantic.LoadCache(semanticQuery.CachePath)
+		if cacheErr != nil {
+			return nil, cacheErr
+		}
+		ranker.Cache = cache
+	}
+
+	documents := make([]semantic.Document, 0, len(matched))
+	for _, candidate := range matched {
+		documents = append(documents, semantic.Document{
+			ID:         candidate.Conversation.ID,
+			UpdateUnix: candidate.Conversation.UpdateTime.Unix(),
+			Text:       strings.Join([]string{candidate.Conversation.Title, utils.DialogueText(model.ActiveBranch(candidate.Conversation))}, "\n"),
+		})
+	}
+
+	scored := ranker.Rank(semanticQuery.Text, documents, semanticQuery.MinScore)
+	ranked := make([]filters.Candidate, 0, len(scored))
+	for _, entry := range scored {
+		ranked = append(ranked, matched[entry.Index])
+	}
+	if ranker.Cache != nil {
+		if present != nil {
+			presentUnix := make(map[string]int64, len(present))
+			for conversationID, updated := range present {
+				presentUnix[conversationID] = updated.Unix()
+			}
+			ranker.Cache.Retain(presentUnix)
+		}
+		if saveErr := ranker.Cache.Save(); saveErr != nil {
+			return nil, saveErr
+		}
+	}
+	return ranked, nil
+}
@@ -0,0 +1,110 @@
+package extract
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/utils"
+	"openai_extract/internal/web"
+
+	"go.uber.org/zap"
+)
+
+const exportTempPattern = "openai_extract-export-*"
+
+// Serve loads every conversation of the exports and serves the web UI for them at address until ctx is done
+// or the server fails. Exports from the UI are written with outputSettings and sent as a ZIP of the
+// conversation folders.
+func Serve(ctx context.Context, inputSettings InputSettings, address string, outputSettings OutputSettings) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+	files := archive.Combine(sources)
+
+	conversations, collectErr := newestConversations(ctx, logger, sources, inputSettings.Paths)
+	if collectErr != nil {
+		return collectErr
+	}
+	for index := range conversations {
+		conversations[index].Archive = files
+	}
+	sort.SliceStable(conversations, func(left, right int) bool {
+		return conversations[left].Conversation.CreateTime.After(conversations[right].Conversation.CreateTime)
+	})
+
+	server, serverErr := web.NewServer(conversations, func(writer io.Writer, selected []filters.Candidate) error {
+		exportErr := exportArchive(logger, writer, selected, outputSettings)
+		if exportErr != nil {
+			logger.Error("export conversations", zap.Error(exportErr))
+		}
+		return exportErr
+	})
+	if serverErr != nil {
+		return serverErr
+	}
+	utils.PrintLine(fmt.Sprintf("serving %d conversations at http://%s/", len(conversations), address))
+	return server.Serve(ctx, address)
+}
+
+// exportArchive writes the conversation folders of the selection to a temporary folder and streams them to
+// writer as a ZIP archive.
+func exportArchive(logger *zap.Logger, writer io.Writer, selected []filters.Candidate, outputSettings OutputSettings) error {
+	exportRoot, tempErr := os.MkdirTemp("", exportTempPattern)
+	if tempErr != nil {
+		return fmt.Errorf("create export folder: %w", tempErr)
+	}
+	defer os.RemoveAll(exportRoot)
+
+	folders, writerErr := newFolderWriter(logger, exportRoot, outputSettings)
+	if writerErr != nil {
+		return writerErr
+	}
+	defer folders.close()
+	for _, candidate := range selected {
+		if _, writeErr := folders.write(candidate, folders.nextFolderName(candidate.Conversation), nil); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	zipped := zip.NewWriter(writer)
+	walkErr := filepath.WalkDir(exportRoot, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil || entry.IsDir() {
+			return walkErr
+		}
+		relative, relErr := filepath.Rel(exportRoot, path)
+		if relErr != nil {
+			return relErr
+		}
+		target, createErr := zipped.Create(filepath.ToSlash(relative))
+		if createErr != nil {
+			return createErr
+		}
+		source, openErr := os.Open(path)
+		if openErr != nil {
+			return openErr
+		}
+		defer source.Close()
+		_, copyErr := io.Copy(target, source)
+		return copyErr
+	})
+	if walkErr != nil {
+		return fmt.Errorf("zip export: %w", walkErr)
+	}
+	return zipped.Close()
+}
@@ -0,0 +1,118 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+	"openai_extract/internal/utils"
+)
+
+// Title match scores, best first; a conversation scoring zero does not match the reference at all.
+const (
+	scoreID = 5 - iota
+	scoreTitleExact
+	scoreTitlePrefix
+	scoreTitleContains
+	scoreTitleWords
+	scoreNone
+)
+
+const ambiguousListLimit = 10
+
+// RunShow prints one conversation to stdout as a readable transcript. reference is a conversation id or, when
+// no id equals it, a title: an exact title wins over one starting with it, which wins over one containing it,
+// which wins over one containing all of its words. Several conversations sharing the best match are listed
+// instead, so the reference can be narrowed.
+func RunShow(ctx context.Context, inputSettings InputSettings, reference string, outputSettings OutputSettings) error {
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+	files := archive.Combine(sources)
+
+	bestScore := scoreNone
+	best := newConversationSet()
+	for index, source := range sources {
+		if len(sources) > 1 && !source.HasConversations() {
+			continue
+		}
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				return nil
+			}
+			score := referenceScore(conversation, reference)
+			if score == scoreNone || score < bestScore {
+				return nil
+			}
+			if score > bestScore {
+				bestScore, best = score, newConversationSet()
+			}
+			best.offer(filters.Candidate{Conversation: conversation, Serialized: serialized, Archive: files, Origin: origin}, true)
+			return nil
+		})
+		if scanErr != nil {
+			return fmt.Errorf("%s: %w", inputSettings.Paths[index], scanErr)
+		}
+	}
+
+	matches := best.candidates()
+	switch {
+	case len(matches) == 0:
+		return fmt.Errorf("no conversation has id or title %q", reference)
+	case len(matches) > 1:
+		return ambiguousReference(reference, matches)
+	}
+	conversation := matches[0].Conversation
+	if outputSettings.Location != nil {
+		conversation = conversation.In(outputSettings.Location)
+	}
+	entry := render.NewConversationEntry(conversation, outputSettings.renderedMessages(conversation), "")
+	_, writeErr := os.Stdout.Write(render.RenderTerminal(entry))
+	return writeErr
+}
+
+// referenceScore rates how well the conversation's id or title matches reference, case-insensitively for titles.
+func referenceScore(conversation model.Conversation, reference string) int {
+	if conversation.ID == reference {
+		return scoreID
+	}
+	title := utils.ToLowerTrim(conversation.Title)
+	wanted := utils.ToLowerTrim(reference)
+	switch {
+	case wanted == "" || title == "":
+		return scoreNone
+	case title == wanted:
+		return scoreTitleExact
+	case strings.HasPrefix(title, wanted):
+		return scoreTitlePrefix
+	case strings.Contains(title, wanted):
+		return scoreTitleContains
+	}
+	for _, word := range strings.Fields(wanted) {
+		if !strings.Contains(title, word) {
+			return scoreNone
+		}
+	}
+	return scoreTitleWords
+}
+
+func ambiguousReference(reference string, matches []filters.Candidate) error {
+	var listing strings.Builder
+	for index, candidate := range matches {
+		if index == ambiguousListLimit {
+			fmt.Fprintf(&listing, "\n  … and %d more", len(matches)-ambiguousListLimit)
+			break
+		}
+		conversation := candidate.Conversation
+		fmt.Fprintf(&listing, "\n  %s  %s  %s", conversation.ID, conversation.CreateTime.Format("2006-01-02"), conversation.Title)
+	}
+	return fmt.Errorf("%d conversations match %q; pass one of their ids:%s", len(matches), reference, listing.String())
+}
@@ -0,0 +1,49 @@
+package extract
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const (
+	sidecarFileName        = "metadata.json"
+	instructionsSidecarKey = "instructions"
+)
+
+// conversationSidecar assembles metadata.json: what indexing a result needs without reparsing the conversation
+// (id, title, times, model, message count, languages, content types, gizmo id, and the attachments written),
+// followed by the account-level records of the export and the instructions the assistant was given.
+func conversationSidecar(candidate filters.Candidate, attachmentNames []string) map[string]any {
+	conversation := candidate.Conversation
+	sidecar := map[string]any{
+		"id":            conversation.ID,
+		"title":         conversation.Title,
+		"create_time":   conversation.CreateTime.Format(time.RFC3339),
+		"model":         model.Model(conversation),
+		"message_count": len(utils.VisibleMessages(model.ActiveBranch(conversation))),
+		"languages":     sortedNames(filters.EnumerateLanguages(conversation)),
+		"content_types": sortedNames(filters.EnumerateContentTypes(conversation)),
+		"attachments":   attachmentNames,
+	}
+	if !conversation.UpdateTime.IsZero() {
+		sidecar["update_time"] = conversation.UpdateTime.Format(time.RFC3339)
+	}
+	if conversation.GizmoID != "" {
+		sidecar["gizmo_id"] = conversation.GizmoID
+	}
+	maps.Copy(sidecar, candidate.Archive.Metadata(candidate.Origin.Root).Sidecar(conversation.ID))
+	if instructions := model.EffectiveInstructions(conversation); !instructions.IsEmpty() {
+		sidecar[instructionsSidecarKey] = instructions
+	}
+	return sidecar
+}
+
+// sortedNames lists a set in sorted order, as an empty list rather than null when the set is empty.
+func sortedNames(set map[string]struct{}) []string {
+	return append([]string{}, slices.Sorted(maps.Keys(set))...)
+}
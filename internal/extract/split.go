@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"openai_extract/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const splitFileExtension = ".json"
+
+// RunSplit writes every conversation of the exports into outputFolder as its own pretty-printed JSON file,
+// named like extraction folders after its start date in location and its slugified title, e.g.
+// "2024-03-01_terraform-module-refactor.json". No pattern is involved: the whole history is unpacked, keeping
+// the newest copy of a conversation present in several exports.
+func RunSplit(ctx context.Context, inputSettings InputSettings, outputFolder string, location *time.Location) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+
+	conversations, collectErr := newestConversations(ctx, logger, sources, inputSettings.Paths)
+	if collectErr != nil {
+		return collectErr
+	}
+
+	if mkErr := utils.EnsureDir(outputFolder); mkErr != nil {
+		return fmt.Errorf("create output folder: %w", mkErr)
+	}
+	usedNames := make(map[string]int)
+	for _, candidate := range conversations {
+		fileName := nextConversationName(usedNames, candidate.Conversation.In(location)) + splitFileExtension
+		if writeErr := utils.WritePrettyJSON(filepath.Join(outputFolder, fileName), candidate.Serialized); writeErr != nil {
+			return fmt.Errorf("write conversation %s: %w", candidate.Conversation.ID, writeErr)
+		}
+	}
+	utils.PrintLine(fmt.Sprintf("wrote %d conversations to %s", len(conversations), outputFolder))
+	return nil
+}
@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/utils"
+)
+
+// extractionState remembers which conversations earlier runs extracted, and at which update time, so a run
+// against a newer export writes only new or changed conversations.
+type extractionState struct {
+	path          string
+	Conversations map[string]extractedConversation `json:"conversations"`
+	dirty         bool
+}
+
+type extractedConversation struct {
+	UpdateTime  time.Time `json:"update_time"`
+	Folder      string    `json:"folder,omitempty"`
+	ExtractedAt time.Time `json:"extracted_at"`
+}
+
+// loadExtractionState reads the state file at path; a missing file yields an empty state.
+func loadExtractionState(path string) (*extractionState, error) {
+	state := &extractionState{path: path, Conversations: make(map[string]extractedConversation)}
+	content, readErr := os.ReadFile(path)
+	if errors.Is(readErr, fs.ErrNotExist) {
+		return state, nil
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("read state %q: %w", path, readErr)
+	}
+	if decodeErr := json.Unmarshal(content, state); decodeErr != nil {
+		return nil, fmt.Errorf("parse state %q: %w", path, decodeErr)
+	}
+	if state.Conversations == nil {
+		state.Conversations = make(map[string]extractedConversation)
+	}
+	return state, nil
+}
+
+// unchanged reports whether the candidate was already extracted at its current update time or later.
+func (state *extractionState) unchanged(candidate filters.Candidate) bool {
+	previous, extracted := state.Conversations[candidate.Conversation.ID]
+	if !extracted {
+		return false
+	}
+	return !updateTimeOf(candidate).After(previous.UpdateTime)
+}
+
+// pending drops the candidates an earlier run already extracted unchanged.
+func (state *extractionState) pending(candidates []filters.Candidate) []filters.Candidate {
+	kept := candidates[:0]
+	for _, candidate := range candidates {
+		if !state.unchanged(candidate) {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
+
+func (state *extractionState) record(candidate filters.Candidate, folder string) {
+	conversationID := candidate.Conversation.ID
+	if conversationID == "" {
+		return
+	}
+	state.Conversations[conversationID] = extractedConversation{
+		UpdateTime:  updateTimeOf(candidate),
+		Folder:      folder,
+		ExtractedAt: time.Now().UTC(),
+	}
+	state.dirty = true
+}
+
+// save writes the state back when conversations were recorded.
+func (state *extractionState) save() error {
+	if !state.dirty {
+		return nil
+	}
+	encoded, encodeErr := json.MarshalIndent(state, "", "  ")
+	if encodeErr != nil {
+		return fmt.Errorf("encode state: %w", encodeErr)
+	}
+	if writeErr := utils.WriteFileAtomic(state.path, encoded); writeErr != nil {
+		return fmt.Errorf("write state: %w", writeErr)
+	}
+	state.dirty = false
+	return nil
+}
+
+// updateTimeOf returns the conversation update_time, falling back to create_time for never-updated ones.
+func updateTimeOf(candidate filters.Candidate) time.Time {
+	updated := candidate.Conversation.UpdateTime
+	if updated.IsZero() {
+		return candidate.Conversation.CreateTime
+	}
+	return updated
+}
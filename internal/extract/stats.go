@@ -0,0 +1,115 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+	"openai_extract/internal/utils"
+)
+
+const (
+	statsMonthLayout = "2006-01"
+	unknownModel     = "unknown"
+)
+
+// conversationSummary is what the statistics need of one conversation, kept instead of the conversation so
+// large exports can be summarized without holding them in memory.
+type conversationSummary struct {
+	created     time.Time
+	updated     time.Time
+	messages    int
+	model       string
+	attachments []string
+}
+
+// RunStats summarizes every conversation in the exports, counting a conversation present in several of them
+// once, by its most recently updated copy, and prints the statistics to stdout.
+func RunStats(ctx context.Context, inputSettings InputSettings, format render.StatsFormat) error {
+	summaries, collectErr := collectSummaries(ctx, inputSettings)
+	if collectErr != nil {
+		return collectErr
+	}
+	rendered, renderErr := render.RenderStats(aggregateStats(summaries), format)
+	if renderErr != nil {
+		return renderErr
+	}
+	_, writeErr := os.Stdout.Write(rendered)
+	return writeErr
+}
+
+func collectSummaries(ctx context.Context, inputSettings InputSettings) (map[string]conversationSummary, error) {
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer closeSources(sources)
+	files := archive.Combine(sources)
+
+	summaries := make(map[string]conversationSummary)
+	for index, source := range sources {
+		if len(sources) > 1 && !source.HasConversations() {
+			continue
+		}
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				return nil
+			}
+			key := conversation.ID
+			if key == "" {
+				key = fmt.Sprintf("#%d", len(summaries))
+			} else if previous, seen := summaries[key]; seen && !conversation.UpdateTime.After(previous.updated) {
+				return nil
+			}
+			candidate := filters.Candidate{Conversation: conversation, Serialized: serialized, Archive: files, Origin: origin}
+			summaries[key] = conversationSummary{
+				created:     conversation.CreateTime,
+				updated:     conversation.UpdateTime,
+				messages:    utils.CountDialogueMessages(model.ActiveBranch(conversation)),
+				model:       model.Model(conversation),
+				attachments: filters.CollectLinkedFiles(candidate),
+			}
+			return nil
+		})
+		if scanErr != nil {
+			return nil, fmt.Errorf("%s: %w", inputSettings.Paths[index], scanErr)
+		}
+	}
+	return summaries, nil
+}
+
+func aggregateStats(summaries map[string]conversationSummary) render.Stats {
+	stats := render.Stats{ConversationsPerMonth: make(map[string]int), Models: make(map[string]int)}
+	for _, summary := range summaries {
+		stats.Conversations++
+		stats.Messages += summary.messages
+		if stats.FirstConversation.IsZero() || summary.created.Before(stats.FirstConversation) {
+			stats.FirstConversation = summary.created
+		}
+		if summary.created.After(stats.LastConversation) {
+			stats.LastConversation = summary.created
+		}
+		stats.ConversationsPerMonth[summary.created.Format(statsMonthLayout)]++
+		slug := summary.model
+		if slug == "" {
+			slug = unknownModel
+		}
+		stats.Models[slug]++
+		if len(summary.attachments) > 0 {
+			stats.ConversationsWithAttachments++
+		}
+		for _, attachment := range summary.attachments {
+			stats.Attachments++
+			if filters.IsImagePath(attachment) {
+				stats.ImageAttachments++
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,155 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+)
+
+// validationReport is the machine-readable outcome of RunValidate. Lists are always present, empty when there
+// is nothing to report, so consumers need not tell missing from empty.
+type validationReport struct {
+	Valid    bool            `json:"valid"`
+	Archives []archiveReport `json:"archives"`
+}
+
+type archiveReport struct {
+	Path                 string                      `json:"path"`
+	Valid                bool                        `json:"valid"`
+	Entries              int                         `json:"entries"`
+	StructureErrors      []string                    `json:"structure_errors"`
+	CorruptEntries       []corruptEntryReport        `json:"corrupt_entries"`
+	Conversations        int                         `json:"conversations"`
+	SchemaVersions       map[model.SchemaVersion]int `json:"schema_versions"`
+	InvalidConversations []conversationReport        `json:"invalid_conversations"`
+	AttachmentReferences int                         `json:"attachment_references"`
+	MissingAttachments   []missingAttachment         `json:"missing_attachments"`
+}
+
+type corruptEntryReport struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+type conversationReport struct {
+	Position int      `json:"position"`
+	ID       string   `json:"id,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Problems []string `json:"problems"`
+}
+
+type missingAttachment struct {
+	Conversation string `json:"conversation"`
+	Message      string `json:"message"`
+	File         string `json:"file"`
+}
+
+// RunValidate checks every export without extracting anything and prints a JSON report to stdout: the
+// archive structure, the schema version of each conversation, whether each conversation parses with an
+// intact message tree, and whether every referenced attachment is present. It fails when any export has
+// problems, after printing the report.
+func RunValidate(ctx context.Context, inputSettings InputSettings) error {
+	sources, openErr := openSources(ctx, inputSettings)
+	if openErr != nil {
+		return openErr
+	}
+	defer closeSources(sources)
+	files := archive.Combine(sources)
+
+	anyConversations := false
+	for _, source := range sources {
+		anyConversations = anyConversations || source.HasConversations()
+	}
+	report := validationReport{Valid: true}
+	for index, source := range sources {
+		// Attachment-only parts of a split export are fine as long as some part holds the conversations.
+		requireConversations := len(sources) == 1 || !anyConversations
+		archiveResult := validateArchive(ctx, inputSettings.Paths[index], source, files, requireConversations)
+		report.Valid = report.Valid && archiveResult.Valid
+		report.Archives = append(report.Archives, archiveResult)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		return fmt.Errorf("write report: %w", encodeErr)
+	}
+	if !report.Valid {
+		return fmt.Errorf("validation found problems in %d of %d exports", countInvalid(report.Archives), len(report.Archives))
+	}
+	return nil
+}
+
+func validateArchive(ctx context.Context, archiveFilePath string, source *archive.Archive, files *archive.Archive, requireConversations bool) archiveReport {
+	result := archiveReport{
+		Path:                 archiveFilePath,
+		Entries:              len(source.Names()),
+		StructureErrors:      []string{},
+		CorruptEntries:       []corruptEntryReport{},
+		SchemaVersions:       make(map[model.SchemaVersion]int),
+		InvalidConversations: []conversationReport{},
+		MissingAttachments:   []missingAttachment{},
+	}
+	for _, entry := range source.VerifyEntries() {
+		result.CorruptEntries = append(result.CorruptEntries, corruptEntryReport{Name: entry.Name, Error: entry.Err.Error()})
+	}
+
+	if !source.HasConversations() {
+		if requireConversations {
+			result.StructureErrors = append(result.StructureErrors, "neither conversations.json nor chat.html found in archive")
+		}
+	} else {
+		scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+			result.Conversations++
+			result.SchemaVersions[model.DetectSchema(serialized)]++
+			conversation, decodeErr := model.Decode(serialized)
+			if decodeErr != nil {
+				result.InvalidConversations = append(result.InvalidConversations, conversationReport{
+					Position: result.Conversations,
+					Problems: []string{fmt.Sprintf("invalid JSON: %v", decodeErr)},
+				})
+				return nil
+			}
+			if problems := model.CheckStructure(conversation); len(problems) > 0 {
+				result.InvalidConversations = append(result.InvalidConversations, conversationReport{
+					Position: result.Conversations,
+					ID:       conversation.ID,
+					Title:    conversation.Title,
+					Problems: problems,
+				})
+			}
+			eachAttachment(conversation, func(messageID string, fileID string) {
+				result.AttachmentReferences++
+				if !files.HasFile(origin.Root, fileID) {
+					result.MissingAttachments = append(result.MissingAttachments, missingAttachment{
+						Conversation: conversation.ID,
+						Message:      messageID,
+						File:         fileID,
+					})
+				}
+			})
+			return nil
+		})
+		if scanErr != nil {
+			result.StructureErrors = append(result.StructureErrors, fmt.Sprintf("read conversations: %v", scanErr))
+		}
+	}
+
+	result.Valid = len(result.StructureErrors) == 0 && len(result.CorruptEntries) == 0 &&
+		len(result.InvalidConversations) == 0 && len(result.MissingAttachments) == 0
+	return result
+}
+
+func countInvalid(archives []archiveReport) int {
+	invalid := 0
+	for _, archiveResult := range archives {
+		if !archiveResult.Valid {
+			invalid++
+		}
+	}
+	return invalid
+}
@@ -0,0 +1,66 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// verifyArchive checks an export before anything is extracted from it: every entry must read back intact,
+// and every file referenced by an asset pointer must be present in files, which spans all parts of a split
+// export. Each problem is logged; the returned error summarizes them.
+func verifyArchive(ctx context.Context, logger *zap.Logger, archiveFilePath string, source *archive.Archive, files *archive.Archive) error {
+	corrupt := source.VerifyEntries()
+	for _, entry := range corrupt {
+		logger.Warn("corrupted archive entry", zap.String("archive", archiveFilePath), zap.String("entry", entry.Name), zap.Error(entry.Err))
+	}
+
+	missing := 0
+	if !source.HasConversations() {
+		if len(corrupt) > 0 {
+			return fmt.Errorf("verify %s: %d corrupted entries", archiveFilePath, len(corrupt))
+		}
+		return nil
+	}
+	scanErr := source.EachConversation(ctx, func(serialized []byte, origin archive.Origin) error {
+		conversation, decodeErr := model.Decode(serialized)
+		if decodeErr != nil {
+			return nil
+		}
+		eachAttachment(conversation, func(messageID string, fileID string) {
+			if files.HasFile(origin.Root, fileID) {
+				return
+			}
+			missing++
+			logger.Warn("missing attachment",
+				zap.String("archive", archiveFilePath),
+				zap.String("conversation", conversation.ID),
+				zap.String("message", messageID),
+				zap.String("file", fileID))
+		})
+		return nil
+	})
+	if scanErr != nil {
+		return fmt.Errorf("verify %s: %w", archiveFilePath, scanErr)
+	}
+	if len(corrupt) > 0 || missing > 0 {
+		return fmt.Errorf("verify %s: %d corrupted entries, %d missing attachments", archiveFilePath, len(corrupt), missing)
+	}
+	return nil
+}
+
+// eachAttachment hands visit the id of every file a message of the conversation references by asset pointer.
+func eachAttachment(conversation model.Conversation, visit func(messageID string, fileID string)) {
+	for _, message := range model.AllMessages(conversation) {
+		for _, asset := range message.Assets {
+			pointer, _ := asset["asset_pointer"].(string)
+			if fileID, isFile := archive.AssetFileID(pointer); isFile {
+				visit(message.ID, fileID)
+			}
+		}
+	}
+}
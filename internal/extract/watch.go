@@ -0,0 +1,114 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultWatchInterval is how often Watch looks for new exports.
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchSettings tunes how Watch looks for exports.
+type WatchSettings struct {
+	// Interval is the time between two looks at the folder.
+	Interval time.Duration
+	// ProcessExisting also hands over the exports already in the folder when watching starts.
+	ProcessExisting bool
+}
+
+// exportSnapshot is what a look at the folder saw of one export file.
+type exportSnapshot struct {
+	size     int64
+	modified time.Time
+}
+
+// Watch looks at folder every interval for export ZIPs and calls extract with the path of each new one. A ZIP is
+// handed over once its size and modification time held still between two looks, so an export still being
+// downloaded is not read half-written; one replaced later by a different file of the same name is handed over
+// again. Failed extractions are logged and watching goes on. Watch returns when ctx is done.
+func Watch(ctx context.Context, folder string, settings WatchSettings, extract func(exportPath string) error) error {
+	logger, loggerErr := zap.NewProduction()
+	if loggerErr != nil {
+		return fmt.Errorf("init logger: %w", loggerErr)
+	}
+	defer logger.Sync()
+
+	interval := settings.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	pending := make(map[string]exportSnapshot)
+	handled := make(map[string]exportSnapshot)
+	if !settings.ProcessExisting {
+		existing, listErr := listExports(folder)
+		if listErr != nil {
+			return listErr
+		}
+		handled = existing
+	}
+	logger.Info("watching for exports", zap.String("folder", folder), zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		current, listErr := listExports(folder)
+		if listErr != nil {
+			logger.Error("list exports", zap.String("folder", folder), zap.Error(listErr))
+		}
+		paths := make([]string, 0, len(current))
+		for exportPath := range current {
+			paths = append(paths, exportPath)
+		}
+		sort.Strings(paths)
+		for _, exportPath := range paths {
+			snapshot := current[exportPath]
+			if handled[exportPath] == snapshot {
+				continue
+			}
+			if pending[exportPath] != snapshot {
+				pending[exportPath] = snapshot
+				continue
+			}
+			delete(pending, exportPath)
+			handled[exportPath] = snapshot
+			logger.Info("extracting new export", zap.String("export", exportPath))
+			if extractErr := extract(exportPath); extractErr != nil {
+				logger.Error("extract export", zap.String("export", exportPath), zap.Error(extractErr))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// listExports returns the ZIP files directly in folder, skipping hidden ones such as partial downloads.
+func listExports(folder string) (map[string]exportSnapshot, error) {
+	dirEntries, readErr := os.ReadDir(folder)
+	if readErr != nil {
+		return nil, fmt.Errorf("read watched folder: %w", readErr)
+	}
+	exports := make(map[string]exportSnapshot)
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), zipSuffix) {
+			continue
+		}
+		info, infoErr := dirEntry.Info()
+		if infoErr != nil {
+			continue
+		}
+		exports[filepath.Join(folder, name)] = exportSnapshot{size: info.Size(), modified: info.ModTime()}
+	}
+	return exports, nil
+}
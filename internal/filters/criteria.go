@@ -0,0 +1,402 @@
+package filters
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const qualifierTimeLayout = time.RFC3339
+
+// Candidate is a conversation under evaluation, in decoded and serialized form, with the export it was read from.
+type Candidate struct {
+	Conversation model.Conversation
+	Serialized   []byte
+	Archive      *archive.Archive
+	Origin       archive.Origin
+}
+
+// Predicate reports whether a candidate conversation passes one filter.
+type Predicate func(candidate Candidate) bool
+
+// Criteria holds the conversation-level filters applied after pattern matching.
+type Criteria struct {
+	ContentTypes []string
+	Languages    []string
+	FilterMode   MatchMode
+	Since        time.Time
+	Until        time.Time
+	UpdatedSince time.Time
+	MinMessages  int
+	MaxMessages  int
+	MinWords     int
+	MinTokens    int
+	MinDuration  time.Duration
+	MaxDuration  time.Duration
+	HasFiles     bool
+	HasImages    bool
+	HasDalle     bool
+	HasCanvas    bool
+	Voice        bool
+	Tools        []string
+	GPTs         []string
+	Projects     []string
+	Members      []string
+	Archived     *bool
+	Starred      *bool
+	Page         Page
+}
+
+// setMatcher reports whether the values found satisfy the desired ones, ranging over found only until the
+// outcome is known.
+type setMatcher func(found iter.Seq[string], desired []string, normalizer func(string) string) bool
+
+var filterModeMatchers = map[MatchMode]setMatcher{
+	MatchAll: yieldsAllDesired,
+	MatchAny: yieldsAnyDesired,
+}
+
+var filterModeQualifierPrefixes = map[MatchMode]string{
+	MatchAll: "",
+	MatchAny: "any of ",
+}
+
+type criterion struct {
+	active    func(criteria Criteria) bool
+	predicate func(criteria Criteria) Predicate
+	qualifier func(criteria Criteria) string
+	// costly marks predicates that walk every message or the archive, which run after the others so a
+	// conversation a cheap predicate rejects never reaches them.
+	costly bool
+}
+
+var criteriaTable = []criterion{
+	{
+		active: func(criteria Criteria) bool { return len(criteria.ContentTypes) > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return criteria.setMatcher()(contentTypes(candidate.Conversation), criteria.ContentTypes, utils.ToLowerTrim)
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("%scontent type(s) %q", criteria.setQualifierPrefix(), strings.Join(criteria.ContentTypes, ","))
+		},
+		costly: true,
+	},
+	{
+		active: func(criteria Criteria) bool { return len(criteria.Languages) > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return criteria.setMatcher()(languages(candidate.Conversation), criteria.Languages, NormalizeLanguageName)
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("%slanguage(s) %q", criteria.setQualifierPrefix(), strings.Join(criteria.Languages, ","))
+		},
+		costly: true,
+	},
+	{
+		active: func(criteria Criteria) bool { return !criteria.Since.IsZero() },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return !candidate.Conversation.CreateTime.Before(criteria.Since)
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return "created since " + criteria.Since.Format(qualifierTimeLayout)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return !criteria.Until.IsZero() },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return !candidate.Conversation.CreateTime.After(criteria.Until)
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return "created until " + criteria.Until.Format(qualifierTimeLayout)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return !criteria.UpdatedSince.IsZero() },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				updated := candidate.Conversation.UpdateTime
+				if updated.IsZero() {
+					updated = candidate.Conversation.CreateTime
+				}
+				return !updated.Before(criteria.UpdatedSince)
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return "updated since " + criteria.UpdatedSince.Format(qualifierTimeLayout)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.MinMessages > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return utils.CountDialogueMessages(model.ActiveBranch(candidate.Conversation)) >= criteria.MinMessages
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("at least %d messages", criteria.MinMessages)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.MaxMessages > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return utils.CountDialogueMessages(model.ActiveBranch(candidate.Conversation)) <= criteria.MaxMessages
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("at most %d messages", criteria.MaxMessages)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.MinWords > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return utils.CountWords(utils.DialogueText(model.ActiveBranch(candidate.Conversation))) >= criteria.MinWords
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("at least %d words", criteria.MinWords)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.MinTokens > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return utils.EstimateTokens(utils.DialogueText(model.ActiveBranch(candidate.Conversation))) >= criteria.MinTokens
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("at least %d estimated tokens", criteria.MinTokens)
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.MinDuration > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return utils.ConversationSpan(model.ActiveBranch(candidate.Conversation)) >= criteria.MinDuration
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return "a duration of at least " + criteria.MinDuration.String()
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.MaxDuration > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return utils.ConversationSpan(model.ActiveBranch(candidate.Conversation)) <= criteria.MaxDuration
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return "a duration of at most " + criteria.MaxDuration.String()
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.HasFiles },
+		predicate: func(_ Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return len(CollectLinkedFiles(candidate)) > 0
+			}
+		},
+		qualifier: func(_ Criteria) string { return "attached files" },
+		costly:    true,
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.HasImages },
+		predicate: func(_ Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				for _, archivePath := range CollectLinkedFiles(candidate) {
+					if IsImagePath(archivePath) {
+						return true
+					}
+				}
+				return false
+			}
+		},
+		qualifier: func(_ Criteria) string { return "attached images" },
+		costly:    true,
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.HasDalle },
+		predicate: func(_ Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return UsesDalle(model.ActiveBranch(candidate.Conversation))
+			}
+		},
+		qualifier: func(_ Criteria) string { return "DALL-E image generation" },
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.HasCanvas },
+		predicate: func(_ Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return UsesCanvas(model.ActiveBranch(candidate.Conversation))
+			}
+		},
+		qualifier: func(_ Criteria) string { return "canvas documents" },
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.Voice },
+		predicate: func(_ Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return IsVoiceConversation(candidate.Conversation, model.ActiveBranch(candidate.Conversation))
+			}
+		},
+		qualifier: func(_ Criteria) string { return "voice mode" },
+	},
+	{
+		active: func(criteria Criteria) bool { return len(criteria.Tools) > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return HasAllDesired(EnumerateTools(candidate.Conversation, model.ActiveBranch(candidate.Conversation)), criteria.Tools, NormalizeToolName)
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("tool(s) %q", strings.Join(criteria.Tools, ","))
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return len(criteria.GPTs) > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				for _, wanted := range criteria.GPTs {
+					if MatchesGPT(candidate.Conversation, wanted) {
+						return true
+					}
+				}
+				return false
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("custom GPT(s) %q", strings.Join(criteria.GPTs, ","))
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return len(criteria.Projects) > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				for _, wanted := range criteria.Projects {
+					if MatchesProject(candidate.Conversation, wanted) {
+						return true
+					}
+				}
+				return false
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("project(s) %q", strings.Join(criteria.Projects, ","))
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return len(criteria.Members) > 0 },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				for _, wanted := range criteria.Members {
+					if MatchesMember(candidate.Origin, wanted) {
+						return true
+					}
+				}
+				return false
+			}
+		},
+		qualifier: func(criteria Criteria) string {
+			return fmt.Sprintf("workspace member(s) %q", strings.Join(criteria.Members, ","))
+		},
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.Archived != nil },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return candidate.Conversation.IsArchived == *criteria.Archived
+			}
+		},
+		qualifier: func(criteria Criteria) string { return statusQualifier("archived", *criteria.Archived) },
+	},
+	{
+		active: func(criteria Criteria) bool { return criteria.Starred != nil },
+		predicate: func(criteria Criteria) Predicate {
+			return func(candidate Candidate) bool {
+				return candidate.Conversation.IsStarred == *criteria.Starred
+			}
+		},
+		qualifier: func(criteria Criteria) string { return statusQualifier("starred", *criteria.Starred) },
+	},
+}
+
+// Validate reports unknown criteria settings.
+func (criteria Criteria) Validate() error {
+	if _, known := filterModeMatchers[criteria.effectiveFilterMode()]; !known {
+		return fmt.Errorf("unknown filter mode %q (supported: %s, %s)", criteria.FilterMode, MatchAny, MatchAll)
+	}
+	return nil
+}
+
+func (criteria Criteria) effectiveFilterMode() MatchMode {
+	if criteria.FilterMode == "" {
+		return MatchAll
+	}
+	return MatchMode(utils.ToLowerTrim(string(criteria.FilterMode)))
+}
+
+func (criteria Criteria) setMatcher() setMatcher {
+	return filterModeMatchers[criteria.effectiveFilterMode()]
+}
+
+func (criteria Criteria) setQualifierPrefix() string {
+	return filterModeQualifierPrefixes[criteria.effectiveFilterMode()]
+}
+
+// Predicates returns the predicates for every filter configured in the criteria, the costly ones last, so
+// MatchesAll stops at a cheap rejection before reaching them. Filters that are not configured add nothing.
+func (criteria Criteria) Predicates() []Predicate {
+	var predicates, costly []Predicate
+	for _, entry := range criteriaTable {
+		switch {
+		case !entry.active(criteria):
+		case entry.costly:
+			costly = append(costly, entry.predicate(criteria))
+		default:
+			predicates = append(predicates, entry.predicate(criteria))
+		}
+	}
+	return append(predicates, costly...)
+}
+
+// Qualifiers describes every configured filter for use in no-match errors.
+func (criteria Criteria) Qualifiers() []string {
+	var qualifiers []string
+	for _, entry := range criteriaTable {
+		if entry.active(criteria) {
+			qualifiers = append(qualifiers, entry.qualifier(criteria))
+		}
+	}
+	return qualifiers
+}
+
+func statusQualifier(status string, wanted bool) string {
+	if wanted {
+		return status + " status"
+	}
+	return "non-" + status + " status"
+}
+
+// MatchesAll reports whether the candidate satisfies every predicate.
+func MatchesAll(predicates []Predicate, candidate Candidate) bool {
+	for _, predicate := range predicates {
+		if !predicate(candidate) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package filters
+
+import (
+	"iter"
+	"regexp"
+	"sort"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+const (
+	fencedDetectionThreshold   = 3
+	unfencedDetectionThreshold = 4
+	unfencedMinimumSignals     = 2
+)
+
+type languageSignal struct {
+	expression *regexp.Regexp
+	weight     int
+}
+
+func signal(expression string, weight int) languageSignal {
+	return languageSignal{expression: regexp.MustCompile(`(?m)` + expression), weight: weight}
+}
+
+var (
+	languageSignatures = map[string][]languageSignal{
+		"go": {
+			signal(`^package \w+$`, 3),
+			signal(`\bfunc (\(\w+ \*?\w+\) )?\w+\(`, 3),
+			signal(`\w+ := `, 1),
+			signal(`\bfmt\.\w+\(`, 3),
+			signal(`^import \($`, 3),
+			signal(`\berr != nil\b`, 3),
+		},
+		"python": {
+			signal(`^\s*def \w+\(.*\):\s*$`, 3),
+			signal(`^\s*from [\w.]+ import \w+`, 3),
+			signal(`^\s*import \w+ as \w+$`, 3),
+			signal(`^\s*import [\w.]+$`, 1),
+			signal(`\bprint\(`, 1),
+			signal(`\bself\.\w+`, 2),
+			signal(`^\s*(elif .*|except.*|if __name__ == .*):\s*$`, 3),
+		},
+		"javascript": {
+			signal(`\bconsole\.log\(`, 3),
+			signal(`\b(const|let) \w+ = `, 1),
+			signal(`\) => \{`, 2),
+			signal(`\bfunction \w*\(`, 2),
+			signal(`\brequire\(['"]`, 3),
+			signal(`\bdocument\.(getElementById|querySelector)`, 3),
+			signal(`^export (default )?(function|const|class)\b`, 2),
+		},
+		"typescript": {
+			signal(`^\s*(export )?interface \w+ \{`, 3),
+			signal(`^\s*(export )?type \w+ = `, 3),
+			signal(`\w+\??: (string|number|boolean)\b`, 2),
+		},
+		"shell": {
+			signal(`^#!/(usr/)?bin/(env )?(ba|z)?sh`, 4),
+			signal(`^\s*(sudo|apt-get|apt|brew|echo|export|cd|mkdir|chmod|curl|wget|git)\s`, 2),
+			signal(`^\s*fi\s*$`, 3),
+			signal(`\$\{\w+\}`, 1),
+		},
+		"java": {
+			signal(`\bpublic (static )?(final )?(class|void|int|String)\b`, 3),
+			signal(`\bSystem\.out\.println\(`, 4),
+			signal(`^import java\.`, 4),
+		},
+		"rust": {
+			signal(`\bfn \w+\(`, 2),
+			signal(`\blet mut \w+`, 3),
+			signal(`\bprintln!\(`, 4),
+			signal(`^use \w+(::\w+)+;`, 3),
+		},
+		"cpp": {
+			signal(`^#include <\w+(\.h)?>`, 3),
+			signal(`\bstd::\w+`, 3),
+			signal(`\bcout <<`, 3),
+		},
+		"csharp": {
+			signal(`^using System(\.\w+)*;`, 4),
+			signal(`\bConsole\.WriteLine\(`, 4),
+			signal(`\bnamespace \w+(\.\w+)*\s*\{?$`, 2),
+		},
+		"ruby": {
+			signal(`^\s*def \w+[?!]?$`, 2),
+			signal(`^\s*puts\s`, 2),
+			signal(`^\s*end$`, 1),
+			signal(`^require ['"]\w+['"]$`, 3),
+		},
+		"php": {
+			signal(`<\?php`, 5),
+			signal(`\$\w+->\w+`, 2),
+		},
+		"sql": {
+			signal(`(?i)^\s*select\s.+\sfrom\s`, 3),
+			signal(`(?i)^\s*insert into\s`, 3),
+			signal(`(?i)^\s*create table\s`, 4),
+			signal(`(?i)\bwhere\s+\w+\s*=`, 1),
+		},
+		"html": {
+			signal(`(?i)<!doctype html>`, 5),
+			signal(`(?i)</?(html|head|body|div|span)\b[^>]*>`, 2),
+		},
+		"css": {
+			signal(`^\s*[.#]?[\w-]+\s*\{\s*$`, 1),
+			signal(`^\s*(color|margin|padding|display|font-size):\s*[^;]+;`, 3),
+		},
+		"hcl": {
+			signal(`^(resource|provider|module|variable) "\w+"`, 4),
+		},
+	}
+)
+
+// DetectableLanguages returns the languages DetectLanguage can report, sorted.
+func DetectableLanguages() []string {
+	languages := make([]string, 0, len(languageSignatures))
+	for language := range languageSignatures {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// DetectLanguage classifies a piece of code by scoring weighted syntax signals; it returns the best-scoring
+// language when its score reaches the threshold and at least minimumSignals distinct signals fired.
+func DetectLanguage(code string, threshold int, minimumSignals int) (string, bool) {
+	bestLanguage := ""
+	bestScore := 0
+	for language, signals := range languageSignatures {
+		score := 0
+		fired := 0
+		for _, candidate := range signals {
+			if candidate.expression.MatchString(code) {
+				score += candidate.weight
+				fired++
+			}
+		}
+		if fired < minimumSignals || score < threshold {
+			continue
+		}
+		if score > bestScore || (score == bestScore && language < bestLanguage) {
+			bestLanguage = language
+			bestScore = score
+		}
+	}
+	return bestLanguage, bestLanguage != ""
+}
+
+// detectContentLanguages classifies the fenced code blocks of a message text and the code left outside them,
+// yielding each language found; blocks after the caller stops ranging are not classified.
+func detectContentLanguages(text string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if !strings.Contains(text, "\n") {
+			return
+		}
+		for _, block := range utils.FindCodeBlocks(text) {
+			if language, ok := DetectLanguage(block.Body, fencedDetectionThreshold, 1); ok && !yield(language) {
+				return
+			}
+		}
+		if language, ok := DetectLanguage(utils.StripCodeBlocks(text), unfencedDetectionThreshold, unfencedMinimumSignals); ok {
+			yield(language)
+		}
+	}
+}
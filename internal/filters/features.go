@@ -0,0 +1,130 @@
+package filters
+
+import (
+	"regexp"
+	"strings"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const (
+	dalleToolPrefix  = "dalle"
+	dalleMetadata    = "dalle"
+	assetMetadata    = "metadata"
+	projectIDPrefix  = "g-p-"
+	canvasToolPrefix = "canmore."
+	canvasMetadata   = "canvas"
+)
+
+var canvasContentMarkers = []string{"canvas", "textdoc"}
+
+const (
+	voiceMessageMetadata = "voice_mode_message"
+	audioContentMarker   = "audio"
+	assetContentType     = "content_type"
+)
+
+var reGizmoID = regexp.MustCompile(`(?i)\bg-(?:p-)?[a-z0-9]+`)
+
+// MatchesGPT reports whether the conversation was held with the custom GPT identified by
+// an id (also accepted inside a GPT URL or slug) or by its display name when the export records one.
+func MatchesGPT(conversation model.Conversation, wanted string) bool {
+	wanted = strings.TrimSpace(wanted)
+	if identifier := reGizmoID.FindString(wanted); identifier != "" {
+		for _, value := range []string{conversation.GizmoID, conversation.TemplateID} {
+			if value != "" && strings.EqualFold(value, identifier) {
+				return true
+			}
+		}
+		return false
+	}
+	return conversation.GizmoName != "" && strings.EqualFold(conversation.GizmoName, wanted)
+}
+
+// UsesDalle reports whether any message invoked the DALL-E tool or carries an image generated by it.
+func UsesDalle(messages []utils.Message) bool {
+	for _, message := range messages {
+		if strings.HasPrefix(message.AuthorName, dalleToolPrefix) || strings.HasPrefix(message.Recipient, dalleToolPrefix) {
+			return true
+		}
+		for _, asset := range message.Assets {
+			metadata, _ := asset[assetMetadata].(map[string]any)
+			if metadata[dalleMetadata] != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UsesCanvas reports whether any message created or edited a canvas/textdoc document.
+func UsesCanvas(messages []utils.Message) bool {
+	for _, message := range messages {
+		if strings.HasPrefix(message.Recipient, canvasToolPrefix) || strings.HasPrefix(message.AuthorName, canvasToolPrefix) {
+			return true
+		}
+		if message.Metadata[canvasMetadata] != nil {
+			return true
+		}
+		contentType := strings.ToLower(message.ContentType)
+		for _, marker := range canvasContentMarkers {
+			if strings.Contains(contentType, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsVoiceConversation reports whether the conversation was held in voice mode: a voice is recorded on the conversation,
+// a message is flagged as a voice-mode message, or a message carries audio asset pointers or transcriptions.
+func IsVoiceConversation(conversation model.Conversation, messages []utils.Message) bool {
+	if conversation.Voice != "" {
+		return true
+	}
+	for _, message := range messages {
+		if flagged, _ := message.Metadata[voiceMessageMetadata].(bool); flagged {
+			return true
+		}
+		for _, asset := range message.Assets {
+			if contentType, _ := asset[assetContentType].(string); strings.Contains(contentType, audioContentMarker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProjectOf returns the ChatGPT Project id and, when the export records it, the project name of a conversation.
+func ProjectOf(conversation model.Conversation) (string, string) {
+	identifier := conversation.ProjectID
+	if identifier == "" && strings.HasPrefix(strings.ToLower(conversation.GizmoID), projectIDPrefix) {
+		identifier = conversation.GizmoID
+	}
+	return identifier, conversation.ProjectName
+}
+
+// MatchesProject reports whether the conversation belongs to the project given by id (also inside a project URL) or name.
+func MatchesProject(conversation model.Conversation, wanted string) bool {
+	identifier, name := ProjectOf(conversation)
+	if identifier == "" && name == "" {
+		return false
+	}
+	wanted = strings.TrimSpace(wanted)
+	if wantedID := reGizmoID.FindString(wanted); wantedID != "" && strings.EqualFold(wantedID, identifier) {
+		return true
+	}
+	return strings.EqualFold(wanted, identifier) || (name != "" && strings.EqualFold(wanted, name))
+}
+
+// MatchesMember reports whether a conversation belongs to the wanted workspace member, given as the email
+// from the member's user.json or the name of the member's folder in the export.
+func MatchesMember(origin archive.Origin, wanted string) bool {
+	wanted = utils.ToLowerTrim(wanted)
+	if wanted == "" {
+		return false
+	}
+	return strings.ToLower(origin.Member) == wanted || strings.ToLower(origin.FolderName()) == wanted
+}
@@ -1,33 +1,52 @@
 package filters
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"iter"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
 )
 
-var (
-	reContentType   = regexp.MustCompile(`"content_type"\s*:\s*"([^"]+)"`)
-	reTypeField     = regexp.MustCompile(`"type"\s*:\s*"([^"]+)"`)
-	reLanguageField = regexp.MustCompile(`"language"\s*:\s*"([^"]+)"`)
-	reCodeFenceLang = regexp.MustCompile("```([A-Za-z0-9_+-]+)")
+var reCodeFenceLang = regexp.MustCompile("```([A-Za-z0-9_+-]+)")
+
+// lowerScratch recycles the buffers serialized conversations, which can run to megabytes, are lowered into.
+var lowerScratch = sync.Pool{New: func() any { return new([]byte) }}
+
+const (
+	assetPointerField   = "asset_pointer"
+	attachmentsMetadata = "attachments"
+	attachmentIDField   = "id"
 )
 
-// EnumerateContentTypes extracts content types present in a conversation JSON blob.
-func EnumerateContentTypes(conversationJSON []byte) map[string]struct{} {
-	result := make(map[string]struct{})
-	for _, m := range reContentType.FindAllSubmatch(conversationJSON, -1) {
-		if len(m) > 1 {
-			result[strings.ToLower(string(m[1]))] = struct{}{}
-		}
-	}
-	for _, m := range reTypeField.FindAllSubmatch(conversationJSON, -1) {
-		if len(m) > 1 {
-			result[strings.ToLower(string(m[1]))] = struct{}{}
+// EnumerateContentTypes returns the content types of every message in a conversation, on any branch,
+// and of the assets its messages carry.
+func EnumerateContentTypes(conversation model.Conversation) map[string]struct{} {
+	return collectSet(contentTypes(conversation))
+}
+
+// contentTypes yields the lowered content types EnumerateContentTypes collects, possibly more than once, and
+// stops reading messages once the caller stops ranging.
+func contentTypes(conversation model.Conversation) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, message := range model.AllMessages(conversation) {
+			if message.ContentType != "" && !yield(strings.ToLower(message.ContentType)) {
+				return
+			}
+			for _, asset := range message.Assets {
+				if contentType, _ := asset[assetContentType].(string); contentType != "" && !yield(strings.ToLower(contentType)) {
+					return
+				}
+			}
 		}
 	}
-	return result
 }
 
 // NormalizeLanguageName canonicalizes language names.
@@ -53,19 +72,44 @@ func NormalizeLanguageName(name string) string {
 	}
 }
 
-// EnumerateLanguages extracts languages from JSON "language" fields and Markdown code fences.
-func EnumerateLanguages(conversationJSON []byte) map[string]struct{} {
-	result := make(map[string]struct{})
-	for _, m := range reLanguageField.FindAllSubmatch(conversationJSON, -1) {
-		if len(m) > 1 {
-			result[NormalizeLanguageName(string(m[1]))] = struct{}{}
+// EnumerateLanguages returns the languages of a conversation's messages on any branch: the language recorded
+// on code messages, Markdown code fence labels, and a heuristic classification of fenced code bodies and
+// unfenced code in message text.
+func EnumerateLanguages(conversation model.Conversation) map[string]struct{} {
+	return collectSet(languages(conversation))
+}
+
+// languages yields the normalized languages EnumerateLanguages collects, possibly more than once. The recorded
+// languages and fence labels of every message come first, and the heuristic classifier, by far the costliest
+// source, only runs for as long as the caller keeps ranging.
+func languages(conversation model.Conversation) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		messages := model.AllMessages(conversation)
+		for _, message := range messages {
+			if message.Language != "" && !yield(NormalizeLanguageName(message.Language)) {
+				return
+			}
+			for _, m := range reCodeFenceLang.FindAllStringSubmatch(message.Text, -1) {
+				if len(m) > 1 && !yield(NormalizeLanguageName(m[1])) {
+					return
+				}
+			}
 		}
-	}
-	for _, m := range reCodeFenceLang.FindAllSubmatch(conversationJSON, -1) {
-		if len(m) > 1 {
-			result[NormalizeLanguageName(string(m[1]))] = struct{}{}
+		for _, message := range messages {
+			for language := range detectContentLanguages(message.Text) {
+				if !yield(language) {
+					return
+				}
+			}
 		}
 	}
+}
+
+func collectSet(values iter.Seq[string]) map[string]struct{} {
+	result := make(map[string]struct{})
+	for value := range values {
+		result[value] = struct{}{}
+	}
 	return result
 }
 
@@ -83,45 +127,133 @@ func HasAnyDesired(found map[string]struct{}, desired []string, normalizer func(
 	return false
 }
 
-// CollectLinkedFiles finds attachments under "files/" referenced by filename in the conversation JSON.
-func CollectLinkedFiles(conversationJSON []byte, fileContentMap map[string][]byte) map[string][]byte {
-	found := make(map[string][]byte)
+var imageExtensions = map[string]struct{}{
+	".png":  {},
+	".jpg":  {},
+	".jpeg": {},
+	".gif":  {},
+	".webp": {},
+	".bmp":  {},
+	".svg":  {},
+	".heic": {},
+	".tiff": {},
+}
 
-	var archiveFiles []string
-	for key := range fileContentMap {
-		lower := strings.ToLower(filepath.ToSlash(key))
-		if strings.HasPrefix(lower, "files/") && !strings.HasSuffix(lower, "/") {
-			archiveFiles = append(archiveFiles, key)
+// IsImagePath reports whether an archive path names an image file by its extension.
+func IsImagePath(archivePath string) bool {
+	_, image := imageExtensions[strings.ToLower(filepath.Ext(archivePath))]
+	return image
+}
+
+// CollectLinkedFiles finds the attachments a candidate references: entries resolved from the file ids of its
+// asset pointers and attachment metadata, wherever the export stores them below the conversation's root, and
+// files under root+"files/" whose name appears in the conversation JSON. Entries are returned in archive order.
+func CollectLinkedFiles(candidate Candidate) []string {
+	root := candidate.Origin.Root
+	resolved := make(map[string]struct{})
+	for _, fileID := range referencedFileIDs(candidate.Conversation) {
+		for _, name := range candidate.Archive.FileEntries(root, fileID) {
+			resolved[name] = struct{}{}
 		}
 	}
-
-	conversationStringLower := strings.ToLower(string(conversationJSON))
-	for _, archivePath := range archiveFiles {
+	var found []string
+	filesPrefix := strings.ToLower(root) + "files/"
+	scratch := lowerScratch.Get().(*[]byte)
+	defer lowerScratch.Put(scratch)
+	var conversationLower []byte
+	for _, archivePath := range candidate.Archive.Names() {
+		if _, referenced := resolved[archivePath]; referenced {
+			found = append(found, archivePath)
+			continue
+		}
+		lower := strings.ToLower(filepath.ToSlash(archivePath))
+		if !strings.HasPrefix(lower, filesPrefix) || strings.HasSuffix(lower, "/") {
+			continue
+		}
 		base := strings.ToLower(filepath.Base(archivePath))
 		if base == "" {
 			continue
 		}
-		if strings.Contains(conversationStringLower, base) {
-			found[archivePath] = fileContentMap[archivePath]
+		if conversationLower == nil {
+			*scratch = utils.AppendLower((*scratch)[:0], utils.UnescapeJSONUnicode(candidate.Serialized))
+			conversationLower = *scratch
+		}
+		if bytes.Contains(conversationLower, []byte(base)) {
+			found = append(found, archivePath)
 		}
 	}
 	return found
 }
 
+// referencedFileIDs returns the file ids a conversation's messages point at on any branch: asset pointers
+// in the content, and the ids of uploads listed in the attachments metadata.
+func referencedFileIDs(conversation model.Conversation) []string {
+	var fileIDs []string
+	for _, message := range model.AllMessages(conversation) {
+		for _, asset := range message.Assets {
+			pointer, _ := asset[assetPointerField].(string)
+			if fileID, isFile := archive.AssetFileID(pointer); isFile {
+				fileIDs = append(fileIDs, fileID)
+			}
+		}
+		attachments, _ := message.Metadata[attachmentsMetadata].([]any)
+		for _, rawAttachment := range attachments {
+			attachment, _ := rawAttachment.(map[string]any)
+			if fileID, _ := attachment[attachmentIDField].(string); fileID != "" {
+				fileIDs = append(fileIDs, fileID)
+			}
+		}
+	}
+	return fileIDs
+}
+
+// ErrNoMatch is wrapped by the errors BuildNoMatchError creates.
+var ErrNoMatch = errors.New("no conversations matched")
+
 // BuildNoMatchError creates a precise error when nothing matched.
-func BuildNoMatchError(patternCSV string, contentTypes []string, languages []string) error {
-	ct := strings.Join(contentTypes, ",")
-	lang := strings.Join(languages, ",")
-	switch {
-	case ct != "" && lang != "":
-		return fmt.Errorf("no conversations matched patterns [%s] with content type(s) %q and language(s) %q", patternCSV, ct, lang)
-	case ct != "":
-		return fmt.Errorf("no conversations matched patterns [%s] with content type(s) %q", patternCSV, ct)
-	case lang != "":
-		return fmt.Errorf("no conversations matched patterns [%s] with language(s) %q", patternCSV, lang)
-	default:
-		return fmt.Errorf("no conversations matched patterns [%s]", patternCSV)
+func BuildNoMatchError(subject string, qualifiers []string) error {
+	if len(qualifiers) == 0 {
+		return fmt.Errorf("%w %s", ErrNoMatch, subject)
+	}
+	return fmt.Errorf("%w %s with %s", ErrNoMatch, subject, strings.Join(qualifiers, " and "))
+}
+
+// yieldsAnyDesired reports whether found yields any desired value, ranging over it only until one turns up.
+func yieldsAnyDesired(found iter.Seq[string], desired []string, normalizer func(string) string) bool {
+	if len(desired) == 0 {
+		return true
+	}
+	wanted := normalizedSet(desired, normalizer)
+	for value := range found {
+		if _, ok := wanted[value]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// yieldsAllDesired reports whether found yields every desired value, ranging over it only until the last one
+// turns up.
+func yieldsAllDesired(found iter.Seq[string], desired []string, normalizer func(string) string) bool {
+	if len(desired) == 0 {
+		return true
+	}
+	missing := normalizedSet(desired, normalizer)
+	for value := range found {
+		delete(missing, value)
+		if len(missing) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizedSet(values []string, normalizer func(string) string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[normalizer(value)] = struct{}{}
 	}
+	return set
 }
 
 func HasAllDesired(found map[string]struct{}, desired []string, normalizer func(string) string) bool {
@@ -0,0 +1,85 @@
+package filters
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+// TitleHitRole stands in for the role of hits in the conversation title, which belongs to no message.
+const TitleHitRole = "title"
+
+// Hit is one place a positive pattern matched: a message, or the title, and the character offset in its text.
+// Text is the whole text matched against, and Start and End the byte span of the match in it.
+type Hit struct {
+	Pattern   string
+	MessageID string
+	Role      string
+	Offset    int
+	Text      string
+	Start     int
+	End       int
+}
+
+// Hits locates every match of the positive patterns in a candidate, in the title and in the text of the
+// messages each pattern's scope covers. Whole-document patterns are located in the title and in the messages
+// on every branch; a match only inside metadata or tool payloads has no message text to point at.
+func (matcher *Matcher) Hits(candidate Candidate) []Hit {
+	var hits []Hit
+	for _, pattern := range matcher.patterns {
+		includeTitle, messages := matcher.hitTargets(pattern.scope, candidate)
+		if includeTitle {
+			hits = append(hits, matcher.locate(pattern, candidate.Conversation.Title, "", TitleHitRole)...)
+		}
+		for _, message := range messages {
+			hits = append(hits, matcher.locate(pattern, message.Text, message.ID, message.Role)...)
+		}
+	}
+	return hits
+}
+
+func (matcher *Matcher) hitTargets(scope Scope, candidate Candidate) (bool, []utils.Message) {
+	switch scope {
+	case ScopeTitle:
+		return true, nil
+	case ScopeRole:
+		var messages []utils.Message
+		for _, message := range model.ActiveBranch(candidate.Conversation) {
+			if message.Role == matcher.role && (!matcher.visibleOnly || utils.IsVisible(message)) {
+				messages = append(messages, message)
+			}
+		}
+		return false, messages
+	case ScopeVisible:
+		return true, utils.VisibleMessages(model.ActiveBranch(candidate.Conversation))
+	default:
+		return true, model.AllMessages(candidate.Conversation)
+	}
+}
+
+func (matcher *Matcher) locate(pattern compiledPattern, text string, messageID string, role string) []Hit {
+	var hits []Hit
+	for _, location := range pattern.expression.FindAllStringIndex(text, -1) {
+		hits = append(hits, Hit{
+			Pattern:   pattern.text,
+			MessageID: messageID,
+			Role:      role,
+			Offset:    utf8.RuneCountInString(text[:location[0]]),
+			Text:      text,
+			Start:     location[0],
+			End:       location[1],
+		})
+	}
+	return hits
+}
+
+// LineSpan returns the zero-based first and last line of the hit's text that hold the match, widened by
+// context lines on each side and clamped to the text.
+func (hit Hit) LineSpan(context int) (int, int) {
+	first := strings.Count(hit.Text[:hit.Start], "\n")
+	last := first + strings.Count(hit.Text[hit.Start:hit.End], "\n")
+	lineCount := strings.Count(hit.Text, "\n") + 1
+	return max(first-context, 0), min(last+context, lineCount-1)
+}
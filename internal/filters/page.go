@@ -0,0 +1,21 @@
+package filters
+
+import "fmt"
+
+// Page selects a window of the matched conversations after they are ordered by create_time.
+type Page struct {
+	Skip  int
+	Limit int
+}
+
+// Apply returns the window of matched candidates selected by the page; a zero Limit means no limit.
+func (page Page) Apply(matched []Candidate) ([]Candidate, error) {
+	if page.Skip >= len(matched) && page.Skip > 0 {
+		return nil, fmt.Errorf("--skip %d leaves nothing of the %d matched conversations", page.Skip, len(matched))
+	}
+	windowEnd := len(matched)
+	if page.Limit > 0 && page.Skip+page.Limit < windowEnd {
+		windowEnd = page.Skip + page.Limit
+	}
+	return matched[page.Skip:windowEnd], nil
+}
@@ -0,0 +1,87 @@
+package filters
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// Prefilter narrows the conversations a matcher can match, for full-text indexes. It only ever over-approximates:
+// every conversation the matcher accepts satisfies the prefilter.
+type Prefilter struct {
+	// IDs, when non-empty, are the only conversations that can match.
+	IDs []string
+	// Fragments holds, for each positive pattern, the lowercase runs of letters and digits that every match of it
+	// contains; a nil entry means the pattern cannot be narrowed. Each run lies within one word of the text.
+	Fragments [][]string
+	// MatchAny reports that one matching pattern suffices, so the conversations of every pattern are united
+	// rather than intersected.
+	MatchAny bool
+}
+
+// Prefilter derives the prefilter of the matcher's ids and positive patterns. Exclusions cannot narrow anything.
+func (matcher *Matcher) Prefilter() Prefilter {
+	prefilter := Prefilter{MatchAny: matcher.decisiveOutcome}
+	for identifier := range matcher.ids {
+		prefilter.IDs = append(prefilter.IDs, identifier)
+	}
+	for _, pattern := range matcher.patterns {
+		prefilter.Fragments = append(prefilter.Fragments, requiredFragments(pattern.expression))
+	}
+	return prefilter
+}
+
+// IsWordRune reports whether a rune belongs to an indexed word: a letter or a digit.
+func IsWordRune(current rune) bool {
+	return unicode.IsLetter(current) || unicode.IsDigit(current)
+}
+
+// requiredFragments returns the word runs of the literals every match of expression contains, lowered.
+func requiredFragments(expression *regexp.Regexp) []string {
+	parsed, parseErr := syntax.Parse(expression.String(), syntax.Perl)
+	if parseErr != nil {
+		return nil
+	}
+	var fragments []string
+	for _, literal := range requiredLiterals(parsed.Simplify()) {
+		for _, run := range strings.FieldsFunc(strings.ToLower(literal), func(current rune) bool { return !IsWordRune(current) }) {
+			fragments = append(fragments, run)
+		}
+	}
+	return fragments
+}
+
+// requiredLiterals collects literal strings that occur in every match of a regular expression: runs of adjacent
+// literals in concatenations, looking through groups and repetitions that occur at least once.
+func requiredLiterals(expression *syntax.Regexp) []string {
+	switch expression.Op {
+	case syntax.OpLiteral:
+		return []string{string(expression.Rune)}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(expression.Sub[0])
+	case syntax.OpRepeat:
+		if expression.Min >= 1 {
+			return requiredLiterals(expression.Sub[0])
+		}
+	case syntax.OpConcat:
+		var literals []string
+		var pending strings.Builder
+		for _, sub := range expression.Sub {
+			if sub.Op == syntax.OpLiteral {
+				pending.WriteString(string(sub.Rune))
+				continue
+			}
+			if pending.Len() > 0 {
+				literals = append(literals, pending.String())
+				pending.Reset()
+			}
+			literals = append(literals, requiredLiterals(sub)...)
+		}
+		if pending.Len() > 0 {
+			literals = append(literals, pending.String())
+		}
+		return literals
+	}
+	return nil
+}
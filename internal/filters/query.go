@@ -0,0 +1,301 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+// Scope names the part of a conversation a pattern is matched against.
+type Scope string
+
+const (
+	// ScopeDocument matches against the whole serialized conversation, with its \u escapes decoded so text
+	// matches alike whichever way the export's encoder wrote it.
+	ScopeDocument Scope = "document"
+	// ScopeTitle matches against the conversation title only.
+	ScopeTitle Scope = "title"
+	// ScopeRole matches against the text of messages authored by the query role.
+	ScopeRole Scope = "role"
+	// ScopeVisible matches against the title and the messages the ChatGPT UI shows.
+	ScopeVisible Scope = "visible"
+)
+
+// SearchScope selects whether hidden and internal conversation content takes part in matching.
+type SearchScope string
+
+const (
+	// SearchAll matches against everything in the conversation, including metadata and tool payloads.
+	SearchAll SearchScope = "all"
+	// SearchVisible matches only against what the ChatGPT UI displays.
+	SearchVisible SearchScope = "visible"
+)
+
+var knownSearchScopes = map[SearchScope]struct{}{
+	SearchAll:     {},
+	SearchVisible: {},
+}
+
+// MatchMode selects how multiple positive patterns combine.
+type MatchMode string
+
+const (
+	// MatchAll requires every pattern to match (AND).
+	MatchAll MatchMode = "all"
+	// MatchAny requires at least one pattern to match (OR).
+	MatchAny MatchMode = "any"
+)
+
+var matchModeDecisiveOutcomes = map[MatchMode]bool{
+	MatchAll: false,
+	MatchAny: true,
+}
+
+// KnownRoles lists the message author roles accepted by Query.Role.
+var KnownRoles = []string{"user", "assistant", "system", "tool"}
+
+var scopePrefixes = map[string]Scope{
+	"title:": ScopeTitle,
+}
+
+var scopeTexts = map[Scope]func(matcher *Matcher, candidate Candidate) []byte{
+	ScopeDocument: func(_ *Matcher, candidate Candidate) []byte {
+		return utils.UnescapeJSONUnicode(candidate.Serialized)
+	},
+	ScopeTitle: func(_ *Matcher, candidate Candidate) []byte {
+		return []byte(candidate.Conversation.Title)
+	},
+	ScopeRole: func(matcher *Matcher, candidate Candidate) []byte {
+		var texts []string
+		for _, message := range model.ActiveBranch(candidate.Conversation) {
+			if message.Role == matcher.role && (!matcher.visibleOnly || utils.IsVisible(message)) {
+				texts = append(texts, message.Text)
+			}
+		}
+		return []byte(strings.Join(texts, "\n"))
+	},
+	ScopeVisible: func(_ *Matcher, candidate Candidate) []byte {
+		texts := []string{candidate.Conversation.Title}
+		for _, message := range model.ActiveBranch(candidate.Conversation) {
+			if utils.IsVisible(message) {
+				texts = append(texts, message.Text)
+			}
+		}
+		return []byte(strings.Join(texts, "\n"))
+	},
+}
+
+// Query describes the search patterns and how they are interpreted.
+type Query struct {
+	IDs           []string
+	Patterns      []string
+	Excludes      []string
+	MatchMode     MatchMode
+	WholeWord     bool
+	CaseSensitive bool
+	TitleOnly     bool
+	Role          string
+	SearchScope   SearchScope
+	Syntax        utils.PatternSyntax
+	Semantic      SemanticQuery
+}
+
+// SemanticQuery ranks conversations by lexical similarity to a query, the words and word pairs they share,
+// instead of by patterns.
+type SemanticQuery struct {
+	Text      string
+	MinScore  float64
+	CachePath string
+}
+
+type compiledPattern struct {
+	text       string
+	scope      Scope
+	expression *regexp.Regexp
+}
+
+// Matcher evaluates compiled query patterns against candidate conversations.
+type Matcher struct {
+	ids             map[string]struct{}
+	patterns        []compiledPattern
+	exclusions      []compiledPattern
+	decisiveOutcome bool
+	visibleOnly     bool
+	role            string
+}
+
+// Compile compiles every query and exclusion pattern, resolving per-pattern scope prefixes such as title:.
+func (query Query) Compile() (*Matcher, error) {
+	if validateErr := query.Validate(); validateErr != nil {
+		return nil, validateErr
+	}
+	visibleOnly := query.effectiveSearchScope() == SearchVisible
+	defaultScope := ScopeDocument
+	switch {
+	case visibleOnly && !query.TitleOnly && query.Role == "":
+		defaultScope = ScopeVisible
+	case query.TitleOnly:
+		defaultScope = ScopeTitle
+	case query.Role != "":
+		defaultScope = ScopeRole
+	}
+	patternOptions := utils.PatternOptions{WholeWord: query.WholeWord, CaseSensitive: query.CaseSensitive, Syntax: query.Syntax}
+	patterns, patternsErr := compilePatterns(query.Patterns, defaultScope, patternOptions)
+	if patternsErr != nil {
+		return nil, patternsErr
+	}
+	exclusions, exclusionsErr := compilePatterns(query.Excludes, defaultScope, patternOptions)
+	if exclusionsErr != nil {
+		return nil, fmt.Errorf("exclude: %w", exclusionsErr)
+	}
+	ids := make(map[string]struct{}, len(query.IDs))
+	for _, identifier := range query.IDs {
+		ids[strings.TrimSpace(identifier)] = struct{}{}
+	}
+	return &Matcher{
+		ids:             ids,
+		patterns:        patterns,
+		exclusions:      exclusions,
+		decisiveOutcome: matchModeDecisiveOutcomes[query.effectiveMatchMode()],
+		visibleOnly:     visibleOnly,
+		role:            utils.ToLowerTrim(query.Role),
+	}, nil
+}
+
+func compilePatterns(patternTexts []string, defaultScope Scope, patternOptions utils.PatternOptions) ([]compiledPattern, error) {
+	compiled := make([]compiledPattern, 0, len(patternTexts))
+	for _, patternText := range patternTexts {
+		scope, body := splitScopePrefix(patternText, defaultScope)
+		expression, compileErr := utils.CompileUserPattern(body, patternOptions)
+		if compileErr != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", patternText, compileErr)
+		}
+		compiled = append(compiled, compiledPattern{text: patternText, scope: scope, expression: expression})
+	}
+	return compiled, nil
+}
+
+// Validate reports conflicting or unknown query settings.
+func (query Query) Validate() error {
+	if _, known := matchModeDecisiveOutcomes[query.effectiveMatchMode()]; !known {
+		return fmt.Errorf("unknown match mode %q (supported: %s, %s)", query.MatchMode, MatchAny, MatchAll)
+	}
+	if _, known := knownSearchScopes[query.effectiveSearchScope()]; !known {
+		return fmt.Errorf("unknown search scope %q (supported: %s, %s)", query.SearchScope, SearchVisible, SearchAll)
+	}
+	if query.Role == "" {
+		return nil
+	}
+	if query.TitleOnly {
+		return errors.New("--title-only and --role cannot be combined")
+	}
+	if !slices.Contains(KnownRoles, utils.ToLowerTrim(query.Role)) {
+		return fmt.Errorf("unknown role %q (supported: %s)", query.Role, strings.Join(KnownRoles, ", "))
+	}
+	return nil
+}
+
+// Subject describes what the query selects by, for use in no-match errors.
+func (query Query) Subject() string {
+	if len(query.IDs) > 0 {
+		return fmt.Sprintf("ids [%s]", utils.StringsJoinComma(query.IDs))
+	}
+	if query.Semantic.Text != "" && len(query.Patterns) == 0 {
+		return fmt.Sprintf("semantic query %q", query.Semantic.Text)
+	}
+	return fmt.Sprintf("patterns [%s]", utils.StringsJoinComma(query.Patterns))
+}
+
+func (query Query) effectiveSearchScope() SearchScope {
+	if query.SearchScope == "" {
+		return SearchAll
+	}
+	return SearchScope(utils.ToLowerTrim(string(query.SearchScope)))
+}
+
+func (query Query) effectiveMatchMode() MatchMode {
+	if query.MatchMode == "" {
+		return MatchAll
+	}
+	return MatchMode(utils.ToLowerTrim(string(query.MatchMode)))
+}
+
+// Matches reports whether the candidate is one of the requested ids or, without ids, whether the patterns
+// match their scopes under the match mode and no exclusion pattern does.
+func (matcher *Matcher) Matches(candidate Candidate) bool {
+	if len(matcher.ids) > 0 {
+		_, requested := matcher.ids[candidate.Conversation.ID]
+		return requested
+	}
+	scopeCache := make(map[Scope][]byte, len(scopeTexts))
+	if !matcher.matchPositive(candidate, scopeCache) {
+		return false
+	}
+	for _, exclusion := range matcher.exclusions {
+		if matcher.matchPattern(exclusion, candidate, scopeCache) {
+			return false
+		}
+	}
+	return true
+}
+
+// RulesOut reports whether the serialized conversation alone shows that Matches rejects it: when every pattern
+// must match and one matched against the whole document misses, or when one pattern suffices and all of them
+// are matched against the whole document and miss. Patterns are tried against the same unescaped text Matches
+// uses, so it never rules out a conversation Matches accepts, and callers can skip decoding what it rules out.
+func (matcher *Matcher) RulesOut(serialized []byte) bool {
+	if len(matcher.ids) > 0 || len(matcher.patterns) == 0 {
+		return false
+	}
+	var document []byte
+	for _, pattern := range matcher.patterns {
+		if pattern.scope != ScopeDocument {
+			if matcher.decisiveOutcome {
+				return false
+			}
+			continue
+		}
+		if document == nil {
+			document = utils.UnescapeJSONUnicode(serialized)
+		}
+		if pattern.expression.Match(document) == matcher.decisiveOutcome {
+			return !matcher.decisiveOutcome
+		}
+	}
+	return matcher.decisiveOutcome
+}
+
+func (matcher *Matcher) matchPositive(candidate Candidate, scopeCache map[Scope][]byte) bool {
+	if len(matcher.patterns) == 0 {
+		return true
+	}
+	for _, pattern := range matcher.patterns {
+		if matcher.matchPattern(pattern, candidate, scopeCache) == matcher.decisiveOutcome {
+			return matcher.decisiveOutcome
+		}
+	}
+	return !matcher.decisiveOutcome
+}
+
+func (matcher *Matcher) matchPattern(pattern compiledPattern, candidate Candidate, scopeCache map[Scope][]byte) bool {
+	text, cached := scopeCache[pattern.scope]
+	if !cached {
+		text = scopeTexts[pattern.scope](matcher, candidate)
+		scopeCache[pattern.scope] = text
+	}
+	return pattern.expression.Match(text)
+}
+
+func splitScopePrefix(patternText string, defaultScope Scope) (Scope, string) {
+	for prefix, scope := range scopePrefixes {
+		if strings.HasPrefix(patternText, prefix) {
+			return scope, strings.TrimPrefix(patternText, prefix)
+		}
+	}
+	return defaultScope, patternText
+}
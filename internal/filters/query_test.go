@@ -0,0 +1,52 @@
+package filters
+
+import (
+	"testing"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const (
+	asciiEscapedRecord = `{"id":"c1","title":"Caf\u00e9 notes","mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":["O\u00f9 est le caf\u00e9 ? \u003cb\u003ebold\u003c/b\u003e \ud83d\ude00"]}}}},"current_node":"m1"}`
+	utf8Record         = `{"id":"c2","title":"Café notes","mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":["Où est le café ? <b>bold</b> 😀"]}}}},"current_node":"m1"}`
+)
+
+func TestMatcherUnescapesDocument(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    Query
+		expected bool
+	}{
+		{name: "accented word", query: Query{Patterns: []string{"café"}}, expected: true},
+		{name: "accented capital", query: Query{Patterns: []string{"Où"}, CaseSensitive: true}, expected: true},
+		{name: "case folded accent", query: Query{Patterns: []string{"CAFÉ"}}, expected: true},
+		{name: "html markup", query: Query{Patterns: []string{"<b>bold</b>"}, Syntax: utils.SyntaxLiteral}, expected: true},
+		{name: "emoji", query: Query{Patterns: []string{"😀"}}, expected: true},
+		{name: "escape text itself", query: Query{Patterns: []string{"u00e9"}}, expected: false},
+		{name: "absent word", query: Query{Patterns: []string{"thé"}}, expected: false},
+		{name: "any of absent and present", query: Query{Patterns: []string{"thé", "café"}, MatchMode: MatchAny}, expected: true},
+		{name: "excluded accent", query: Query{Patterns: []string{"notes"}, Excludes: []string{"café"}}, expected: false},
+	}
+	for _, record := range []string{asciiEscapedRecord, utf8Record} {
+		conversation, decodeErr := model.Decode([]byte(record))
+		if decodeErr != nil {
+			t.Fatalf("Decode: %v", decodeErr)
+		}
+		candidate := Candidate{Conversation: conversation, Serialized: []byte(record)}
+		for _, testCase := range testCases {
+			t.Run(conversation.ID+" "+testCase.name, func(t *testing.T) {
+				matcher, compileErr := testCase.query.Compile()
+				if compileErr != nil {
+					t.Fatalf("Compile: %v", compileErr)
+				}
+				if matched := matcher.Matches(candidate); matched != testCase.expected {
+					t.Errorf("Matches = %v, want %v", matched, testCase.expected)
+				}
+				if matcher.RulesOut(candidate.Serialized) && testCase.expected {
+					t.Errorf("RulesOut ruled out a conversation Matches accepts")
+				}
+			})
+		}
+	}
+}
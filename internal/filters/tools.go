@@ -0,0 +1,147 @@
+package filters
+
+import (
+	"sort"
+	"strings"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const (
+	toolBrowsing        = "browsing"
+	toolCodeInterpreter = "code_interpreter"
+	toolDalle           = "dalle"
+	toolPlugins         = "plugins"
+	toolCanvas          = "canvas"
+	toolMemory          = "memory"
+	toolFileSearch      = "file_search"
+	namespaceSeparator  = "."
+)
+
+type toolSignature struct {
+	namespaces   []string
+	contentTypes []string
+	metadataKeys []string
+}
+
+var toolSignatures = map[string]toolSignature{
+	toolBrowsing: {
+		namespaces:   []string{"browser", "web"},
+		contentTypes: []string{"tether_browsing_display", "tether_quote"},
+		metadataKeys: []string{"citations", "search_result_groups"},
+	},
+	toolCodeInterpreter: {
+		namespaces:   []string{"python"},
+		contentTypes: []string{"execution_output"},
+		metadataKeys: []string{"aggregate_result"},
+	},
+	toolDalle:      {namespaces: []string{"dalle"}},
+	toolCanvas:     {namespaces: []string{"canmore"}},
+	toolMemory:     {namespaces: []string{"bio"}},
+	toolFileSearch: {namespaces: []string{"file_search", "myfiles_browser"}},
+}
+
+var toolAliases = map[string]string{
+	"browser":                toolBrowsing,
+	"web":                    toolBrowsing,
+	"search":                 toolBrowsing,
+	"python":                 toolCodeInterpreter,
+	"code-interpreter":       toolCodeInterpreter,
+	"advanced_data_analysis": toolCodeInterpreter,
+	"dall-e":                 toolDalle,
+	"plugin":                 toolPlugins,
+	"canmore":                toolCanvas,
+	"bio":                    toolMemory,
+}
+
+// KnownTools lists the canonical tool names accepted by --tool.
+func KnownTools() []string {
+	names := []string{toolPlugins}
+	for name := range toolSignatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NormalizeToolName canonicalizes tool names and their common aliases.
+func NormalizeToolName(name string) string {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := toolAliases[normalized]; ok {
+		return canonical
+	}
+	return normalized
+}
+
+// EnumerateTools returns the canonical names of the tools used in a conversation, detected from
+// message recipients and author names, tool content types, and tool metadata.
+func EnumerateTools(conversation model.Conversation, messages []utils.Message) map[string]struct{} {
+	found := make(map[string]struct{})
+	for _, message := range messages {
+		for _, participant := range []string{message.Recipient, message.AuthorName} {
+			if tool, ok := toolForParticipant(participant); ok {
+				found[tool] = struct{}{}
+			}
+		}
+		for tool, signature := range toolSignatures {
+			if signature.matchesContent(message) {
+				found[tool] = struct{}{}
+			}
+		}
+	}
+	if UsesDalle(messages) {
+		found[toolDalle] = struct{}{}
+	}
+	if len(conversation.PluginIDs) > 0 {
+		found[toolPlugins] = struct{}{}
+	}
+	return found
+}
+
+func toolForParticipant(participant string) (string, bool) {
+	if participant == "" || participant == "all" {
+		return "", false
+	}
+	namespace, _, qualified := strings.Cut(strings.ToLower(participant), namespaceSeparator)
+	for tool, signature := range toolSignatures {
+		for _, candidate := range signature.namespaces {
+			if namespace == candidate {
+				return tool, true
+			}
+		}
+	}
+	if qualified {
+		return toolPlugins, true
+	}
+	return "", false
+}
+
+func (signature toolSignature) matchesContent(message utils.Message) bool {
+	for _, contentType := range signature.contentTypes {
+		if message.ContentType == contentType {
+			return true
+		}
+	}
+	for _, key := range signature.metadataKeys {
+		if value, present := message.Metadata[key]; present && !isEmptyValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyValue(value any) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(typed) == 0
+	case map[string]any:
+		return len(typed) == 0
+	case string:
+		return typed == ""
+	default:
+		return false
+	}
+}
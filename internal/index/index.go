@@ -0,0 +1,409 @@
+// Package index builds and queries a persistent full-text index of ChatGPT exports, so repeated searches read
+// only the conversations that can match instead of decompressing and scanning the whole export.
+package index
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	indexVersion = 2
+	sqliteDriver = "sqlite"
+	// trigramLength is the shortest fragment the trigram tokenizer can look up.
+	trigramLength = 3
+)
+
+// indexSchema creates the tables of an index: the exports it was built from, one row per conversation holding
+// its compressed JSON, and an FTS5 trigram table of each conversation's words, keyed by the conversation's
+// ordinal, which finds the conversations holding a fragment inside one of their words without reading any
+// other words.
+var indexSchema = fmt.Sprintf(`
+PRAGMA journal_mode = OFF;
+PRAGMA synchronous = OFF;
+PRAGMA user_version = %d;
+CREATE TABLE sources (
+	position INTEGER PRIMARY KEY,
+	path     TEXT NOT NULL,
+	size     INTEGER NOT NULL,
+	mod_time INTEGER NOT NULL
+);
+CREATE TABLE conversations (
+	ordinal       INTEGER PRIMARY KEY,
+	id            TEXT NOT NULL,
+	origin_root   TEXT NOT NULL,
+	origin_member TEXT NOT NULL,
+	compressed    BLOB NOT NULL
+);
+CREATE INDEX conversations_id ON conversations (id);
+CREATE VIRTUAL TABLE words USING fts5(text, tokenize = 'trigram', content = '', contentless_delete = 1);`, indexVersion)
+
+// Source fingerprints an export the index was built from, so a replaced or modified export is noticed.
+type Source struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Index is an opened full-text index.
+type Index struct {
+	handle  *sql.DB
+	sources []Source
+}
+
+// Fingerprint describes the export files at paths as they are now.
+func Fingerprint(paths []string) ([]Source, error) {
+	sources := make([]Source, 0, len(paths))
+	for _, exportPath := range paths {
+		absolutePath, absErr := filepath.Abs(exportPath)
+		if absErr != nil {
+			return nil, fmt.Errorf("resolve %q: %w", exportPath, absErr)
+		}
+		info, statErr := os.Stat(absolutePath)
+		if statErr != nil {
+			return nil, fmt.Errorf("stat %q: %w", exportPath, statErr)
+		}
+		sources = append(sources, Source{Path: absolutePath, Size: info.Size(), ModTime: info.ModTime().UTC()})
+	}
+	return sources, nil
+}
+
+// Build indexes every conversation of the archives, opened from sources, into a new index file at indexPath.
+// A conversation present in several archives is indexed once, by its most recently updated copy. It returns the
+// number of conversations indexed.
+func Build(ctx context.Context, indexPath string, sources []Source, archives []*archive.Archive) (int, error) {
+	var indexed int
+	buildErr := utils.ReplaceFileAtomic(indexPath, func(temporaryPath string) error {
+		handle, openErr := sql.Open(sqliteDriver, temporaryPath)
+		if openErr != nil {
+			return openErr
+		}
+		defer handle.Close()
+		handle.SetMaxOpenConns(1)
+		builder, beginErr := newIndexBuilder(ctx, handle, sources)
+		if beginErr != nil {
+			return beginErr
+		}
+		defer builder.transaction.Rollback()
+		for _, source := range archives {
+			if len(archives) > 1 && !source.HasConversations() {
+				continue
+			}
+			if scanErr := source.EachConversation(ctx, builder.add); scanErr != nil {
+				return scanErr
+			}
+		}
+		if commitErr := builder.transaction.Commit(); commitErr != nil {
+			return commitErr
+		}
+		indexed = len(builder.latest) + builder.anonymous
+		return handle.Close()
+	})
+	if buildErr != nil {
+		return 0, fmt.Errorf("write index: %w", buildErr)
+	}
+	return indexed, nil
+}
+
+// indexedCopy is the copy of a conversation an index under construction holds.
+type indexedCopy struct {
+	ordinal    int64
+	updateTime time.Time
+}
+
+type indexBuilder struct {
+	ctx         context.Context
+	transaction *sql.Tx
+	nextOrdinal int64
+	latest      map[string]indexedCopy
+	// anonymous counts the conversations without an ID, which are never superseded.
+	anonymous int
+}
+
+func newIndexBuilder(ctx context.Context, handle *sql.DB, sources []Source) (*indexBuilder, error) {
+	if _, schemaErr := handle.ExecContext(ctx, indexSchema); schemaErr != nil {
+		return nil, fmt.Errorf("create index schema: %w", schemaErr)
+	}
+	transaction, beginErr := handle.BeginTx(ctx, nil)
+	if beginErr != nil {
+		return nil, beginErr
+	}
+	for position, source := range sources {
+		_, insertErr := transaction.ExecContext(ctx, `INSERT INTO sources (position, path, size, mod_time) VALUES (?, ?, ?, ?)`,
+			position, source.Path, source.Size, source.ModTime.UnixNano())
+		if insertErr != nil {
+			transaction.Rollback()
+			return nil, fmt.Errorf("store source %q: %w", source.Path, insertErr)
+		}
+	}
+	return &indexBuilder{ctx: ctx, transaction: transaction, latest: make(map[string]indexedCopy)}, nil
+}
+
+func (builder *indexBuilder) add(serialized []byte, origin archive.Origin) error {
+	conversation, decodeErr := model.Decode(serialized)
+	if decodeErr != nil {
+		return nil
+	}
+	ordinal := builder.nextOrdinal
+	builder.nextOrdinal++
+	if conversation.ID == "" {
+		builder.anonymous++
+	} else {
+		if previous, seen := builder.latest[conversation.ID]; seen {
+			if !conversation.UpdateTime.After(previous.updateTime) {
+				return nil
+			}
+			if removeErr := builder.remove(previous.ordinal); removeErr != nil {
+				return fmt.Errorf("index conversation %s: %w", conversation.ID, removeErr)
+			}
+		}
+		builder.latest[conversation.ID] = indexedCopy{ordinal: ordinal, updateTime: conversation.UpdateTime}
+	}
+
+	var compressed bytes.Buffer
+	compressor, _ := flate.NewWriter(&compressed, flate.BestSpeed)
+	if _, compressErr := compressor.Write(serialized); compressErr != nil {
+		return compressErr
+	}
+	if closeErr := compressor.Close(); closeErr != nil {
+		return closeErr
+	}
+	_, insertErr := builder.transaction.ExecContext(builder.ctx, `INSERT INTO conversations (ordinal, id, origin_root, origin_member, compressed) VALUES (?, ?, ?, ?, ?)`,
+		ordinal, conversation.ID, origin.Root, origin.Member, compressed.Bytes())
+	if insertErr != nil {
+		return fmt.Errorf("index conversation %s: %w", conversation.ID, insertErr)
+	}
+	words := slices.Collect(maps.Keys(conversationWords(conversation, serialized)))
+	if _, wordsErr := builder.transaction.ExecContext(builder.ctx, `INSERT INTO words (rowid, text) VALUES (?, ?)`, ordinal, strings.Join(words, " ")); wordsErr != nil {
+		return fmt.Errorf("index conversation %s: %w", conversation.ID, wordsErr)
+	}
+	return nil
+}
+
+// remove drops the conversation stored at ordinal, superseded by a newer copy.
+func (builder *indexBuilder) remove(ordinal int64) error {
+	if _, deleteErr := builder.transaction.ExecContext(builder.ctx, `DELETE FROM conversations WHERE ordinal = ?`, ordinal); deleteErr != nil {
+		return deleteErr
+	}
+	_, deleteErr := builder.transaction.ExecContext(builder.ctx, `DELETE FROM words WHERE rowid = ?`, ordinal)
+	return deleteErr
+}
+
+// conversationWords returns the lowercase words of everything a pattern can be matched against: the serialized
+// conversation, its title, and the text of every message on any branch.
+func conversationWords(conversation model.Conversation, serialized []byte) map[string]struct{} {
+	words := make(map[string]struct{})
+	addWords := func(text string) {
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(current rune) bool { return !filters.IsWordRune(current) }) {
+			words[word] = struct{}{}
+		}
+	}
+	addWords(string(utils.UnescapeJSONUnicode(serialized)))
+	addWords(conversation.Title)
+	for _, message := range model.AllMessages(conversation) {
+		addWords(message.Text)
+	}
+	return words
+}
+
+// Open opens the index at indexPath and reads the exports it was built from.
+func Open(indexPath string) (*Index, error) {
+	if _, statErr := os.Stat(indexPath); statErr != nil {
+		return nil, fmt.Errorf("open index: %w", statErr)
+	}
+	handle, openErr := sql.Open(sqliteDriver, indexPath)
+	if openErr != nil {
+		return nil, fmt.Errorf("open index: %w", openErr)
+	}
+	sources, readErr := readSources(handle)
+	if readErr != nil {
+		handle.Close()
+		return nil, fmt.Errorf("read index %q: %w", indexPath, readErr)
+	}
+	return &Index{handle: handle, sources: sources}, nil
+}
+
+func readSources(handle *sql.DB) ([]Source, error) {
+	var version int
+	if versionErr := handle.QueryRow(`PRAGMA user_version`).Scan(&version); versionErr != nil {
+		return nil, errors.New("not an openai_extract index")
+	}
+	if version != indexVersion {
+		return nil, fmt.Errorf("index version %d is not supported; rebuild it", version)
+	}
+	rows, queryErr := handle.Query(`SELECT path, size, mod_time FROM sources ORDER BY position`)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+	var sources []Source
+	for rows.Next() {
+		var source Source
+		var modTime int64
+		if scanErr := rows.Scan(&source.Path, &source.Size, &modTime); scanErr != nil {
+			return nil, scanErr
+		}
+		source.ModTime = time.Unix(0, modTime).UTC()
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+// Close closes the index file.
+func (index *Index) Close() error {
+	return index.handle.Close()
+}
+
+// CheckSources fails unless the index was built from exactly these exports, unchanged since.
+func (index *Index) CheckSources(sources []Source) error {
+	stale := len(sources) != len(index.sources)
+	for position := 0; !stale && position < len(sources); position++ {
+		indexed := index.sources[position]
+		stale = indexed.Path != sources[position].Path || indexed.Size != sources[position].Size || !indexed.ModTime.Equal(sources[position].ModTime)
+	}
+	if stale {
+		return errors.New("index was built from other or since modified exports; rebuild it with the index command")
+	}
+	return nil
+}
+
+// EachCandidate hands every indexed conversation the prefilter admits to visit, serialized, with its origin,
+// in the order they were indexed. A non-nil error from visit stops the iteration and is returned.
+func (index *Index) EachCandidate(prefilter filters.Prefilter, visit func(serialized []byte, origin archive.Origin) error) error {
+	admitted, candidatesErr := index.candidates(prefilter)
+	if candidatesErr != nil {
+		return fmt.Errorf("query index: %w", candidatesErr)
+	}
+	if admitted == nil {
+		rows, queryErr := index.handle.Query(`SELECT id, origin_root, origin_member, compressed FROM conversations ORDER BY ordinal`)
+		if queryErr != nil {
+			return fmt.Errorf("query index: %w", queryErr)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if visitErr := visitRow(rows, visit); visitErr != nil {
+				return visitErr
+			}
+		}
+		return rows.Err()
+	}
+	statement, prepareErr := index.handle.Prepare(`SELECT id, origin_root, origin_member, compressed FROM conversations WHERE ordinal = ?`)
+	if prepareErr != nil {
+		return fmt.Errorf("query index: %w", prepareErr)
+	}
+	defer statement.Close()
+	for _, ordinal := range slices.Sorted(maps.Keys(admitted)) {
+		row := statement.QueryRow(ordinal)
+		if visitErr := visitRow(row, visit); visitErr != nil {
+			return visitErr
+		}
+	}
+	return nil
+}
+
+// visitRow decompresses the conversation of a conversations row and hands it to visit.
+func visitRow(row interface{ Scan(...any) error }, visit func(serialized []byte, origin archive.Origin) error) error {
+	var identifier string
+	var origin archive.Origin
+	var compressed []byte
+	if scanErr := row.Scan(&identifier, &origin.Root, &origin.Member, &compressed); scanErr != nil {
+		return fmt.Errorf("query index: %w", scanErr)
+	}
+	serialized, readErr := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if readErr != nil {
+		return fmt.Errorf("read indexed conversation %q: %w", identifier, readErr)
+	}
+	return visit(serialized, origin)
+}
+
+// candidates returns the ordinals of the conversations the prefilter admits, or nil when it admits every
+// conversation.
+func (index *Index) candidates(prefilter filters.Prefilter) (map[int64]struct{}, error) {
+	if len(prefilter.IDs) > 0 {
+		admitted := make(map[int64]struct{})
+		for _, identifier := range prefilter.IDs {
+			if collectErr := index.collectOrdinals(admitted, `SELECT ordinal FROM conversations WHERE id = ?`, identifier); collectErr != nil {
+				return nil, collectErr
+			}
+		}
+		return admitted, nil
+	}
+	var combined map[int64]struct{}
+	for _, fragments := range prefilter.Fragments {
+		admitted, fragmentsErr := index.fragmentCandidates(fragments)
+		switch {
+		case fragmentsErr != nil:
+			return nil, fragmentsErr
+		case admitted == nil && prefilter.MatchAny:
+			return nil, nil
+		case admitted == nil:
+			continue
+		case combined == nil:
+			combined = admitted
+		case prefilter.MatchAny:
+			maps.Copy(combined, admitted)
+		default:
+			maps.DeleteFunc(combined, func(ordinal int64, _ struct{}) bool {
+				_, kept := admitted[ordinal]
+				return !kept
+			})
+		}
+	}
+	return combined, nil
+}
+
+// fragmentCandidates returns the ordinals of the conversations holding every fragment inside one of their
+// words, looked up through the trigram table, or nil when no fragment is long enough to narrow by. Fragments
+// shorter than a trigram are left to the matcher.
+func (index *Index) fragmentCandidates(fragments []string) (map[int64]struct{}, error) {
+	var phrases []string
+	for _, fragment := range fragments {
+		if utf8.RuneCountInString(fragment) >= trigramLength {
+			phrases = append(phrases, `"`+fragment+`"`)
+		}
+	}
+	if len(phrases) == 0 {
+		return nil, nil
+	}
+	admitted := make(map[int64]struct{})
+	if collectErr := index.collectOrdinals(admitted, `SELECT rowid FROM words WHERE words MATCH ?`, strings.Join(phrases, " AND ")); collectErr != nil {
+		return nil, collectErr
+	}
+	return admitted, nil
+}
+
+// collectOrdinals adds the ordinals the query returns for argument to admitted.
+func (index *Index) collectOrdinals(admitted map[int64]struct{}, query string, argument string) error {
+	rows, queryErr := index.handle.Query(query, argument)
+	if queryErr != nil {
+		return queryErr
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ordinal int64
+		if scanErr := rows.Scan(&ordinal); scanErr != nil {
+			return scanErr
+		}
+		admitted[ordinal] = struct{}{}
+	}
+	return rows.Err()
+}
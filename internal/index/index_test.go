@@ -0,0 +1,193 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+)
+
+// conversationRecord renders a conversation with one user message as exported.
+func conversationRecord(conversationID string, updateUnix int, text string) string {
+	return fmt.Sprintf(`{"id":%q,"title":"notes","create_time":1709301792,"update_time":%d,"mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":[%q]}}}},"current_node":"m1"}`,
+		conversationID, updateUnix, text)
+}
+
+// buildTestIndex writes each export as a conversations.json, indexes them together, and opens the index.
+func buildTestIndex(t *testing.T, exports ...[]string) (*Index, []Source, int) {
+	t.Helper()
+	folder := t.TempDir()
+	var paths []string
+	var archives []*archive.Archive
+	for position, records := range exports {
+		exportPath := filepath.Join(folder, fmt.Sprintf("export%d.json", position))
+		if writeErr := os.WriteFile(exportPath, []byte("["+strings.Join(records, ",")+"]"), 0o600); writeErr != nil {
+			t.Fatalf("WriteFile: %v", writeErr)
+		}
+		opened, openErr := archive.OpenArchive(exportPath, archive.OpenOptions{})
+		if openErr != nil {
+			t.Fatalf("OpenArchive: %v", openErr)
+		}
+		t.Cleanup(func() { opened.Close() })
+		paths = append(paths, exportPath)
+		archives = append(archives, opened)
+	}
+	sources, fingerprintErr := Fingerprint(paths)
+	if fingerprintErr != nil {
+		t.Fatalf("Fingerprint: %v", fingerprintErr)
+	}
+	indexPath := filepath.Join(folder, "search.idx")
+	indexed, buildErr := Build(context.Background(), indexPath, sources, archives)
+	if buildErr != nil {
+		t.Fatalf("Build: %v", buildErr)
+	}
+	opened, openErr := Open(indexPath)
+	if openErr != nil {
+		t.Fatalf("Open: %v", openErr)
+	}
+	t.Cleanup(func() { opened.Close() })
+	return opened, sources, indexed
+}
+
+// candidateTexts returns the message text of every conversation the prefilter admits, in index order.
+func candidateTexts(t *testing.T, opened *Index, prefilter filters.Prefilter) []string {
+	t.Helper()
+	var texts []string
+	eachErr := opened.EachCandidate(prefilter, func(serialized []byte, origin archive.Origin) error {
+		conversation, decodeErr := model.Decode(serialized)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		for _, message := range model.AllMessages(conversation) {
+			texts = append(texts, message.Text)
+		}
+		return nil
+	})
+	if eachErr != nil {
+		t.Fatalf("EachCandidate: %v", eachErr)
+	}
+	return texts
+}
+
+func TestEachCandidate(t *testing.T) {
+	opened, _, indexed := buildTestIndex(t, []string{
+		conversationRecord("c1", 100, "terraform plan for the module"),
+		conversationRecord("c2", 100, "ansible playbook for the módulo"),
+		conversationRecord("c3", 100, "kubernetes manifests"),
+	})
+	if indexed != 3 {
+		t.Fatalf("indexed = %d, want 3", indexed)
+	}
+	everything := []string{"terraform plan for the module", "ansible playbook for the módulo", "kubernetes manifests"}
+	testCases := []struct {
+		name      string
+		prefilter filters.Prefilter
+		expected  []string
+	}{
+		{name: "no narrowing", prefilter: filters.Prefilter{}, expected: everything},
+		{name: "fragment inside a word", prefilter: filters.Prefilter{Fragments: [][]string{{"erraf"}}}, expected: everything[:1]},
+		{name: "every fragment of a pattern", prefilter: filters.Prefilter{Fragments: [][]string{{"the", "modul"}}}, expected: everything[:1]},
+		{name: "non-ascii fragment", prefilter: filters.Prefilter{Fragments: [][]string{{"ódul"}}}, expected: everything[1:2]},
+		{name: "fragments shorter than a trigram are left to the matcher", prefilter: filters.Prefilter{Fragments: [][]string{{"an"}}}, expected: everything},
+		{name: "short fragments beside a long one", prefilter: filters.Prefilter{Fragments: [][]string{{"an", "playbook"}}}, expected: everything[1:2]},
+		{name: "unnarrowed pattern", prefilter: filters.Prefilter{Fragments: [][]string{nil, {"manifest"}}}, expected: everything[2:]},
+		{name: "all patterns", prefilter: filters.Prefilter{Fragments: [][]string{{"for"}, {"ansible"}}}, expected: everything[1:2]},
+		{name: "any pattern", prefilter: filters.Prefilter{Fragments: [][]string{{"terraform"}, {"kubernetes"}}, MatchAny: true}, expected: []string{everything[0], everything[2]}},
+		{name: "any pattern with an unnarrowed one", prefilter: filters.Prefilter{Fragments: [][]string{{"terraform"}, nil}, MatchAny: true}, expected: everything},
+		{name: "missing word", prefilter: filters.Prefilter{Fragments: [][]string{{"helm"}}}},
+		{name: "ids", prefilter: filters.Prefilter{IDs: []string{"c3", "c1", "missing"}}, expected: []string{everything[0], everything[2]}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			texts := candidateTexts(t, opened, testCase.prefilter)
+			if !slices.Equal(texts, testCase.expected) {
+				t.Errorf("candidates = %q, want %q", texts, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestBuildKeepsNewestCopy(t *testing.T) {
+	opened, _, indexed := buildTestIndex(t,
+		[]string{conversationRecord("c1", 100, "first draft"), conversationRecord("", 100, "anonymous note")},
+		[]string{conversationRecord("c1", 200, "second revision"), conversationRecord("", 100, "another note")},
+	)
+	if indexed != 3 {
+		t.Fatalf("indexed = %d, want 3", indexed)
+	}
+	testCases := []struct {
+		name      string
+		prefilter filters.Prefilter
+		expected  []string
+	}{
+		{name: "superseded copy is gone", prefilter: filters.Prefilter{Fragments: [][]string{{"draft"}}}},
+		{name: "newest copy", prefilter: filters.Prefilter{IDs: []string{"c1"}}, expected: []string{"second revision"}},
+		{name: "every conversation", prefilter: filters.Prefilter{}, expected: []string{"anonymous note", "second revision", "another note"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			texts := candidateTexts(t, opened, testCase.prefilter)
+			if !slices.Equal(texts, testCase.expected) {
+				t.Errorf("candidates = %q, want %q", texts, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCheckSources(t *testing.T) {
+	opened, sources, _ := buildTestIndex(t, []string{conversationRecord("c1", 100, "hello")})
+	modified := sources[0]
+	modified.Size++
+	testCases := []struct {
+		name      string
+		sources   []Source
+		expectErr bool
+	}{
+		{name: "unchanged", sources: sources},
+		{name: "modified", sources: []Source{modified}, expectErr: true},
+		{name: "other exports", sources: append(slices.Clone(sources), modified), expectErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if checkErr := opened.CheckSources(testCase.sources); (checkErr != nil) != testCase.expectErr {
+				t.Errorf("CheckSources error = %v, want error %v", checkErr, testCase.expectErr)
+			}
+		})
+	}
+}
+
+func TestOpenRejectsOtherFiles(t *testing.T) {
+	folder := t.TempDir()
+	testCases := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "previous format", content: []byte("OAEIDX1\n")},
+		{name: "json", content: []byte(`{"id":"c1"}`)},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			indexPath := filepath.Join(folder, testCase.name)
+			if writeErr := os.WriteFile(indexPath, testCase.content, 0o600); writeErr != nil {
+				t.Fatalf("WriteFile: %v", writeErr)
+			}
+			if opened, openErr := Open(indexPath); openErr == nil {
+				opened.Close()
+				t.Fatal("Open accepted a file that is not an index")
+			}
+		})
+	}
+	if _, openErr := Open(filepath.Join(folder, "missing")); openErr == nil {
+		t.Fatal("Open accepted a missing index")
+	}
+	if _, statErr := os.Stat(filepath.Join(folder, "missing")); statErr == nil {
+		t.Fatal("Open created the missing index")
+	}
+}
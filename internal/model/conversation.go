@@ -0,0 +1,371 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"openai_extract/internal/utils"
+)
+
+const partSeparator = "\n"
+
+var createTimeFields = []string{"create_time", "createTime", "create-time", "start_time"}
+
+// Conversation is one conversation of an export. Decoding is tolerant: unknown fields are ignored and a field
+// whose JSON type does not match is left at its zero value instead of failing the whole conversation.
+type Conversation struct {
+	ID          string
+	Title       string
+	CreateTime  time.Time
+	UpdateTime  time.Time
+	Mapping     map[string]MessageNode
+	CurrentNode string
+	IsArchived  bool
+	IsStarred   bool
+	// GizmoID is the custom GPT or Project the conversation was held with; TemplateID is the older field for it.
+	GizmoID    string
+	TemplateID string
+	GizmoName  string
+	// GizmoInstructions is the configured prompt of the custom GPT, when the export embeds its configuration.
+	GizmoInstructions string
+	ProjectID         string
+	ProjectName       string
+	Voice             string
+	PluginIDs         []string
+	// DefaultModel is the model slug the conversation was started with.
+	DefaultModel string
+}
+
+// In returns a copy of the conversation with its own and its messages' timestamps expressed in location, so
+// everything rendered from it shows local times of that zone. The instants are unchanged.
+func (conversation Conversation) In(location *time.Location) Conversation {
+	localized := conversation
+	localized.CreateTime = conversation.CreateTime.In(location)
+	localized.UpdateTime = conversation.UpdateTime.In(location)
+	localized.Mapping = make(map[string]MessageNode, len(conversation.Mapping))
+	for key, node := range conversation.Mapping {
+		if node.Message != nil {
+			message := *node.Message
+			message.CreateTime = message.CreateTime.In(location)
+			node.Message = &message
+		}
+		localized.Mapping[key] = node
+	}
+	return localized
+}
+
+// MessageNode is one entry of a conversation mapping: a message slot linked to its parent and children. Edited
+// prompts and regenerated answers show up as siblings, so the mapping is a tree rather than a list.
+type MessageNode struct {
+	ID       string
+	Parent   string
+	Children []string
+	Message  *Message
+}
+
+// Message is one message as exported, before it is flattened for matching and rendering.
+type Message struct {
+	ID         string
+	Author     Author
+	Recipient  string
+	CreateTime time.Time
+	Content    Content
+	Metadata   map[string]any
+}
+
+// Author identifies who wrote a message: a role and, for tool messages, the tool name.
+type Author struct {
+	Role     string
+	Name     string
+	Metadata map[string]any
+}
+
+// Content is the body of a message. Text holds the single text of code and execution output messages,
+// Parts the pieces of text and multimodal ones, and Thoughts the reasoning steps of "thoughts" messages.
+// UserProfile and UserInstructions are the custom instructions carried by "user_editable_context" messages.
+type Content struct {
+	ContentType      string
+	Text             string
+	Language         string
+	Parts            []Part
+	Thoughts         []Thought
+	UserProfile      string
+	UserInstructions string
+}
+
+// Thought is one reasoning step of a reasoning model, as summarized in the export.
+type Thought struct {
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+}
+
+// Part is one piece of message content: plain text, or an object such as an image or audio asset pointer.
+type Part struct {
+	Text   string
+	Object map[string]any
+}
+
+type rawObject map[string]json.RawMessage
+
+func decodeObject(data []byte) rawObject {
+	var object rawObject
+	if json.Unmarshal(data, &object) != nil {
+		return nil
+	}
+	return object
+}
+
+// field decodes one field into target, leaving target untouched when the field is absent or mistyped.
+func (object rawObject) field(key string, target any) {
+	if value, present := object[key]; present {
+		_ = json.Unmarshal(value, target)
+	}
+}
+
+func (object rawObject) timestamp(keys ...string) time.Time {
+	for _, key := range keys {
+		var value any
+		object.field(key, &value)
+		if parsed, ok := utils.ParseTimestamp(value); ok {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// Decode decodes one serialized conversation. Only data that is not a JSON object is an error.
+func Decode(data []byte) (Conversation, error) {
+	var conversation Conversation
+	if err := json.Unmarshal(data, &conversation); err != nil {
+		return Conversation{}, err
+	}
+	return conversation, nil
+}
+
+// DecodeIdentity decodes only the id and update time of one serialized conversation, which is enough to tell
+// its copies apart, without building its message tree. Only data that is not a JSON object is an error.
+func DecodeIdentity(data []byte) (Conversation, error) {
+	var identity struct {
+		ConversationID json.RawMessage `json:"conversation_id"`
+		ID             json.RawMessage `json:"id"`
+		UpdateTime     json.RawMessage `json:"update_time"`
+	}
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return Conversation{}, err
+	}
+	object := rawObject{"conversation_id": identity.ConversationID, "id": identity.ID, "update_time": identity.UpdateTime}
+	var conversation Conversation
+	object.field("conversation_id", &conversation.ID)
+	if conversation.ID == "" {
+		object.field("id", &conversation.ID)
+	}
+	conversation.UpdateTime = object.timestamp("update_time")
+	return conversation, nil
+}
+
+// UnmarshalJSON decodes a conversation tolerantly. The id prefers conversation_id over id, and a conversation
+// without any creation timestamp is dated at decode time so it still sorts and gets an output folder.
+func (conversation *Conversation) UnmarshalJSON(data []byte) error {
+	var object rawObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	object.field("conversation_id", &conversation.ID)
+	if conversation.ID == "" {
+		object.field("id", &conversation.ID)
+	}
+	object.field("title", &conversation.Title)
+	conversation.CreateTime = object.timestamp(createTimeFields...)
+	if conversation.CreateTime.IsZero() {
+		conversation.CreateTime = time.Now()
+	}
+	conversation.UpdateTime = object.timestamp("update_time")
+	object.field("current_node", &conversation.CurrentNode)
+	object.field("is_archived", &conversation.IsArchived)
+	object.field("is_starred", &conversation.IsStarred)
+	object.field("gizmo_id", &conversation.GizmoID)
+	object.field("conversation_template_id", &conversation.TemplateID)
+	object.field("voice", &conversation.Voice)
+	object.field("default_model_slug", &conversation.DefaultModel)
+	object.field("plugin_ids", &conversation.PluginIDs)
+	object.field("project_id", &conversation.ProjectID)
+	conversation.GizmoName = decodeGizmoName(object)
+	conversation.GizmoInstructions = decodeGizmoInstructions(object)
+	conversation.ProjectName = decodeProjectName(object)
+
+	var mapping map[string]json.RawMessage
+	object.field("mapping", &mapping)
+	conversation.Mapping = make(map[string]MessageNode, len(mapping))
+	for key, rawNode := range mapping {
+		var node MessageNode
+		_ = json.Unmarshal(rawNode, &node)
+		node.ID = key
+		conversation.Mapping[key] = node
+	}
+	return nil
+}
+
+func decodeGizmoName(object rawObject) string {
+	var name string
+	if object.field("gizmo_name", &name); name != "" {
+		return name
+	}
+	var gizmo struct {
+		Display struct {
+			Name string `json:"name"`
+		} `json:"display"`
+	}
+	object.field("gizmo", &gizmo)
+	return gizmo.Display.Name
+}
+
+// decodeGizmoInstructions reads the instructions of an embedded gizmo, either at its top level or under the
+// nested "gizmo" object the gizmo API wraps them in.
+func decodeGizmoInstructions(object rawObject) string {
+	gizmo := decodeObject(object["gizmo"])
+	var instructions string
+	if gizmo.field("instructions", &instructions); instructions != "" {
+		return instructions
+	}
+	decodeObject(gizmo["gizmo"]).field("instructions", &instructions)
+	return instructions
+}
+
+func decodeProjectName(object rawObject) string {
+	var name string
+	if object.field("project_name", &name); name != "" {
+		return name
+	}
+	project := decodeObject(object["project"])
+	for _, key := range []string{"name", "title"} {
+		if project.field(key, &name); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// UnmarshalJSON decodes a mapping node tolerantly; a null or malformed message leaves Message nil.
+func (node *MessageNode) UnmarshalJSON(data []byte) error {
+	object := decodeObject(data)
+	object.field("id", &node.ID)
+	object.field("parent", &node.Parent)
+	var children []any
+	object.field("children", &children)
+	for _, rawChild := range children {
+		if child, ok := rawChild.(string); ok {
+			node.Children = append(node.Children, child)
+		}
+	}
+	if rawMessage := bytes.TrimSpace(object["message"]); len(rawMessage) > 0 && rawMessage[0] == '{' {
+		node.Message = &Message{}
+		_ = json.Unmarshal(rawMessage, node.Message)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a message tolerantly.
+func (message *Message) UnmarshalJSON(data []byte) error {
+	object := decodeObject(data)
+	object.field("id", &message.ID)
+	object.field("author", &message.Author)
+	object.field("recipient", &message.Recipient)
+	message.CreateTime = object.timestamp("create_time")
+	object.field("content", &message.Content)
+	object.field("metadata", &message.Metadata)
+	return nil
+}
+
+// UnmarshalJSON decodes an author tolerantly.
+func (author *Author) UnmarshalJSON(data []byte) error {
+	object := decodeObject(data)
+	object.field("role", &author.Role)
+	object.field("name", &author.Name)
+	object.field("metadata", &author.Metadata)
+	return nil
+}
+
+// UnmarshalJSON decodes message content tolerantly, keeping every part that is a string or an object.
+func (content *Content) UnmarshalJSON(data []byte) error {
+	object := decodeObject(data)
+	object.field("content_type", &content.ContentType)
+	object.field("text", &content.Text)
+	object.field("language", &content.Language)
+	object.field("thoughts", &content.Thoughts)
+	object.field("user_profile", &content.UserProfile)
+	object.field("user_instructions", &content.UserInstructions)
+	var parts []any
+	object.field("parts", &parts)
+	for _, rawPart := range parts {
+		switch typed := rawPart.(type) {
+		case string:
+			content.Parts = append(content.Parts, Part{Text: typed})
+		case map[string]any:
+			text, _ := typed["text"].(string)
+			content.Parts = append(content.Parts, Part{Text: text, Object: typed})
+		}
+	}
+	return nil
+}
+
+// Assets returns the object parts of the content, such as image and audio asset pointers.
+func (content Content) Assets() []map[string]any {
+	var assets []map[string]any
+	for _, part := range content.Parts {
+		if part.Object != nil {
+			assets = append(assets, part.Object)
+		}
+	}
+	return assets
+}
+
+// PlainText joins the text and every non-empty text part of the content.
+func (content Content) PlainText() string {
+	var pieces []string
+	if content.Text != "" {
+		pieces = append(pieces, content.Text)
+	}
+	for _, part := range content.Parts {
+		if part.Text != "" {
+			pieces = append(pieces, part.Text)
+		}
+	}
+	return strings.Join(pieces, partSeparator)
+}
+
+// Reasoning joins the reasoning steps of the content into Markdown, each under its bold summary.
+func (content Content) Reasoning() string {
+	var pieces []string
+	for _, thought := range content.Thoughts {
+		var step []string
+		if summary := strings.TrimSpace(thought.Summary); summary != "" {
+			step = append(step, "**"+summary+"**")
+		}
+		if body := strings.TrimSpace(thought.Content); body != "" {
+			step = append(step, body)
+		}
+		if len(step) > 0 {
+			pieces = append(pieces, strings.Join(step, "\n\n"))
+		}
+	}
+	return strings.Join(pieces, "\n\n")
+}
+
+// Flatten returns the flattened view of the message used for matching and rendering.
+func (message Message) Flatten() utils.Message {
+	return utils.Message{
+		ID:          message.ID,
+		Role:        message.Author.Role,
+		AuthorName:  message.Author.Name,
+		Recipient:   message.Recipient,
+		ContentType: message.Content.ContentType,
+		Language:    message.Content.Language,
+		Text:        message.Content.PlainText(),
+		Reasoning:   message.Content.Reasoning(),
+		CreateTime:  message.CreateTime,
+		Metadata:    message.Metadata,
+		Assets:      message.Content.Assets(),
+	}
+}
@@ -0,0 +1,59 @@
+package model
+
+import "strings"
+
+const (
+	systemRole              = "system"
+	userContextMetadata     = "user_context_message_data"
+	aboutUserMetadataKey    = "about_user_message"
+	aboutModelMetadataKey   = "about_model_message"
+	userEditableContextType = "user_editable_context"
+)
+
+// Instructions is the context the assistant had besides the dialogue: the system prompt, the user's custom
+// instructions, and the configured prompt of the custom GPT. Empty fields are omitted when encoded.
+type Instructions struct {
+	SystemPrompt      string `json:"system_prompt,omitempty"`
+	AboutUser         string `json:"about_user,omitempty"`
+	AboutModel        string `json:"about_model,omitempty"`
+	GizmoInstructions string `json:"gizmo_instructions,omitempty"`
+}
+
+// IsEmpty reports whether no instructions were found.
+func (instructions Instructions) IsEmpty() bool {
+	return instructions == Instructions{}
+}
+
+// EffectiveInstructions collects the instructions in force on the conversation's active branch. Custom
+// instructions come from "user_editable_context" messages or, in older exports, from the
+// user_context_message_data metadata of the hidden system message; later messages override earlier ones.
+func EffectiveInstructions(conversation Conversation) Instructions {
+	instructions := Instructions{GizmoInstructions: strings.TrimSpace(conversation.GizmoInstructions)}
+	tree := NewTree(conversation)
+	for _, key := range tree.PathTo(tree.ActiveLeaf()) {
+		message := tree.Nodes[key].Message
+		if message == nil {
+			continue
+		}
+		if message.Content.ContentType == userEditableContextType {
+			setIfPresent(&instructions.AboutUser, message.Content.UserProfile)
+			setIfPresent(&instructions.AboutModel, message.Content.UserInstructions)
+		}
+		if userContext, ok := message.Metadata[userContextMetadata].(map[string]any); ok {
+			aboutUser, _ := userContext[aboutUserMetadataKey].(string)
+			aboutModel, _ := userContext[aboutModelMetadataKey].(string)
+			setIfPresent(&instructions.AboutUser, aboutUser)
+			setIfPresent(&instructions.AboutModel, aboutModel)
+		}
+		if message.Author.Role == systemRole {
+			setIfPresent(&instructions.SystemPrompt, message.Content.PlainText())
+		}
+	}
+	return instructions
+}
+
+func setIfPresent(target *string, value string) {
+	if trimmed := strings.TrimSpace(value); trimmed != "" {
+		*target = trimmed
+	}
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SchemaVersion identifies the generation of the ChatGPT export format a conversation was written in. Exports
+// carry no version number, so it is inferred from the fields each generation introduced.
+type SchemaVersion string
+
+const (
+	// SchemaUnknown is a conversation without a "mapping" message tree, which no known generation omits.
+	SchemaUnknown SchemaVersion = "unknown"
+	// SchemaV1 conversations hold a "mapping" tree and identify themselves by "id" alone.
+	SchemaV1 SchemaVersion = "1"
+	// SchemaV2 conversations add "conversation_id".
+	SchemaV2 SchemaVersion = "2"
+	// SchemaV3 conversations add "default_model_slug", the model the conversation was started with.
+	SchemaV3 SchemaVersion = "3"
+)
+
+// KnownSchemaVersions lists the schema versions DetectSchema reports, oldest first.
+var KnownSchemaVersions = []SchemaVersion{SchemaV1, SchemaV2, SchemaV3}
+
+// DetectSchema infers the schema version of one serialized conversation.
+func DetectSchema(serialized []byte) SchemaVersion {
+	object := decodeObject(serialized)
+	if _, present := object["mapping"]; !present {
+		return SchemaUnknown
+	}
+	if _, present := object["conversation_id"]; !present {
+		return SchemaV1
+	}
+	if _, present := object["default_model_slug"]; !present {
+		return SchemaV2
+	}
+	return SchemaV3
+}
+
+// CheckStructure returns the structural problems of a decoded conversation that tolerant decoding let
+// through: an empty message tree, a current node missing from it, and nodes linked to missing nodes.
+func CheckStructure(conversation Conversation) []string {
+	if len(conversation.Mapping) == 0 {
+		return []string{"no messages in mapping"}
+	}
+	var problems []string
+	if conversation.CurrentNode != "" {
+		if _, found := conversation.Mapping[conversation.CurrentNode]; !found {
+			problems = append(problems, fmt.Sprintf("current_node %q is not in mapping", conversation.CurrentNode))
+		}
+	}
+	keys := make([]string, 0, len(conversation.Mapping))
+	for key := range conversation.Mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		node := conversation.Mapping[key]
+		if node.Parent != "" {
+			if _, found := conversation.Mapping[node.Parent]; !found {
+				problems = append(problems, fmt.Sprintf("node %q has missing parent %q", key, node.Parent))
+			}
+		}
+		for _, child := range node.Children {
+			if _, found := conversation.Mapping[child]; !found {
+				problems = append(problems, fmt.Sprintf("node %q has missing child %q", key, child))
+			}
+		}
+	}
+	return problems
+}
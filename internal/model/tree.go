@@ -0,0 +1,221 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"openai_extract/internal/utils"
+)
+
+// BranchMode selects which branches of a conversation's mapping tree are rendered.
+type BranchMode string
+
+const (
+	// BranchCurrent renders only the active branch, as the ChatGPT UI shows it.
+	BranchCurrent BranchMode = "current"
+	// BranchAll also renders abandoned regenerations and edited prompts, each message labelled with its branch.
+	BranchAll BranchMode = "all"
+)
+
+// KnownBranchModes lists the supported branch modes.
+var KnownBranchModes = []BranchMode{BranchCurrent, BranchAll}
+
+const alternativeBranchLabel = "alternative %d"
+
+// Tree indexes the mapping of one conversation.
+type Tree struct {
+	Nodes       map[string]MessageNode
+	CurrentNode string
+}
+
+// NewTree indexes the mapping of a conversation.
+func NewTree(conversation Conversation) Tree {
+	return Tree{Nodes: conversation.Mapping, CurrentNode: conversation.CurrentNode}
+}
+
+// ActiveLeaf returns the node the conversation currently ends at: current_node when it exists, otherwise the
+// leaf reached by always following the newest child from the root.
+func (tree Tree) ActiveLeaf() string {
+	if _, known := tree.Nodes[tree.CurrentNode]; known {
+		return tree.CurrentNode
+	}
+	roots := tree.Roots()
+	if len(roots) == 0 {
+		return ""
+	}
+	leaf := roots[len(roots)-1]
+	visited := map[string]struct{}{leaf: {}}
+	for {
+		children := tree.knownChildren(leaf)
+		if len(children) == 0 {
+			return leaf
+		}
+		next := children[len(children)-1]
+		if _, seen := visited[next]; seen {
+			return leaf
+		}
+		visited[next] = struct{}{}
+		leaf = next
+	}
+}
+
+// Roots returns the nodes without a known parent, in id order.
+func (tree Tree) Roots() []string {
+	var roots []string
+	for key, node := range tree.Nodes {
+		if _, hasParent := tree.Nodes[node.Parent]; !hasParent {
+			roots = append(roots, key)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// PathTo returns the node ids from the root down to leaf, stopping at a cycle or an unknown parent.
+func (tree Tree) PathTo(leaf string) []string {
+	var reversed []string
+	visited := make(map[string]struct{})
+	for current := leaf; current != ""; current = tree.Nodes[current].Parent {
+		if _, known := tree.Nodes[current]; !known {
+			break
+		}
+		if _, seen := visited[current]; seen {
+			break
+		}
+		visited[current] = struct{}{}
+		reversed = append(reversed, current)
+	}
+	path := make([]string, len(reversed))
+	for index, key := range reversed {
+		path[len(reversed)-1-index] = key
+	}
+	return path
+}
+
+func (tree Tree) knownChildren(key string) []string {
+	var children []string
+	for _, child := range tree.Nodes[key].Children {
+		if _, known := tree.Nodes[child]; known {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// ActiveBranch returns the messages on the conversation's active branch, from the first message to the one
+// current_node points at, as the ChatGPT UI shows them. Abandoned regenerations and edits are left out.
+func ActiveBranch(conversation Conversation) []utils.Message {
+	tree := NewTree(conversation)
+	return tree.messagesOn(tree.PathTo(tree.ActiveLeaf()))
+}
+
+// Messages returns the messages to render for mode. With BranchAll, each fork lists its abandoned alternatives,
+// labelled "alternative N" in Message.Branch, before the active continuation, which keeps an empty label.
+func Messages(conversation Conversation, mode BranchMode) []utils.Message {
+	if mode != BranchAll {
+		return ActiveBranch(conversation)
+	}
+	tree := NewTree(conversation)
+	active := make(map[string]struct{})
+	for _, key := range tree.PathTo(tree.ActiveLeaf()) {
+		active[key] = struct{}{}
+	}
+	walker := branchWalker{tree: tree, active: active, visited: make(map[string]struct{})}
+	for _, root := range tree.Roots() {
+		label := ""
+		if _, onActive := active[root]; !onActive {
+			label = walker.nextLabel()
+		}
+		walker.walk(root, label)
+	}
+	return walker.messages
+}
+
+type branchWalker struct {
+	tree         Tree
+	active       map[string]struct{}
+	visited      map[string]struct{}
+	alternatives int
+	messages     []utils.Message
+}
+
+func (walker *branchWalker) nextLabel() string {
+	walker.alternatives++
+	return fmt.Sprintf(alternativeBranchLabel, walker.alternatives)
+}
+
+func (walker *branchWalker) walk(key string, label string) {
+	for key != "" {
+		if _, seen := walker.visited[key]; seen {
+			return
+		}
+		walker.visited[key] = struct{}{}
+		if rawMessage := walker.tree.Nodes[key].Message; rawMessage != nil {
+			message := rawMessage.Flatten()
+			message.Branch = label
+			walker.messages = append(walker.messages, message)
+		}
+		children := walker.tree.knownChildren(key)
+		continuation := ""
+		if len(children) > 0 {
+			continuation = children[len(children)-1]
+		}
+		for _, child := range children {
+			if _, onActive := walker.active[child]; onActive {
+				continuation = child
+			}
+		}
+		for _, child := range children {
+			if child == continuation {
+				continue
+			}
+			childLabel := walker.nextLabel()
+			walker.walk(child, childLabel)
+		}
+		key = continuation
+	}
+}
+
+// AllMessages returns every message in the mapping, on any branch, ordered by creation time.
+func AllMessages(conversation Conversation) []utils.Message {
+	tree := NewTree(conversation)
+	keys := make([]string, 0, len(tree.Nodes))
+	for key := range tree.Nodes {
+		keys = append(keys, key)
+	}
+	messages := tree.messagesOn(keys)
+	sort.SliceStable(messages, func(left, right int) bool {
+		if messages[left].CreateTime.Equal(messages[right].CreateTime) {
+			return messages[left].ID < messages[right].ID
+		}
+		return messages[left].CreateTime.Before(messages[right].CreateTime)
+	})
+	return messages
+}
+
+func (tree Tree) messagesOn(keys []string) []utils.Message {
+	messages := make([]utils.Message, 0, len(keys))
+	for _, key := range keys {
+		if rawMessage := tree.Nodes[key].Message; rawMessage != nil {
+			messages = append(messages, rawMessage.Flatten())
+		}
+	}
+	return messages
+}
+
+const modelSlugMetadata = "model_slug"
+
+// Model returns the model the conversation was held with: the default model slug, else the model slug of the
+// last message on the active branch that records one.
+func Model(conversation Conversation) string {
+	if conversation.DefaultModel != "" {
+		return conversation.DefaultModel
+	}
+	messages := ActiveBranch(conversation)
+	for index := len(messages) - 1; index >= 0; index-- {
+		if slug, _ := messages[index].Metadata[modelSlugMetadata].(string); slug != "" {
+			return slug
+		}
+	}
+	return ""
+}
@@ -0,0 +1,302 @@
+// Package redact masks personal data and secrets in export text, so exports can be shared.
+package redact
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+
+	"openai_extract/internal/utils"
+)
+
+// Kind names a built-in class of sensitive text.
+type Kind string
+
+const (
+	// KindSecret is an API key or access token with a recognizable prefix (OpenAI, GitHub, AWS, Slack, Google).
+	KindSecret Kind = "secret"
+	// KindEmail is an email address.
+	KindEmail Kind = "email"
+	// KindIP is an IPv4 address.
+	KindIP Kind = "ip"
+	// KindCard is a payment card number passing the Luhn check.
+	KindCard Kind = "card"
+	// KindPhone is a phone number written with separators, or in international form such as +14155552671.
+	KindPhone Kind = "phone"
+)
+
+// KnownKinds lists the built-in kinds in the order they are masked: secrets first, as they may contain
+// digits and dots the later kinds would claim, and IP addresses before phone numbers for the same reason.
+var KnownKinds = []Kind{KindSecret, KindEmail, KindIP, KindCard, KindPhone}
+
+// customLabel replaces matches of user-supplied patterns.
+const customLabel = "[REDACTED]"
+
+var kindExpressions = map[Kind]*regexp.Regexp{
+	KindSecret: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|(?:ghp|gho|ghu|ghs|github_pat)_[A-Za-z0-9_]{20,}|AKIA[0-9A-Z]{16}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`),
+	KindEmail:  regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	KindIP:     regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+	KindCard:   regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	KindPhone:  regexp.MustCompile(`\+\d{8,15}\b|(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)\s?|\b\d{2,4}[\s.-])\d{3,4}[\s.-]\d{3,4}\b`),
+}
+
+var kindLabels = map[Kind]string{
+	KindSecret: "[SECRET]",
+	KindEmail:  "[EMAIL]",
+	KindIP:     "[IP]",
+	KindCard:   "[CARD]",
+	KindPhone:  "[PHONE]",
+}
+
+type rule struct {
+	expression *regexp.Regexp
+	label      string
+	accept     func(match string) bool
+}
+
+// Redactor replaces sensitive text with a label naming what was removed, such as "[EMAIL]", and counts the
+// replacements. It is not safe for concurrent use.
+type Redactor struct {
+	rules    []rule
+	Replaced int
+}
+
+// New builds a redactor masking the given built-in kinds, in the order of KnownKinds, followed by the custom
+// patterns, which are compiled as search patterns are.
+func New(kinds []Kind, custom []string) (*Redactor, error) {
+	redactor := &Redactor{}
+	for _, kind := range KnownKinds {
+		if !slices.Contains(kinds, kind) {
+			continue
+		}
+		current := rule{expression: kindExpressions[kind], label: kindLabels[kind]}
+		if kind == KindCard {
+			current.accept = passesLuhn
+		}
+		redactor.rules = append(redactor.rules, current)
+	}
+	for _, kind := range kinds {
+		if _, known := kindExpressions[kind]; !known {
+			return nil, fmt.Errorf("unknown redaction kind %q", kind)
+		}
+	}
+	for _, pattern := range custom {
+		expression, compileErr := utils.CompileUserPattern(pattern, utils.PatternOptions{})
+		if compileErr != nil {
+			return nil, fmt.Errorf("invalid mask pattern %q: %w", pattern, compileErr)
+		}
+		redactor.rules = append(redactor.rules, rule{expression: expression, label: customLabel})
+	}
+	return redactor, nil
+}
+
+// Text returns text with every sensitive match replaced by its label.
+func (redactor *Redactor) Text(text string) string {
+	for _, current := range redactor.rules {
+		text = current.expression.ReplaceAllStringFunc(text, func(match string) string {
+			if current.accept != nil && !current.accept(match) {
+				return match
+			}
+			redactor.Replaced++
+			return current.label
+		})
+	}
+	return text
+}
+
+// CopyText streams source to destination line by line, masking each line.
+func (redactor *Redactor) CopyText(destination io.Writer, source io.Reader) error {
+	reader := bufio.NewReader(source)
+	writer := bufio.NewWriter(destination)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if _, writeErr := writer.WriteString(redactor.Text(line)); writeErr != nil {
+			return writeErr
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return writer.Flush()
+}
+
+// visibleFields are the object fields whose string values, and the strings of the arrays they hold, are text
+// a person wrote or reads: message parts and text, titles, tool output, custom instructions, account details,
+// and notebook cells. Everything else, object keys and ids such as id, *_id, parent, children, and
+// current_node above all, is copied unchanged, so the conversation tree and the references between files
+// survive redaction.
+var visibleFields = map[string]struct{}{
+	"parts": {}, "text": {}, "title": {}, "result": {}, "summary": {}, "snippet": {}, "content": {}, "url": {},
+	"name": {}, "code": {}, "final_expression_output": {}, "user_profile": {}, "user_instructions": {},
+	"about_user_message": {}, "about_model_message": {}, "email": {}, "phone_number": {}, "source": {},
+	"text/plain": {}, "text/html": {}, "text/markdown": {},
+}
+
+// jsonFrame is one open object or array while a JSON document is copied.
+type jsonFrame struct {
+	object bool
+	// expectKey is set in an object while the next string literal is a key.
+	expectKey bool
+	// field is the key of the value being read in an object, and the field holding an array.
+	field string
+}
+
+// jsonPath tracks the containers open at the current position of a JSON document, so each string literal is
+// known to be a key or the value of a field.
+type jsonPath struct {
+	frames []jsonFrame
+}
+
+// field returns the field the value at the current position belongs to; empty at the top level.
+func (path *jsonPath) field() string {
+	if len(path.frames) == 0 {
+		return ""
+	}
+	return path.frames[len(path.frames)-1].field
+}
+
+// expectsKey reports whether the next string literal is an object key.
+func (path *jsonPath) expectsKey() bool {
+	return len(path.frames) > 0 && path.frames[len(path.frames)-1].expectKey
+}
+
+// structural follows a byte outside string literals.
+func (path *jsonPath) structural(current byte) {
+	switch current {
+	case '{':
+		path.frames = append(path.frames, jsonFrame{object: true, expectKey: true, field: path.field()})
+	case '[':
+		path.frames = append(path.frames, jsonFrame{field: path.field()})
+	case '}', ']':
+		if len(path.frames) > 0 {
+			path.frames = path.frames[:len(path.frames)-1]
+		}
+	case ':', ',':
+		if len(path.frames) > 0 && path.frames[len(path.frames)-1].object {
+			path.frames[len(path.frames)-1].expectKey = current == ','
+		}
+	}
+}
+
+// key records the object key just read as the field of the value that follows.
+func (path *jsonPath) key(literal []byte) {
+	var decoded string
+	if json.Unmarshal(literal, &decoded) == nil {
+		path.frames[len(path.frames)-1].field = decoded
+	}
+}
+
+// CopyJSON streams a JSON document from source to destination, masking only the string values of the fields
+// people read (see visibleFields), so keys, ids, numbers such as timestamps, and the order of object keys come
+// through untouched.
+func (redactor *Redactor) CopyJSON(destination io.Writer, source io.Reader) error {
+	reader := bufio.NewReader(source)
+	writer := bufio.NewWriter(destination)
+	var path jsonPath
+	for {
+		current, readErr := reader.ReadByte()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+		if current != '"' {
+			path.structural(current)
+			if writeErr := writer.WriteByte(current); writeErr != nil {
+				return writeErr
+			}
+			continue
+		}
+		literal, literalErr := readStringLiteral(reader)
+		if literalErr != nil && !errors.Is(literalErr, io.EOF) {
+			return literalErr
+		}
+		masked := literal
+		if path.expectsKey() {
+			path.key(literal)
+		} else if _, visible := visibleFields[path.field()]; visible {
+			masked = redactor.jsonString(literal)
+		}
+		if _, writeErr := writer.Write(masked); writeErr != nil {
+			return writeErr
+		}
+		if literalErr != nil {
+			break
+		}
+	}
+	return writer.Flush()
+}
+
+// readStringLiteral reads the rest of a JSON string literal whose opening quote was consumed, returning it
+// with both quotes.
+func readStringLiteral(reader *bufio.Reader) ([]byte, error) {
+	literal := []byte{'"'}
+	for {
+		current, readErr := reader.ReadByte()
+		if readErr != nil {
+			return literal, readErr
+		}
+		literal = append(literal, current)
+		switch current {
+		case '\\':
+			escaped, escapeErr := reader.ReadByte()
+			if escapeErr != nil {
+				return literal, escapeErr
+			}
+			literal = append(literal, escaped)
+		case '"':
+			return literal, nil
+		}
+	}
+}
+
+// jsonString masks one JSON string literal, returning it unchanged when nothing in it is sensitive or it does
+// not decode, as in a truncated document.
+func (redactor *Redactor) jsonString(literal []byte) []byte {
+	var value string
+	if json.Unmarshal(literal, &value) != nil {
+		return literal
+	}
+	masked := redactor.Text(value)
+	if masked == value {
+		return literal
+	}
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(masked) != nil {
+		return literal
+	}
+	return bytes.TrimSuffix(encoded.Bytes(), []byte("\n"))
+}
+
+// passesLuhn reports whether the digits of a card number candidate carry a valid Luhn check digit, which
+// rules out most digit runs that merely look like card numbers.
+func passesLuhn(candidate string) bool {
+	sum, digits := 0, 0
+	for index := len(candidate) - 1; index >= 0; index-- {
+		character := candidate[index]
+		if character < '0' || character > '9' {
+			continue
+		}
+		digit := int(character - '0')
+		if digits%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,94 @@
+package redact
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const digitHeavyUUID = "a1b2c3d4-1234-4567-8901-abcdef123456"
+
+func TestCopyJSONMasksOnlyVisibleValues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{
+			name:     "phone in message part",
+			document: `{"content":{"content_type":"text","parts":["call 415-555-2671 today"]}}`,
+			expected: `{"content":{"content_type":"text","parts":["call [PHONE] today"]}}`,
+		},
+		{
+			name:     "email in title",
+			document: `{"title":"mail jane@example.com"}`,
+			expected: `{"title":"mail [EMAIL]"}`,
+		},
+		{
+			name:     "digit heavy uuid in mapping key, ids, and tree links",
+			document: `{"current_node":"` + digitHeavyUUID + `","mapping":{"` + digitHeavyUUID + `":{"id":"` + digitHeavyUUID + `","parent":"` + digitHeavyUUID + `","children":["` + digitHeavyUUID + `"],"message":{"id":"` + digitHeavyUUID + `","content":{"parts":["hi"]}}}},"conversation_id":"` + digitHeavyUUID + `"}`,
+			expected: `{"current_node":"` + digitHeavyUUID + `","mapping":{"` + digitHeavyUUID + `":{"id":"` + digitHeavyUUID + `","parent":"` + digitHeavyUUID + `","children":["` + digitHeavyUUID + `"],"message":{"id":"` + digitHeavyUUID + `","content":{"parts":["hi"]}}}},"conversation_id":"` + digitHeavyUUID + `"}`,
+		},
+		{
+			name:     "sensitive text as an object key",
+			document: `{"jane@example.com":{"text":"jane@example.com"}}`,
+			expected: `{"jane@example.com":{"text":"[EMAIL]"}}`,
+		},
+		{
+			name:     "field following a nested object",
+			document: `{"metadata":{"gizmo_id":"g-415-555-2671"},"title":"415-555-2671"}`,
+			expected: `{"metadata":{"gizmo_id":"g-415-555-2671"},"title":"[PHONE]"}`,
+		},
+		{
+			name:     "parts holding objects and strings",
+			document: `[{"parts":[{"asset_pointer":"file-service://file-1234-5678-9012"},"ip 10.0.0.1"]}]`,
+			expected: `[{"parts":[{"asset_pointer":"file-service://file-1234-5678-9012"},"ip [IP]"]}]`,
+		},
+		{
+			name:     "escaped quotes and formatting kept",
+			document: "{\n  \"text\": \"say \\\"jane@example.com\\\"\",\n  \"create_time\": 1700000000.5\n}",
+			expected: "{\n  \"text\": \"say \\\"[EMAIL]\\\"\",\n  \"create_time\": 1700000000.5\n}",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			redactor, newErr := New(KnownKinds, nil)
+			if newErr != nil {
+				t.Fatalf("New: %v", newErr)
+			}
+			var output bytes.Buffer
+			if copyErr := redactor.CopyJSON(&output, strings.NewReader(testCase.document)); copyErr != nil {
+				t.Fatalf("CopyJSON: %v", copyErr)
+			}
+			if output.String() != testCase.expected {
+				t.Errorf("CopyJSON(%s)\n got: %s\nwant: %s", testCase.document, output.String(), testCase.expected)
+			}
+		})
+	}
+}
+
+func TestTextMasksKinds(t *testing.T) {
+	testCases := []struct {
+		name     string
+		kinds    []Kind
+		text     string
+		expected string
+	}{
+		{name: "secret", kinds: []Kind{KindSecret}, text: "key sk-abcdefghijklmnopqrstuvwx", expected: "key [SECRET]"},
+		{name: "luhn valid card", kinds: []Kind{KindCard}, text: "card 4111 1111 1111 1111", expected: "card [CARD]"},
+		{name: "luhn invalid digits", kinds: []Kind{KindCard}, text: "order 4111 1111 1111 1112", expected: "order 4111 1111 1111 1112"},
+		{name: "international phone", kinds: []Kind{KindPhone}, text: "call +14155552671", expected: "call [PHONE]"},
+		{name: "kind not selected", kinds: []Kind{KindEmail}, text: "ip 10.0.0.1", expected: "ip 10.0.0.1"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			redactor, newErr := New(testCase.kinds, nil)
+			if newErr != nil {
+				t.Fatalf("New: %v", newErr)
+			}
+			if masked := redactor.Text(testCase.text); masked != testCase.expected {
+				t.Errorf("Text(%q) = %q, want %q", testCase.text, masked, testCase.expected)
+			}
+		})
+	}
+}
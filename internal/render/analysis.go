@@ -0,0 +1,184 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+// AnalysisFormat selects how code interpreter sessions are written out.
+type AnalysisFormat string
+
+const (
+	// AnalysisNone writes nothing for code interpreter sessions.
+	AnalysisNone AnalysisFormat = ""
+	// AnalysisNotebook writes the session as one Jupyter notebook, analysis.ipynb.
+	AnalysisNotebook AnalysisFormat = "notebook"
+	// AnalysisScripts writes each executed cell as a numbered script with its output beside it.
+	AnalysisScripts AnalysisFormat = "scripts"
+)
+
+// KnownAnalysisFormats lists the analysis formats that write output.
+var KnownAnalysisFormats = []AnalysisFormat{AnalysisNotebook, AnalysisScripts}
+
+// AnalysisNotebookName is the file name of the notebook written by AnalysisNotebook.
+const AnalysisNotebookName = "analysis.ipynb"
+
+const (
+	codeInterpreterRecipient = "python"
+	aggregateResultMetadata  = "aggregate_result"
+	analysisScriptFormat     = "%03d.py"
+	analysisOutputFormat     = "%03d.out.txt"
+	notebookFormatMajor      = 4
+	notebookFormatMinor      = 4
+	notebookLanguage         = "python"
+)
+
+// AnalysisCell is one code interpreter execution: the code the assistant ran and what it printed or returned.
+type AnalysisCell struct {
+	Code   string
+	Stdout string
+	Stderr string
+	Result string
+}
+
+// ExtractAnalysisCells pairs each code interpreter call in the messages with the execution output that answered it.
+func ExtractAnalysisCells(messages []utils.Message) []AnalysisCell {
+	var cells []AnalysisCell
+	awaiting := false
+	for _, message := range messages {
+		if message.Recipient == codeInterpreterRecipient {
+			cells = append(cells, AnalysisCell{Code: message.Text})
+			awaiting = true
+			continue
+		}
+		if !awaiting || message.Role != "tool" || message.AuthorName != codeInterpreterRecipient {
+			continue
+		}
+		fillAnalysisOutput(&cells[len(cells)-1], message)
+		awaiting = false
+	}
+	return cells
+}
+
+// fillAnalysisOutput takes stdout, stderr, and the final expression from the aggregate_result metadata the export
+// keeps for executions, falling back to the plain execution output text.
+func fillAnalysisOutput(cell *AnalysisCell, message utils.Message) {
+	aggregate, hasAggregate := message.Metadata[aggregateResultMetadata].(map[string]any)
+	if !hasAggregate {
+		cell.Stdout = message.Text
+		return
+	}
+	var stdout, stderr []string
+	streams, _ := aggregate["messages"].([]any)
+	for _, rawStream := range streams {
+		stream, _ := rawStream.(map[string]any)
+		if messageType, _ := stream["message_type"].(string); messageType != "stream" {
+			continue
+		}
+		text, _ := stream["text"].(string)
+		if name, _ := stream["stream_name"].(string); name == "stderr" {
+			stderr = append(stderr, text)
+		} else {
+			stdout = append(stdout, text)
+		}
+	}
+	cell.Stdout = strings.Join(stdout, "")
+	cell.Stderr = strings.Join(stderr, "")
+	cell.Result, _ = aggregate["final_expression_output"].(string)
+	failure, _ := aggregate["in_kernel_exception"].(map[string]any)
+	traceback, _ := failure["traceback"].([]any)
+	for _, line := range traceback {
+		if text, ok := line.(string); ok {
+			cell.Stderr += text
+		}
+	}
+	if cell.Stdout == "" && cell.Stderr == "" && cell.Result == "" {
+		cell.Stdout = message.Text
+	}
+}
+
+type notebook struct {
+	Cells         []notebookCell `json:"cells"`
+	Metadata      map[string]any `json:"metadata"`
+	NbFormat      int            `json:"nbformat"`
+	NbFormatMinor int            `json:"nbformat_minor"`
+}
+
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	ExecutionCount *int             `json:"execution_count,omitempty"`
+	Metadata       map[string]any   `json:"metadata"`
+	Outputs        []notebookOutput `json:"outputs,omitempty"`
+	Source         string           `json:"source"`
+}
+
+type notebookOutput struct {
+	OutputType     string            `json:"output_type"`
+	Name           string            `json:"name,omitempty"`
+	Text           string            `json:"text,omitempty"`
+	Data           map[string]string `json:"data,omitempty"`
+	ExecutionCount *int              `json:"execution_count,omitempty"`
+	// Metadata is required on execute_result outputs and not allowed on stream outputs.
+	Metadata *struct{} `json:"metadata,omitempty"`
+}
+
+// RenderAnalysis writes the code interpreter cells of a conversation in the given format; it returns nothing
+// when the conversation never ran code.
+func RenderAnalysis(cells []AnalysisCell, format AnalysisFormat) ([]RenderedFile, error) {
+	if len(cells) == 0 || format == AnalysisNone {
+		return nil, nil
+	}
+	if format == AnalysisScripts {
+		return renderAnalysisScripts(cells), nil
+	}
+	document := notebook{
+		Cells:         make([]notebookCell, 0, len(cells)),
+		Metadata:      map[string]any{"language_info": map[string]string{"name": notebookLanguage}},
+		NbFormat:      notebookFormatMajor,
+		NbFormatMinor: notebookFormatMinor,
+	}
+	for index, cell := range cells {
+		executionCount := index + 1
+		notebookEntry := notebookCell{CellType: "code", ExecutionCount: &executionCount, Metadata: map[string]any{}, Source: cell.Code, Outputs: []notebookOutput{}}
+		if cell.Stdout != "" {
+			notebookEntry.Outputs = append(notebookEntry.Outputs, notebookOutput{OutputType: "stream", Name: "stdout", Text: cell.Stdout})
+		}
+		if cell.Stderr != "" {
+			notebookEntry.Outputs = append(notebookEntry.Outputs, notebookOutput{OutputType: "stream", Name: "stderr", Text: cell.Stderr})
+		}
+		if cell.Result != "" {
+			notebookEntry.Outputs = append(notebookEntry.Outputs, notebookOutput{
+				OutputType:     "execute_result",
+				Data:           map[string]string{"text/plain": cell.Result},
+				ExecutionCount: &executionCount,
+				Metadata:       &struct{}{},
+			})
+		}
+		document.Cells = append(document.Cells, notebookEntry)
+	}
+	encoded, encodeErr := json.MarshalIndent(document, "", " ")
+	if encodeErr != nil {
+		return nil, fmt.Errorf("encode analysis notebook: %w", encodeErr)
+	}
+	return []RenderedFile{{Name: AnalysisNotebookName, Content: encoded}}, nil
+}
+
+func renderAnalysisScripts(cells []AnalysisCell) []RenderedFile {
+	files := make([]RenderedFile, 0, 2*len(cells))
+	for index, cell := range cells {
+		files = append(files, RenderedFile{Name: fmt.Sprintf(analysisScriptFormat, index+1), Content: []byte(cell.Code + "\n")})
+		var output []string
+		for _, piece := range []string{cell.Stdout, cell.Stderr, cell.Result} {
+			if piece != "" {
+				output = append(output, strings.TrimRight(piece, "\n"))
+			}
+		}
+		if len(output) > 0 {
+			files = append(files, RenderedFile{Name: fmt.Sprintf(analysisOutputFormat, index+1), Content: []byte(strings.Join(output, "\n") + "\n")})
+		}
+	}
+	return files
+}
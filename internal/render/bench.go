@@ -0,0 +1,69 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"openai_extract/internal/utils"
+)
+
+// BenchRun is what one pass of the matching pipeline over the exports took.
+type BenchRun struct {
+	Duration        time.Duration `json:"duration_ns"`
+	Conversations   int           `json:"conversations"`
+	Bytes           int64         `json:"bytes"`
+	Matched         int           `json:"matched"`
+	AllocatedBytes  uint64        `json:"allocated_bytes"`
+	Allocations     uint64        `json:"allocations"`
+	PeakHeapInUse   uint64        `json:"peak_heap_in_use_bytes"`
+	ConversationsPS float64       `json:"conversations_per_second"`
+	BytesPS         float64       `json:"bytes_per_second"`
+}
+
+// BenchReport describes a benchmark: what was measured, on what, and every run. The Go version and platform
+// are recorded so reports from different releases and machines can be told apart when compared.
+type BenchReport struct {
+	Exports   []string   `json:"exports"`
+	Patterns  []string   `json:"patterns"`
+	Jobs      int        `json:"jobs"`
+	GoVersion string     `json:"go_version"`
+	Platform  string     `json:"platform"`
+	Runs      []BenchRun `json:"runs"`
+	Median    BenchRun   `json:"median"`
+}
+
+// NewBenchReport fills in the environment of a report; the runs are added by the caller.
+func NewBenchReport(exports []string, patterns []string, jobs int) BenchReport {
+	return BenchReport{Exports: exports, Patterns: patterns, Jobs: jobs, GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+}
+
+// RenderBench renders a benchmark report in the given format.
+func RenderBench(report BenchReport, format StatsFormat) ([]byte, error) {
+	if format == StatsJSON {
+		encoded, encodeErr := json.MarshalIndent(report, "", "  ")
+		if encodeErr != nil {
+			return nil, fmt.Errorf("encode bench report: %w", encodeErr)
+		}
+		return append(encoded, '\n'), nil
+	}
+
+	var builder strings.Builder
+	table := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(&builder, "Exports: %s\nPatterns: %s\nJobs: %d, %s on %s\n\n", strings.Join(report.Exports, ", "), strings.Join(report.Patterns, ", "), report.Jobs, report.GoVersion, report.Platform)
+	fmt.Fprintf(table, "Run\tTime\tConversations/s\tMB/s\tMatched\tAllocated\tAllocations\tPeak heap\t\n")
+	for ordinal, run := range report.Runs {
+		writeBenchRow(table, fmt.Sprintf("%d", ordinal+1), run)
+	}
+	writeBenchRow(table, "median", report.Median)
+	table.Flush()
+	return []byte(builder.String()), nil
+}
+
+func writeBenchRow(table *tabwriter.Writer, label string, run BenchRun) {
+	fmt.Fprintf(table, "%s\t%s\t%.0f\t%.1f\t%d\t%s\t%d\t%s\t\n", label, run.Duration.Round(time.Millisecond), run.ConversationsPS,
+		run.BytesPS/(1<<20), run.Matched, utils.FormatByteSize(int64(run.AllocatedBytes)), run.Allocations, utils.FormatByteSize(int64(run.PeakHeapInUse)))
+}
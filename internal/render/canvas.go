@@ -0,0 +1,121 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+const (
+	canvasCreateRecipient = "canmore.create_textdoc"
+	canvasUpdateRecipient = "canmore.update_textdoc"
+	canvasCodeTypePrefix  = "code/"
+	canvasDocumentExt     = ".md"
+	defaultCanvasName     = "canvas"
+	canvasRevisionFormat  = "%s.v%d%s"
+	canvasDuplicateFormat = "%s_%d"
+)
+
+var reUnsafeCanvasName = regexp.MustCompile(`[^\p{L}\p{N} ._-]+`)
+
+type canvasDocument struct {
+	name      string
+	extension string
+	revisions []string
+}
+
+// ExtractCanvasDocuments replays the canvas (textdoc) tool calls in the messages and returns the final content of
+// each document, named after its title, with the extension of its language for code documents. With revisions,
+// every intermediate version is returned too, numbered in the order the edits were made.
+func ExtractCanvasDocuments(messages []utils.Message, revisions bool) []RenderedFile {
+	var documents []*canvasDocument
+	var current *canvasDocument
+	for _, message := range messages {
+		switch message.Recipient {
+		case canvasCreateRecipient:
+			var created struct {
+				Name    string `json:"name"`
+				Type    string `json:"type"`
+				Content string `json:"content"`
+			}
+			if json.Unmarshal([]byte(message.Text), &created) != nil {
+				continue
+			}
+			extension := canvasDocumentExt
+			if language, isCode := strings.CutPrefix(created.Type, canvasCodeTypePrefix); isCode {
+				extension = extensionForLanguage(language)
+			}
+			current = &canvasDocument{name: created.Name, extension: extension, revisions: []string{created.Content}}
+			documents = append(documents, current)
+		case canvasUpdateRecipient:
+			if current == nil {
+				continue
+			}
+			if updated, applied := applyCanvasUpdates(current.revisions[len(current.revisions)-1], message.Text); applied {
+				current.revisions = append(current.revisions, updated)
+			}
+		}
+	}
+
+	var files []RenderedFile
+	usedNames := make(map[string]int)
+	for _, document := range documents {
+		name := canvasFileStem(document.name)
+		usedNames[name]++
+		if usedNames[name] > 1 {
+			name = fmt.Sprintf(canvasDuplicateFormat, name, usedNames[name])
+		}
+		final := document.revisions[len(document.revisions)-1]
+		files = append(files, RenderedFile{Name: name + document.extension, Content: []byte(final)})
+		if !revisions {
+			continue
+		}
+		for index, revision := range document.revisions {
+			files = append(files, RenderedFile{
+				Name:    fmt.Sprintf(canvasRevisionFormat, name, index+1, document.extension),
+				Content: []byte(revision),
+			})
+		}
+	}
+	return files
+}
+
+// applyCanvasUpdates applies the regex replacements of one update_textdoc call. Patterns match across lines,
+// and replacements are literal; an update that does not parse or compile is skipped.
+func applyCanvasUpdates(content string, call string) (string, bool) {
+	var request struct {
+		Updates []struct {
+			Pattern     string `json:"pattern"`
+			Multiple    bool   `json:"multiple"`
+			Replacement string `json:"replacement"`
+		} `json:"updates"`
+	}
+	if json.Unmarshal([]byte(call), &request) != nil || len(request.Updates) == 0 {
+		return content, false
+	}
+	for _, update := range request.Updates {
+		pattern, compileErr := regexp.Compile(`(?s)` + update.Pattern)
+		if compileErr != nil {
+			return content, false
+		}
+		if update.Multiple {
+			content = pattern.ReplaceAllLiteralString(content, update.Replacement)
+			continue
+		}
+		if location := pattern.FindStringIndex(content); location != nil {
+			content = content[:location[0]] + update.Replacement + content[location[1]:]
+		}
+	}
+	return content, true
+}
+
+func canvasFileStem(title string) string {
+	stem := strings.Trim(reUnsafeCanvasName.ReplaceAllString(title, "-"), " .-")
+	if stem == "" {
+		return defaultCanvasName
+	}
+	return stem
+}
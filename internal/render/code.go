@@ -0,0 +1,76 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/utils"
+)
+
+const (
+	snippetFileNameFormat = "snippet_%03d%s"
+	defaultCodeExtension  = ".txt"
+)
+
+var languageExtensions = map[string]string{
+	"python":     ".py",
+	"go":         ".go",
+	"javascript": ".js",
+	"typescript": ".ts",
+	"shell":      ".sh",
+	"cpp":        ".cpp",
+	"c":          ".c",
+	"csharp":     ".cs",
+	"java":       ".java",
+	"kotlin":     ".kt",
+	"swift":      ".swift",
+	"rust":       ".rs",
+	"ruby":       ".rb",
+	"php":        ".php",
+	"sql":        ".sql",
+	"html":       ".html",
+	"css":        ".css",
+	"json":       ".json",
+	"yaml":       ".yaml",
+	"yml":        ".yaml",
+	"toml":       ".toml",
+	"xml":        ".xml",
+	"markdown":   ".md",
+	"md":         ".md",
+	"dockerfile": ".dockerfile",
+	"makefile":   ".mk",
+	"hcl":        ".tf",
+	"terraform":  ".tf",
+	"r":          ".r",
+	"scala":      ".scala",
+	"lua":        ".lua",
+	"perl":       ".pl",
+	"powershell": ".ps1",
+	"jsx":        ".jsx",
+	"tsx":        ".tsx",
+}
+
+// ExtractCodeFiles turns every fenced code block in the messages into a numbered source file.
+func ExtractCodeFiles(messages []utils.Message) []RenderedFile {
+	var files []RenderedFile
+	for _, message := range messages {
+		for _, block := range utils.FindCodeBlocks(message.Text) {
+			if strings.TrimSpace(block.Body) == "" {
+				continue
+			}
+			files = append(files, RenderedFile{
+				Name:    fmt.Sprintf(snippetFileNameFormat, len(files)+1, extensionForLanguage(block.Language)),
+				Content: []byte(block.Body + "\n"),
+			})
+		}
+	}
+	return files
+}
+
+func extensionForLanguage(language string) string {
+	if extension, ok := languageExtensions[filters.NormalizeLanguageName(language)]; ok {
+		return extension
+	}
+	return defaultCodeExtension
+}
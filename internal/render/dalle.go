@@ -0,0 +1,75 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/utils"
+)
+
+const (
+	dalleToolPrefix     = "dalle"
+	dalleAssetMetadata  = "metadata"
+	dalleMetadataKey    = "dalle"
+	dalleAssetPointer   = "asset_pointer"
+	dallePromptField    = "prompt"
+	dalleSeedField      = "seed"
+	dalleGenIDField     = "gen_id"
+	dalleParentGenField = "parent_gen_id"
+)
+
+// DalleImage is one image generated by DALL-E, with the prompt and seed it was generated from. File is the
+// name it was written under, filled in once the image is copied out of the archive.
+type DalleImage struct {
+	File        string `json:"file"`
+	FileID      string `json:"file_id"`
+	Prompt      string `json:"prompt"`
+	Seed        *int64 `json:"seed,omitempty"`
+	GenID       string `json:"gen_id,omitempty"`
+	ParentGenID string `json:"parent_gen_id,omitempty"`
+	MessageID   string `json:"message_id"`
+}
+
+// DalleImages finds the images DALL-E generated in the messages, in order. The prompt is the one recorded with
+// the image; images without one fall back to the prompt of the last request sent to the DALL-E tool.
+func DalleImages(messages []utils.Message) []DalleImage {
+	var images []DalleImage
+	requestedPrompt := ""
+	for _, message := range messages {
+		if strings.HasPrefix(message.Recipient, dalleToolPrefix) {
+			var request struct {
+				Prompt string `json:"prompt"`
+			}
+			if json.Unmarshal([]byte(message.Text), &request) == nil && request.Prompt != "" {
+				requestedPrompt = request.Prompt
+			}
+			continue
+		}
+		for _, asset := range message.Assets {
+			metadata, _ := asset[dalleAssetMetadata].(map[string]any)
+			generation, generated := metadata[dalleMetadataKey].(map[string]any)
+			if !generated {
+				continue
+			}
+			pointer, _ := asset[dalleAssetPointer].(string)
+			fileID, isFile := archive.AssetFileID(pointer)
+			if !isFile {
+				continue
+			}
+			image := DalleImage{FileID: fileID, MessageID: message.ID}
+			image.Prompt, _ = generation[dallePromptField].(string)
+			if image.Prompt == "" {
+				image.Prompt = requestedPrompt
+			}
+			if rawSeed, seeded := generation[dalleSeedField].(float64); seeded {
+				seed := int64(rawSeed)
+				image.Seed = &seed
+			}
+			image.GenID, _ = generation[dalleGenIDField].(string)
+			image.ParentGenID, _ = generation[dalleParentGenField].(string)
+			images = append(images, image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+const (
+	digestHeading         = "# Conversation digest"
+	digestAnchorFallback  = "conversation"
+	digestDuplicateFormat = "%s-%d"
+)
+
+// RenderDigest renders the entries as one chronologically ordered Markdown document with a table of contents.
+// Titles are escaped so Markdown renders them literally, and each conversation is anchored by its slugified
+// title. With timestamps, each message heading carries the time the message was written.
+func RenderDigest(entries []ConversationEntry, timestamps bool) []byte {
+	ordered := sortedByCreateTime(entries)
+	anchors := digestAnchors(ordered)
+
+	var builder strings.Builder
+	builder.WriteString(digestHeading + "\n\n## Contents\n\n")
+	for index, entry := range ordered {
+		fmt.Fprintf(&builder, "%d. [%s](#%s) — %s\n", index+1, escapeMarkdown(displayTitle(entry.Title)), anchors[index], entry.CreateTime.Format(markdownTimeLayout))
+	}
+	builder.WriteString("\n")
+
+	for index, entry := range ordered {
+		fmt.Fprintf(&builder, "<a id=\"%s\"></a>\n\n## %s\n\n", anchors[index], escapeMarkdown(displayTitle(entry.Title)))
+		fmt.Fprintf(&builder, "_Created %s_", entry.CreateTime.Format(markdownTimeLayout))
+		if entry.FolderPath != "" {
+			fmt.Fprintf(&builder, " · folder `%s`", filepath.Base(entry.FolderPath))
+		}
+		builder.WriteString("\n\n")
+		writeMarkdownMessages(&builder, entry.transcript(), "###", timestamps)
+	}
+	return []byte(builder.String())
+}
+
+// digestAnchors returns the anchor of each entry: its slugified title, or "conversation" when nothing of the
+// title is left, numbered from -2 on when another entry already took it.
+func digestAnchors(entries []ConversationEntry) []string {
+	anchors := make([]string, len(entries))
+	used := make(map[string]struct{}, len(entries))
+	for index, entry := range entries {
+		base := utils.Slugify(entry.Title)
+		if base == "" {
+			base = digestAnchorFallback
+		}
+		anchor := base
+		for number := 2; ; number++ {
+			if _, taken := used[anchor]; !taken {
+				break
+			}
+			anchor = fmt.Sprintf(digestDuplicateFormat, base, number)
+		}
+		used[anchor] = struct{}{}
+		anchors[index] = anchor
+	}
+	return anchors
+}
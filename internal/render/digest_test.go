@@ -0,0 +1,61 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderDigestEscapesTitles(t *testing.T) {
+	created := time.Date(2024, time.March, 1, 14, 3, 12, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		titles   []string
+		contains []string
+	}{
+		{
+			name:     "plain title",
+			titles:   []string{"Terraform module"},
+			contains: []string{"1. [Terraform module](#terraform-module)", `<a id="terraform-module"></a>`, "## Terraform module\n"},
+		},
+		{
+			name:     "link metacharacters",
+			titles:   []string{"Fix [bug](x) now"},
+			contains: []string{`1. [Fix \[bug\]\(x\) now](#fix-bug-x-now)`, `## Fix \[bug\]\(x\) now` + "\n"},
+		},
+		{
+			name:     "leading hash",
+			titles:   []string{"# heading"},
+			contains: []string{`1. [\# heading](#heading)`, `## \# heading` + "\n"},
+		},
+		{
+			name:     "title across lines",
+			titles:   []string{"first\n## second"},
+			contains: []string{`## first \#\# second` + "\n"},
+		},
+		{
+			name:     "repeated titles get numbered anchors",
+			titles:   []string{"Notes", "Notes", "Notes 2"},
+			contains: []string{"(#notes)", "(#notes-2)", "(#notes-2-2)"},
+		},
+		{
+			name:     "untitled",
+			titles:   []string{"", "!!!"},
+			contains: []string{"[Untitled conversation](#conversation)", `[\!\!\!](#conversation-2)`},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			entries := make([]ConversationEntry, 0, len(testCase.titles))
+			for index, title := range testCase.titles {
+				entries = append(entries, ConversationEntry{Title: title, CreateTime: created.Add(time.Duration(index) * time.Hour)})
+			}
+			rendered := string(RenderDigest(entries, false))
+			for _, expected := range testCase.contains {
+				if !strings.Contains(rendered, expected) {
+					t.Errorf("digest lacks %q:\n%s", expected, rendered)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DuplicateConversation is one member of a duplicate group. Similarity is the cosine similarity of its
+// transcript to the kept conversation's; Exact marks a transcript identical to it.
+type DuplicateConversation struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	CreateTime time.Time `json:"create_time"`
+	Messages   int       `json:"messages"`
+	Similarity float64   `json:"similarity"`
+	Exact      bool      `json:"exact"`
+}
+
+// DuplicateGroup is a set of conversations holding the same or nearly the same transcript: the one kept and
+// the duplicates of it.
+type DuplicateGroup struct {
+	Keep       DuplicateConversation   `json:"keep"`
+	Duplicates []DuplicateConversation `json:"duplicates"`
+}
+
+const (
+	duplicateDateLayout = "2006-01-02"
+	exactDuplicateLabel = "exact"
+)
+
+// RenderDuplicates renders the duplicate groups in the given format: as a table, each kept conversation is
+// followed by its duplicates and their similarity to it.
+func RenderDuplicates(groups []DuplicateGroup, format StatsFormat) ([]byte, error) {
+	if format == StatsJSON {
+		if groups == nil {
+			groups = []DuplicateGroup{}
+		}
+		encoded, encodeErr := json.MarshalIndent(groups, "", "  ")
+		if encodeErr != nil {
+			return nil, fmt.Errorf("encode duplicates: %w", encodeErr)
+		}
+		return append(encoded, '\n'), nil
+	}
+
+	var builder strings.Builder
+	table := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	duplicates := 0
+	for index, group := range groups {
+		if index > 0 {
+			fmt.Fprintln(table)
+		}
+		writeDuplicateRow(table, "keep", group.Keep)
+		for _, duplicate := range group.Duplicates {
+			similarity := fmt.Sprintf("%.2f", duplicate.Similarity)
+			if duplicate.Exact {
+				similarity = exactDuplicateLabel
+			}
+			writeDuplicateRow(table, "  "+similarity, duplicate)
+		}
+		duplicates += len(group.Duplicates)
+	}
+	if len(groups) > 0 {
+		fmt.Fprintln(table)
+	}
+	fmt.Fprintf(table, "%d duplicate conversations in %d groups\n", duplicates, len(groups))
+	if flushErr := table.Flush(); flushErr != nil {
+		return nil, fmt.Errorf("format duplicates: %w", flushErr)
+	}
+	return []byte(builder.String()), nil
+}
+
+func writeDuplicateRow(table *tabwriter.Writer, label string, conversation DuplicateConversation) {
+	created := "unknown"
+	if !conversation.CreateTime.IsZero() {
+		created = conversation.CreateTime.Format(duplicateDateLayout)
+	}
+	fmt.Fprintf(table, "%s\t%s\t%s\t%d messages\t%s\n", label, conversation.ID, created, conversation.Messages, displayTitle(conversation.Title))
+}
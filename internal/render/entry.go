@@ -0,0 +1,50 @@
+package render
+
+import (
+	"sort"
+	"time"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+// ConversationEntry summarizes a matched conversation for outputs that cover the whole result set.
+type ConversationEntry struct {
+	ID         string
+	Title      string
+	CreateTime time.Time
+	UpdateTime time.Time
+	FolderPath string
+	Messages   []utils.Message
+	// Highlighted holds Messages with matched spans highlighted, for Markdown outputs; nil when nothing is highlighted.
+	Highlighted []utils.Message
+}
+
+// NewConversationEntry builds an entry from a decoded conversation, the messages to render for it, and the folder
+// it was written to, if any.
+func NewConversationEntry(conversation model.Conversation, messages []utils.Message, folderPath string) ConversationEntry {
+	return ConversationEntry{
+		ID:         conversation.ID,
+		Title:      conversation.Title,
+		CreateTime: conversation.CreateTime,
+		UpdateTime: conversation.UpdateTime,
+		FolderPath: folderPath,
+		Messages:   messages,
+	}
+}
+
+// transcript returns the messages Markdown outputs render for the entry.
+func (entry ConversationEntry) transcript() []utils.Message {
+	if entry.Highlighted != nil {
+		return entry.Highlighted
+	}
+	return entry.Messages
+}
+
+func sortedByCreateTime(entries []ConversationEntry) []ConversationEntry {
+	ordered := append([]ConversationEntry(nil), entries...)
+	sort.SliceStable(ordered, func(left, right int) bool {
+		return ordered[left].CreateTime.Before(ordered[right].CreateTime)
+	})
+	return ordered
+}
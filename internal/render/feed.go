@@ -0,0 +1,115 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	atomNamespace      = "http://www.w3.org/2005/Atom"
+	feedTitle          = "Extracted ChatGPT conversations"
+	feedIdentifier     = "urn:openai-extract:feed"
+	feedAuthor         = "openai_extract"
+	entryIDPrefix      = "urn:openai-extract:conversation:"
+	summaryRuneLimit   = 280
+	summaryEllipsis    = "…"
+	fileURLScheme      = "file"
+	alternateRelation  = "alternate"
+	summaryContentType = "text"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomAuthor names who made the feed; RFC 4287 requires one on the feed when its entries carry none.
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Summary   atomSummary `xml:"summary"`
+	Link      *atomLink   `xml:"link,omitempty"`
+}
+
+type atomSummary struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// RenderFeed renders the entries as an Atom feed, newest first, linking each entry to its extracted folder.
+func RenderFeed(entries []ConversationEntry) ([]byte, error) {
+	ordered := sortedByCreateTime(entries)
+	feed := atomFeed{XMLNS: atomNamespace, ID: feedIdentifier, Title: feedTitle, Author: atomAuthor{Name: feedAuthor}}
+	var newest time.Time
+	for index := len(ordered) - 1; index >= 0; index-- {
+		entry := ordered[index]
+		updated := entry.UpdateTime
+		if updated.IsZero() {
+			updated = entry.CreateTime
+		}
+		if updated.After(newest) {
+			newest = updated
+		}
+		atom := atomEntry{
+			ID:        entryIDPrefix + entryIdentifier(entry),
+			Title:     displayTitle(entry.Title),
+			Published: entry.CreateTime.UTC().Format(time.RFC3339),
+			Updated:   updated.UTC().Format(time.RFC3339),
+			Summary:   atomSummary{Type: summaryContentType, Text: summarize(entry)},
+		}
+		if entry.FolderPath != "" {
+			folderURL := url.URL{Scheme: fileURLScheme, Path: filepath.ToSlash(entry.FolderPath) + "/"}
+			atom.Link = &atomLink{Rel: alternateRelation, Href: folderURL.String()}
+		}
+		feed.Entries = append(feed.Entries, atom)
+	}
+	feed.Updated = newest.UTC().Format(time.RFC3339)
+
+	encoded, encodeErr := xml.MarshalIndent(feed, "", "  ")
+	if encodeErr != nil {
+		return nil, fmt.Errorf("encode atom feed: %w", encodeErr)
+	}
+	return append([]byte(xml.Header), append(encoded, '\n')...), nil
+}
+
+func entryIdentifier(entry ConversationEntry) string {
+	if entry.ID != "" {
+		return entry.ID
+	}
+	return fmt.Sprintf("%d", entry.CreateTime.UnixNano())
+}
+
+func summarize(entry ConversationEntry) string {
+	for _, message := range entry.Messages {
+		text := strings.Join(strings.Fields(message.Text), " ")
+		if text == "" {
+			continue
+		}
+		runes := []rune(text)
+		if len(runes) > summaryRuneLimit {
+			return string(runes[:summaryRuneLimit]) + summaryEllipsis
+		}
+		return text
+	}
+	return ""
+}
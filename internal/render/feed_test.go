@@ -0,0 +1,43 @@
+package render
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestRenderFeedNamesAuthor(t *testing.T) {
+	created := time.Date(2024, time.March, 1, 14, 3, 12, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		entries []ConversationEntry
+	}{
+		{name: "no entries"},
+		{name: "entries", entries: []ConversationEntry{{ID: "c1", Title: "Terraform", CreateTime: created}, {ID: "c2", Title: "Ansible", CreateTime: created.Add(time.Hour)}}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rendered, renderErr := RenderFeed(testCase.entries)
+			if renderErr != nil {
+				t.Fatalf("RenderFeed: %v", renderErr)
+			}
+			var feed struct {
+				Authors []struct {
+					Name string `xml:"name"`
+				} `xml:"author"`
+				Entries []struct {
+					ID string `xml:"id"`
+				} `xml:"entry"`
+			}
+			if decodeErr := xml.Unmarshal(rendered, &feed); decodeErr != nil {
+				t.Fatalf("Unmarshal: %v", decodeErr)
+			}
+			if len(feed.Authors) != 1 || feed.Authors[0].Name != feedAuthor {
+				t.Errorf("feed authors = %+v, want one named %q", feed.Authors, feedAuthor)
+			}
+			if len(feed.Entries) != len(testCase.entries) {
+				t.Errorf("feed holds %d entries, want %d", len(feed.Entries), len(testCase.entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const (
+	// DefaultFormat is the per-conversation format written when none is requested.
+	DefaultFormat  = "json"
+	formatShareGPT = "sharegpt"
+	formatMarkdown = "md"
+	formatHTML     = "html"
+	formatSQLite   = "sqlite"
+)
+
+// DocumentInput is what a format renders the document of one conversation from.
+type DocumentInput struct {
+	Conversation model.Conversation
+	// Messages are the messages transcripts include.
+	Messages []utils.Message
+	// Serialized is the conversation as exported.
+	Serialized []byte
+	// Highlights are the spans of pattern hits by message id, marked in transcripts in the Highlight style.
+	Highlights map[string][]TextSpan
+	Highlight  HighlightStyle
+	// Timestamps annotates every message of a transcript with the time it was written.
+	Timestamps bool
+}
+
+// ConversationFormat describes how a matched conversation is written as its primary document.
+type ConversationFormat struct {
+	FileName string
+	Render   func(input DocumentInput) ([]byte, error)
+	// Database marks a format that stores every conversation as rows of one database, named FileName, at the
+	// output root instead of rendering a document into each conversation folder; Render is then nil.
+	Database bool
+}
+
+var conversationFormats = map[string]ConversationFormat{
+	DefaultFormat:  {FileName: "conversation.json", Render: renderPrettyJSON},
+	formatShareGPT: {FileName: "sharegpt.json", Render: renderShareGPT},
+	formatMarkdown: {FileName: "conversation.md", Render: renderMarkdownDocument},
+	formatHTML:     {FileName: "conversation.html", Render: renderHTMLDocument},
+	formatSQLite:   {FileName: "conversations.sqlite", Database: true},
+}
+
+// LookupFormat returns the registered format with the given name.
+func LookupFormat(name string) (ConversationFormat, error) {
+	format, ok := conversationFormats[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return ConversationFormat{}, fmt.Errorf("unknown format %q (supported: %s)", name, strings.Join(FormatNames(), ", "))
+	}
+	return format, nil
+}
+
+// FormatNames lists the registered format names in sorted order.
+func FormatNames() []string {
+	names := make([]string, 0, len(conversationFormats))
+	for name := range conversationFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func renderPrettyJSON(input DocumentInput) ([]byte, error) {
+	return utils.PrettyJSON(input.Serialized)
+}
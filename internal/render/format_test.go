@@ -0,0 +1,64 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+func TestDocumentFormatsHighlightAndTimestamps(t *testing.T) {
+	written := time.Date(2024, time.March, 1, 14, 3, 12, 0, time.UTC)
+	baseInput := DocumentInput{
+		Conversation: model.Conversation{ID: "c1", Title: "Terraform", CreateTime: written},
+		Messages: []utils.Message{
+			{ID: "m1", Role: "user", Text: "plan <the> terraform module", CreateTime: written},
+		},
+		Highlights: map[string][]TextSpan{"m1": {{Start: 11, End: 20}}},
+	}
+	const stamp = "2024-03-01 14:03:12 UTC"
+	testCases := []struct {
+		name       string
+		format     string
+		highlight  HighlightStyle
+		timestamps bool
+		contains   []string
+		excludes   []string
+	}{
+		{name: "markdown mark", format: formatMarkdown, highlight: HighlightMark, contains: []string{"<mark>terraform</mark>"}, excludes: []string{stamp}},
+		{name: "markdown bold", format: formatMarkdown, highlight: HighlightBold, contains: []string{"**terraform**"}},
+		{name: "markdown plain", format: formatMarkdown, excludes: []string{"<mark>", "**"}},
+		{name: "markdown timestamps", format: formatMarkdown, timestamps: true, contains: []string{"## user · " + stamp}},
+		{name: "html mark escapes text", format: formatHTML, highlight: HighlightMark, contains: []string{"plan &lt;the&gt; <mark>terraform</mark> module"}, excludes: []string{stamp}},
+		{name: "html bold", format: formatHTML, highlight: HighlightBold, contains: []string{"<strong>terraform</strong>"}},
+		{name: "html plain", format: formatHTML, contains: []string{"plan &lt;the&gt; terraform module"}, excludes: []string{"<mark>"}},
+		{name: "html timestamps", format: formatHTML, timestamps: true, contains: []string{"user · " + stamp}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			format, lookupErr := LookupFormat(testCase.format)
+			if lookupErr != nil {
+				t.Fatalf("LookupFormat: %v", lookupErr)
+			}
+			input := baseInput
+			input.Highlight = testCase.highlight
+			input.Timestamps = testCase.timestamps
+			rendered, renderErr := format.Render(input)
+			if renderErr != nil {
+				t.Fatalf("Render: %v", renderErr)
+			}
+			for _, expected := range testCase.contains {
+				if !strings.Contains(string(rendered), expected) {
+					t.Errorf("rendered document lacks %q:\n%s", expected, rendered)
+				}
+			}
+			for _, unexpected := range testCase.excludes {
+				if strings.Contains(string(rendered), unexpected) {
+					t.Errorf("rendered document holds %q:\n%s", unexpected, rendered)
+				}
+			}
+		})
+	}
+}
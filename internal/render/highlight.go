@@ -0,0 +1,98 @@
+package render
+
+import (
+	"sort"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+// HighlightStyle selects how matched spans are marked in rendered Markdown.
+type HighlightStyle string
+
+const (
+	// HighlightNone leaves rendered text as it is.
+	HighlightNone HighlightStyle = ""
+	// HighlightMark wraps matched spans in <mark>, which Markdown viewers render as highlighted text.
+	HighlightMark HighlightStyle = "mark"
+	// HighlightBold wraps matched spans in **, for viewers that drop inline HTML.
+	HighlightBold HighlightStyle = "bold"
+)
+
+// KnownHighlightStyles lists the highlight styles that mark matches.
+var KnownHighlightStyles = []HighlightStyle{HighlightMark, HighlightBold}
+
+var highlightDelimiters = map[HighlightStyle][2]string{
+	HighlightMark: {"<mark>", "</mark>"},
+	HighlightBold: {"**", "**"},
+}
+
+// TextSpan is the byte range of a match in a message text.
+type TextSpan struct {
+	Start int
+	End   int
+}
+
+// HighlightMessages returns the messages with the spans recorded for each message id wrapped in the style's
+// delimiters. Overlapping spans are merged; spans that cross a line or fall inside a fenced code block are left
+// alone, since Markdown would show the delimiters literally there.
+func HighlightMessages(messages []utils.Message, spans map[string][]TextSpan, style HighlightStyle) []utils.Message {
+	delimiters, ok := highlightDelimiters[style]
+	if !ok || len(spans) == 0 {
+		return messages
+	}
+	highlighted := make([]utils.Message, len(messages))
+	for index, message := range messages {
+		if messageSpans := spans[message.ID]; message.ID != "" && len(messageSpans) > 0 {
+			message.Text = highlightText(message.Text, messageSpans, delimiters)
+		}
+		highlighted[index] = message
+	}
+	return highlighted
+}
+
+func highlightText(text string, spans []TextSpan, delimiters [2]string) string {
+	var builder strings.Builder
+	highlightSegments(text, spans, func(segment string, matched bool) {
+		if matched {
+			segment = delimiters[0] + segment + delimiters[1]
+		}
+		builder.WriteString(segment)
+	})
+	return builder.String()
+}
+
+// highlightSegments hands text to write in order as segments, marking those of the spans to highlight as
+// matched. Overlapping spans are merged; spans that cross a line or fall inside a fenced code block are written
+// as plain text, since Markdown would show the delimiters literally there.
+func highlightSegments(text string, spans []TextSpan, write func(segment string, matched bool)) {
+	fenced := utils.CodeBlockLines(text)
+	written := 0
+	for _, span := range mergeTextSpans(spans) {
+		if span.Start < written || span.End > len(text) || span.Start == span.End {
+			continue
+		}
+		matched := text[span.Start:span.End]
+		if strings.Contains(matched, "\n") || fenced[strings.Count(text[:span.Start], "\n")] {
+			continue
+		}
+		write(text[written:span.Start], false)
+		write(matched, true)
+		written = span.End
+	}
+	write(text[written:], false)
+}
+
+func mergeTextSpans(spans []TextSpan) []TextSpan {
+	ordered := append([]TextSpan(nil), spans...)
+	sort.Slice(ordered, func(left, right int) bool { return ordered[left].Start < ordered[right].Start })
+	var merged []TextSpan
+	for _, span := range ordered {
+		if count := len(merged); count > 0 && span.Start <= merged[count-1].End {
+			merged[count-1].End = max(merged[count-1].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
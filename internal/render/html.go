@@ -0,0 +1,93 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+var htmlDocument = template.Must(template.New("conversation").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.meta { color: #666; }
+.message { border-top: 1px solid #ddd; padding: 0.5rem 0; }
+.message h2 { font-size: 0.9rem; color: #555; margin: 0.5rem 0; }
+.message.user { background: #f6f8fa; }
+.text { white-space: pre-wrap; overflow-wrap: anywhere; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Created {{.Created}}</p>
+{{range .Messages}}<section class="message {{.Role}}">
+<h2>{{.Label}}</h2>
+<div class="text">{{.Text}}</div>
+</section>
+{{end}}</body>
+</html>
+`))
+
+// htmlHighlightTags are the elements pattern hits are wrapped in, by highlight style.
+var htmlHighlightTags = map[HighlightStyle][2]string{
+	HighlightMark: {"<mark>", "</mark>"},
+	HighlightBold: {"<strong>", "</strong>"},
+}
+
+type htmlMessage struct {
+	Role  string
+	Label string
+	Text  template.HTML
+}
+
+// renderHTMLDocument renders the conversation as a standalone HTML page that opens in any browser.
+func renderHTMLDocument(input DocumentInput) ([]byte, error) {
+	page := struct {
+		Title    string
+		Created  string
+		Messages []htmlMessage
+	}{Title: displayTitle(input.Conversation.Title), Created: input.Conversation.CreateTime.Format(markdownTimeLayout)}
+	for _, message := range input.Messages {
+		if strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		role := message.Role
+		if role == "" {
+			role = unknownRole
+		}
+		label := role
+		if message.Branch != "" {
+			label += " (" + message.Branch + ")"
+		}
+		if stamp := messageTime(message); input.Timestamps && stamp != "" {
+			label += " · " + stamp
+		}
+		page.Messages = append(page.Messages, htmlMessage{Role: role, Label: label, Text: highlightHTML(message.Text, input.Highlights[message.ID], input.Highlight)})
+	}
+	var rendered bytes.Buffer
+	if executeErr := htmlDocument.Execute(&rendered, page); executeErr != nil {
+		return nil, fmt.Errorf("render html: %w", executeErr)
+	}
+	return rendered.Bytes(), nil
+}
+
+// highlightHTML escapes text for the page, wrapping the spans of pattern hits in the element of the style.
+func highlightHTML(text string, spans []TextSpan, style HighlightStyle) template.HTML {
+	tags, highlighted := htmlHighlightTags[style]
+	if !highlighted || len(spans) == 0 {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	var builder strings.Builder
+	highlightSegments(text, spans, func(segment string, matched bool) {
+		if matched {
+			builder.WriteString(tags[0] + template.HTMLEscapeString(segment) + tags[1])
+			return
+		}
+		builder.WriteString(template.HTMLEscapeString(segment))
+	})
+	return template.HTML(builder.String())
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"openai_extract/internal/utils"
+)
+
+// ExportInfo identifies one export at a glance: when it was made, whose account it holds, and how much it
+// contains. ExportDateSource tells where the date came from, as exports record it only in their file name.
+type ExportInfo struct {
+	Path             string         `json:"path"`
+	ExportDate       time.Time      `json:"export_date"`
+	ExportDateSource string         `json:"export_date_source"`
+	AccountEmails    []string       `json:"account_emails"`
+	Conversations    int            `json:"conversations"`
+	AttachmentFiles  int            `json:"attachment_files"`
+	AttachmentBytes  int64          `json:"attachment_bytes"`
+	SchemaVersion    string         `json:"schema_version"`
+	SchemaVersions   map[string]int `json:"schema_versions"`
+}
+
+const infoDateLayout = "2006-01-02 15:04:05"
+
+// RenderInfo renders one block per export in the given format.
+func RenderInfo(infos []ExportInfo, format StatsFormat) ([]byte, error) {
+	if format == StatsJSON {
+		encoded, encodeErr := json.MarshalIndent(infos, "", "  ")
+		if encodeErr != nil {
+			return nil, fmt.Errorf("encode info: %w", encodeErr)
+		}
+		return append(encoded, '\n'), nil
+	}
+
+	var builder strings.Builder
+	table := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	for index, info := range infos {
+		if index > 0 {
+			fmt.Fprintln(table)
+		}
+		fmt.Fprintf(table, "Export\t%s\n", info.Path)
+		if info.ExportDate.IsZero() {
+			fmt.Fprintf(table, "Export date\tunknown\n")
+		} else {
+			fmt.Fprintf(table, "Export date\t%s (%s)\n", info.ExportDate.Format(infoDateLayout), info.ExportDateSource)
+		}
+		account := "unknown"
+		if len(info.AccountEmails) > 0 {
+			account = strings.Join(info.AccountEmails, ", ")
+		}
+		fmt.Fprintf(table, "Account\t%s\n", account)
+		fmt.Fprintf(table, "Conversations\t%d\n", info.Conversations)
+		fmt.Fprintf(table, "Attachments\t%d files, %s\n", info.AttachmentFiles, utils.FormatByteSize(info.AttachmentBytes))
+		fmt.Fprintf(table, "Schema version\t%s\n", describeSchemaVersions(info))
+	}
+	if flushErr := table.Flush(); flushErr != nil {
+		return nil, fmt.Errorf("format info: %w", flushErr)
+	}
+	return []byte(builder.String()), nil
+}
+
+// describeSchemaVersions names the predominant schema version and, when conversations differ, how many
+// conversations use each version.
+func describeSchemaVersions(info ExportInfo) string {
+	if len(info.SchemaVersions) <= 1 {
+		return info.SchemaVersion
+	}
+	versions := make([]string, 0, len(info.SchemaVersions))
+	for version := range info.SchemaVersions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	counts := make([]string, 0, len(versions))
+	for _, version := range versions {
+		counts = append(counts, fmt.Sprintf("%s: %d", version, info.SchemaVersions[version]))
+	}
+	return fmt.Sprintf("%s (%s)", info.SchemaVersion, strings.Join(counts, ", "))
+}
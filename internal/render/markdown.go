@@ -0,0 +1,82 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+const (
+	untitledConversation = "Untitled conversation"
+	markdownTimeLayout   = "2006-01-02 15:04"
+	messageTimeLayout    = "2006-01-02 15:04:05 MST"
+)
+
+func displayTitle(title string) string {
+	if strings.TrimSpace(title) == "" {
+		return untitledConversation
+	}
+	return title
+}
+
+// markdownEscaper backslash-escapes the characters Markdown reads as links, headings, emphasis, or HTML.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"#", `\#`, "<", `\<`, ">", `\>`, "!", `\!`, "|", `\|`, "~", `\~`,
+)
+
+// escapeMarkdown makes text render literally inside a Markdown heading or link text, on one line.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(strings.Join(strings.Fields(text), " "))
+}
+
+// messageTime formats the creation time of a message for annotating transcripts, or "" when it is unknown.
+func messageTime(message utils.Message) string {
+	if message.CreateTime.IsZero() {
+		return ""
+	}
+	return message.CreateTime.Format(messageTimeLayout)
+}
+
+// renderMarkdownDocument renders the conversation as a Markdown transcript with its title as the heading.
+func renderMarkdownDocument(input DocumentInput) ([]byte, error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n\n_Created %s_\n\n", displayTitle(input.Conversation.Title), input.Conversation.CreateTime.Format(markdownTimeLayout))
+	writeMarkdownMessages(&builder, HighlightMessages(input.Messages, input.Highlights, input.Highlight), "##", input.Timestamps)
+	return []byte(builder.String()), nil
+}
+
+func writeMarkdownMessages(builder *strings.Builder, messages []utils.Message, headingPrefix string, timestamps bool) {
+	for _, message := range messages {
+		if strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		role := message.Role
+		if role == "" {
+			role = unknownRole
+		}
+		if message.Branch != "" {
+			role += " _(" + message.Branch + ")_"
+		}
+		if stamp := messageTime(message); timestamps && stamp != "" {
+			role += " · " + stamp
+		}
+		fmt.Fprintf(builder, "%s %s\n\n%s\n\n", headingPrefix, role, message.Text)
+	}
+}
+
+const reasoningSectionFormat = "<details>\n<summary>Reasoning</summary>\n\n%s\n\n</details>"
+
+// ReasoningAsText returns the messages with the reasoning of each reasoning message turned into its text, as a
+// collapsible Markdown section, so transcripts show it instead of skipping the otherwise empty message.
+func ReasoningAsText(messages []utils.Message) []utils.Message {
+	converted := make([]utils.Message, len(messages))
+	for index, message := range messages {
+		if message.Reasoning != "" && strings.TrimSpace(message.Text) == "" {
+			message.Text = fmt.Sprintf(reasoningSectionFormat, message.Reasoning)
+		}
+		converted[index] = message
+	}
+	return converted
+}
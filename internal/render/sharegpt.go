@@ -0,0 +1,44 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/model"
+)
+
+var shareGPTSpeakers = map[string]string{
+	"user":      "human",
+	"assistant": "gpt",
+	"system":    "system",
+}
+
+type shareGPTConversation struct {
+	ID            string         `json:"id"`
+	Title         string         `json:"title,omitempty"`
+	Conversations []shareGPTTurn `json:"conversations"`
+}
+
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+// renderShareGPT always uses the active branch: abandoned regenerations would read as duplicate turns.
+func renderShareGPT(input DocumentInput) ([]byte, error) {
+	entry := NewConversationEntry(input.Conversation, model.ActiveBranch(input.Conversation), "")
+	document := shareGPTConversation{ID: entry.ID, Title: entry.Title, Conversations: []shareGPTTurn{}}
+	for _, message := range entry.Messages {
+		speaker, known := shareGPTSpeakers[message.Role]
+		if !known || strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		document.Conversations = append(document.Conversations, shareGPTTurn{From: speaker, Value: message.Text})
+	}
+	encoded, encodeErr := json.MarshalIndent(document, "", "  ")
+	if encodeErr != nil {
+		return nil, fmt.Errorf("encode sharegpt conversation: %w", encodeErr)
+	}
+	return encoded, nil
+}
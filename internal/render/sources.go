@@ -0,0 +1,87 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+// SourcesFileName is the bibliography written for conversations that cite web pages.
+const SourcesFileName = "sources.md"
+
+const sourcesHeadingFormat = "# Sources: %s"
+
+// Citation is one web page a conversation cited.
+type Citation struct {
+	Title string
+	URL   string
+}
+
+// CollectCitations gathers the web pages cited in the messages' metadata: citations, content references
+// (including grouped web pages), and the cite metadata list of older browsing messages. Each URL is listed
+// once, under the first title recorded for it, in order of first citation.
+func CollectCitations(messages []utils.Message) []Citation {
+	var citations []Citation
+	seen := make(map[string]int)
+	add := func(title string, url string) {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			return
+		}
+		if index, known := seen[url]; known {
+			if citations[index].Title == "" {
+				citations[index].Title = strings.TrimSpace(title)
+			}
+			return
+		}
+		seen[url] = len(citations)
+		citations = append(citations, Citation{Title: strings.TrimSpace(title), URL: url})
+	}
+	for _, message := range messages {
+		for _, rawCitation := range metadataList(message.Metadata, "citations") {
+			citation, _ := rawCitation.(map[string]any)
+			details, _ := citation["metadata"].(map[string]any)
+			addCitedPage(details, add)
+		}
+		for _, rawReference := range metadataList(message.Metadata, "content_references") {
+			reference, _ := rawReference.(map[string]any)
+			addCitedPage(reference, add)
+			for _, rawItem := range metadataList(reference, "items") {
+				item, _ := rawItem.(map[string]any)
+				addCitedPage(item, add)
+			}
+		}
+		citeMetadata, _ := message.Metadata["_cite_metadata"].(map[string]any)
+		for _, rawEntry := range metadataList(citeMetadata, "metadata_list") {
+			entry, _ := rawEntry.(map[string]any)
+			addCitedPage(entry, add)
+		}
+	}
+	return citations
+}
+
+func metadataList(fields map[string]any, key string) []any {
+	list, _ := fields[key].([]any)
+	return list
+}
+
+func addCitedPage(fields map[string]any, add func(title string, url string)) {
+	url, _ := fields["url"].(string)
+	title, _ := fields["title"].(string)
+	add(title, url)
+}
+
+// RenderSources renders the citations as a Markdown bibliography, one linked entry per cited page.
+func RenderSources(title string, citations []Citation) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, sourcesHeadingFormat+"\n\n", displayTitle(title))
+	for index, citation := range citations {
+		label := citation.Title
+		if label == "" {
+			label = citation.URL
+		}
+		fmt.Fprintf(&builder, "%d. [%s](%s)\n", index+1, label, citation.URL)
+	}
+	return []byte(builder.String())
+}
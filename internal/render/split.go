@@ -0,0 +1,48 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+const (
+	splitFileNameFormat = "%03d-%s.md"
+	unknownRole         = "unknown"
+)
+
+// RenderedFile is a named piece of rendered output destined for a conversation folder.
+type RenderedFile struct {
+	Name    string
+	Content []byte
+}
+
+// SplitMessages renders every non-empty message as its own numbered Markdown file, suffixing the names of
+// messages off the active branch with their branch label. Role and branch label come from the export, so they
+// are slugified before going into a file name. With timestamps, each file starts with the time the message was
+// written.
+func SplitMessages(messages []utils.Message, timestamps bool) []RenderedFile {
+	files := make([]RenderedFile, 0, len(messages))
+	for _, message := range messages {
+		if strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		role := utils.Slugify(message.Role)
+		if role == "" {
+			role = unknownRole
+		}
+		if branch := utils.Slugify(message.Branch); branch != "" {
+			role += "-" + branch
+		}
+		content := message.Text + "\n"
+		if stamp := messageTime(message); timestamps && stamp != "" {
+			content = "_" + stamp + "_\n\n" + content
+		}
+		files = append(files, RenderedFile{
+			Name:    fmt.Sprintf(splitFileNameFormat, len(files)+1, role),
+			Content: []byte(content),
+		})
+	}
+	return files
+}
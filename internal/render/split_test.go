@@ -0,0 +1,33 @@
+package render
+
+import (
+	"testing"
+
+	"openai_extract/internal/utils"
+)
+
+func TestSplitMessagesNames(t *testing.T) {
+	testCases := []struct {
+		name         string
+		message      utils.Message
+		expectedName string
+	}{
+		{name: "role", message: utils.Message{Role: "assistant", Text: "hi"}, expectedName: "001-assistant.md"},
+		{name: "branch label", message: utils.Message{Role: "user", Branch: "alternative 2", Text: "hi"}, expectedName: "001-user-alternative-2.md"},
+		{name: "missing role", message: utils.Message{Text: "hi"}, expectedName: "001-unknown.md"},
+		{name: "climbing role", message: utils.Message{Role: "a/../../../../tmp/pwned", Text: "hi"}, expectedName: "001-a-tmp-pwned.md"},
+		{name: "separator only role", message: utils.Message{Role: "../..", Text: "hi"}, expectedName: "001-unknown.md"},
+		{name: "climbing branch", message: utils.Message{Role: "user", Branch: `..\..\evil`, Text: "hi"}, expectedName: "001-user-evil.md"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			files := SplitMessages([]utils.Message{testCase.message}, false)
+			if len(files) != 1 {
+				t.Fatalf("SplitMessages returned %d files, want 1", len(files))
+			}
+			if files[0].Name != testCase.expectedName {
+				t.Errorf("name = %q, want %q", files[0].Name, testCase.expectedName)
+			}
+		})
+	}
+}
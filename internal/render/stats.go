@@ -0,0 +1,88 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// StatsFormat selects how export statistics are printed.
+type StatsFormat string
+
+const (
+	// StatsTable prints the statistics as aligned text tables.
+	StatsTable StatsFormat = "table"
+	// StatsJSON prints the statistics as one JSON document.
+	StatsJSON StatsFormat = "json"
+)
+
+// KnownStatsFormats lists the supported statistics formats.
+var KnownStatsFormats = []StatsFormat{StatsTable, StatsJSON}
+
+const statsDateLayout = "2006-01-02"
+
+// Stats aggregates an export: how many conversations and messages it holds, when they were held, and which
+// models and attachments they used. Months are keyed "YYYY-MM"; models are keyed by slug.
+type Stats struct {
+	Conversations                int            `json:"conversations"`
+	Messages                     int            `json:"messages"`
+	FirstConversation            time.Time      `json:"first_conversation"`
+	LastConversation             time.Time      `json:"last_conversation"`
+	ConversationsPerMonth        map[string]int `json:"conversations_per_month"`
+	Models                       map[string]int `json:"models"`
+	Attachments                  int            `json:"attachments"`
+	ImageAttachments             int            `json:"image_attachments"`
+	ConversationsWithAttachments int            `json:"conversations_with_attachments"`
+}
+
+// RenderStats renders the statistics in the given format.
+func RenderStats(stats Stats, format StatsFormat) ([]byte, error) {
+	if format == StatsJSON {
+		encoded, encodeErr := json.MarshalIndent(stats, "", "  ")
+		if encodeErr != nil {
+			return nil, fmt.Errorf("encode stats: %w", encodeErr)
+		}
+		return append(encoded, '\n'), nil
+	}
+
+	var builder strings.Builder
+	table := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "Conversations\t%d\n", stats.Conversations)
+	fmt.Fprintf(table, "Messages\t%d\n", stats.Messages)
+	if stats.Conversations > 0 {
+		fmt.Fprintf(table, "Date range\t%s – %s\n", stats.FirstConversation.Format(statsDateLayout), stats.LastConversation.Format(statsDateLayout))
+	}
+	fmt.Fprintf(table, "Attachments\t%d (%d images) in %d conversations\n", stats.Attachments, stats.ImageAttachments, stats.ConversationsWithAttachments)
+
+	months := make([]string, 0, len(stats.ConversationsPerMonth))
+	for month := range stats.ConversationsPerMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+	fmt.Fprintf(table, "\nMonth\tConversations\n")
+	for _, month := range months {
+		fmt.Fprintf(table, "%s\t%d\n", month, stats.ConversationsPerMonth[month])
+	}
+
+	models := make([]string, 0, len(stats.Models))
+	for slug := range stats.Models {
+		models = append(models, slug)
+	}
+	sort.Slice(models, func(left, right int) bool {
+		if stats.Models[models[left]] != stats.Models[models[right]] {
+			return stats.Models[models[left]] > stats.Models[models[right]]
+		}
+		return models[left] < models[right]
+	})
+	fmt.Fprintf(table, "\nModel\tConversations\n")
+	for _, slug := range models {
+		fmt.Fprintf(table, "%s\t%d\n", slug, stats.Models[slug])
+	}
+	if flushErr := table.Flush(); flushErr != nil {
+		return nil, fmt.Errorf("format stats: %w", flushErr)
+	}
+	return []byte(builder.String()), nil
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+)
+
+const (
+	templateSuffix     = ".tmpl"
+	templateOutputStem = "conversation"
+	defaultTemplateExt = ".txt"
+)
+
+// TemplateData exposes the structured fields of a conversation to user-supplied templates.
+type TemplateData struct {
+	ID          string
+	Title       string
+	CreateTime  time.Time
+	UpdateTime  time.Time
+	Messages    []utils.Message
+	Attachments []string
+}
+
+// NewTemplateData builds template data from a decoded conversation, the messages to render, and its written
+// attachment names.
+func NewTemplateData(conversation model.Conversation, messages []utils.Message, attachments []string) TemplateData {
+	return TemplateData{
+		ID:          conversation.ID,
+		Title:       conversation.Title,
+		CreateTime:  conversation.CreateTime,
+		UpdateTime:  conversation.UpdateTime,
+		Messages:    messages,
+		Attachments: attachments,
+	}
+}
+
+// TemplateRenderer renders conversations through a user-supplied text/template.
+type TemplateRenderer struct {
+	parsed     *template.Template
+	outputName string
+}
+
+// LoadTemplate parses the template file and derives the output file name from its extension.
+func LoadTemplate(templatePath string) (*TemplateRenderer, error) {
+	source, readErr := os.ReadFile(templatePath)
+	if readErr != nil {
+		return nil, fmt.Errorf("read template %q: %w", templatePath, readErr)
+	}
+	parsed, parseErr := template.New(filepath.Base(templatePath)).Parse(string(source))
+	if parseErr != nil {
+		return nil, fmt.Errorf("parse template %q: %w", templatePath, parseErr)
+	}
+	extension := filepath.Ext(strings.TrimSuffix(filepath.Base(templatePath), templateSuffix))
+	if extension == "" {
+		extension = defaultTemplateExt
+	}
+	return &TemplateRenderer{parsed: parsed, outputName: templateOutputStem + extension}, nil
+}
+
+// OutputName returns the file name rendered output is written to inside a conversation folder.
+func (renderer *TemplateRenderer) OutputName() string {
+	return renderer.outputName
+}
+
+// Render executes the template against the supplied data.
+func (renderer *TemplateRenderer) Render(data TemplateData) ([]byte, error) {
+	var buffer bytes.Buffer
+	if execErr := renderer.parsed.Execute(&buffer, data); execErr != nil {
+		return nil, fmt.Errorf("execute template: %w", execErr)
+	}
+	return buffer.Bytes(), nil
+}
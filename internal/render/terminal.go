@@ -0,0 +1,34 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+const terminalRule = "──"
+
+// RenderTerminal renders one conversation as a plain-text transcript for reading in a terminal: a title block,
+// then each message under a rule naming its author and, when known, when it was written.
+func RenderTerminal(entry ConversationEntry) []byte {
+	var builder strings.Builder
+	title := displayTitle(entry.Title)
+	fmt.Fprintf(&builder, "%s\n%s\n", title, strings.Repeat("=", len([]rune(title))))
+	fmt.Fprintf(&builder, "Created %s · id %s\n\n", entry.CreateTime.Format(markdownTimeLayout), entry.ID)
+	for _, message := range entry.Messages {
+		if strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		label := message.Role
+		if label == "" {
+			label = unknownRole
+		}
+		if message.Branch != "" {
+			label += " (" + message.Branch + ")"
+		}
+		if stamp := messageTime(message); stamp != "" {
+			label += " · " + stamp
+		}
+		fmt.Fprintf(&builder, "%s %s %s\n%s\n\n", terminalRule, label, terminalRule, strings.TrimSpace(message.Text))
+	}
+	return []byte(builder.String())
+}
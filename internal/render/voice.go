@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/utils"
+)
+
+// VoiceTranscriptName is the file name of the merged transcript of a voice conversation.
+const VoiceTranscriptName = "transcript.md"
+
+const (
+	audioPointerContentType  = "audio_asset_pointer"
+	audioPointerField        = "audio_asset_pointer"
+	audioTranscriptionType   = "audio_transcription"
+	voiceTranscriptHeading   = "# Voice transcript: %s"
+	voiceTranscriptTimeStamp = "15:04:05"
+)
+
+var voiceSpeakers = map[string]string{
+	"user":      "User",
+	"assistant": "ChatGPT",
+}
+
+// VoiceClip is one recorded audio clip of a voice conversation.
+type VoiceClip struct {
+	FileID    string
+	Role      string
+	MessageID string
+}
+
+// VoiceClips returns the audio clips the messages point at, in order. User clips are nested in the
+// real-time audio/video pointer the export wraps them in; assistant clips are plain audio pointers.
+func VoiceClips(messages []utils.Message) []VoiceClip {
+	var clips []VoiceClip
+	for _, message := range messages {
+		for _, asset := range message.Assets {
+			pointerAsset := asset
+			if nested, wrapped := asset[audioPointerField].(map[string]any); wrapped {
+				pointerAsset = nested
+			} else if contentType, _ := asset["content_type"].(string); contentType != audioPointerContentType {
+				continue
+			}
+			pointer, _ := pointerAsset["asset_pointer"].(string)
+			if fileID, isFile := archive.AssetFileID(pointer); isFile {
+				clips = append(clips, VoiceClip{FileID: fileID, Role: message.Role, MessageID: message.ID})
+			}
+		}
+	}
+	return clips
+}
+
+// RenderVoiceTranscript merges the audio transcriptions of the messages into one Markdown transcript, each turn
+// labelled with its speaker and time. It returns nil when no message carries a transcription.
+func RenderVoiceTranscript(title string, messages []utils.Message) []byte {
+	var builder strings.Builder
+	turns := 0
+	for _, message := range messages {
+		var pieces []string
+		for _, asset := range message.Assets {
+			if contentType, _ := asset["content_type"].(string); contentType != audioTranscriptionType {
+				continue
+			}
+			if text, _ := asset["text"].(string); strings.TrimSpace(text) != "" {
+				pieces = append(pieces, strings.TrimSpace(text))
+			}
+		}
+		if len(pieces) == 0 {
+			continue
+		}
+		speaker, known := voiceSpeakers[message.Role]
+		if !known {
+			speaker = message.Role
+		}
+		timestamp := ""
+		if !message.CreateTime.IsZero() {
+			timestamp = "[" + message.CreateTime.Format(voiceTranscriptTimeStamp) + "] "
+		}
+		fmt.Fprintf(&builder, "%s**%s:** %s\n\n", timestamp, speaker, strings.Join(pieces, " "))
+		turns++
+	}
+	if turns == 0 {
+		return nil
+	}
+	return []byte(fmt.Sprintf(voiceTranscriptHeading+"\n\n", displayTitle(title)) + builder.String())
+}
@@ -0,0 +1,92 @@
+package semantic
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"openai_extract/internal/utils"
+)
+
+const cacheKeySeparator = "@"
+
+// Cache persists conversation embeddings keyed by conversation id and update time.
+type Cache struct {
+	path    string
+	Version string
+	Vectors map[string]Vector
+	dirty   bool
+}
+
+// LoadCache reads the cache at path; a missing file or one written by another embedder version yields an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	cache := &Cache{path: path, Version: EmbedderVersion, Vectors: make(map[string]Vector)}
+	file, openErr := os.Open(path)
+	if errors.Is(openErr, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if openErr != nil {
+		return nil, fmt.Errorf("open embedding cache %q: %w", path, openErr)
+	}
+	defer file.Close()
+	var stored Cache
+	if decodeErr := gob.NewDecoder(file).Decode(&stored); decodeErr != nil {
+		return cache, nil
+	}
+	if stored.Version == EmbedderVersion && stored.Vectors != nil {
+		cache.Vectors = stored.Vectors
+	}
+	return cache, nil
+}
+
+// Lookup returns the cached vector for key, embedding and caching text when absent.
+func (cache *Cache) Lookup(key string, text string, embedder Embedder) Vector {
+	if vector, ok := cache.Vectors[key]; ok {
+		return vector
+	}
+	vector := embedder.Embed(text)
+	cache.Vectors[key] = vector
+	cache.dirty = true
+	return vector
+}
+
+// Retain drops the vectors of conversations missing from present, which maps the id of every conversation
+// still in the exports to its update time in Unix seconds, so vectors of deleted conversations and of their
+// superseded versions do not pile up.
+func (cache *Cache) Retain(present map[string]int64) {
+	for key := range cache.Vectors {
+		separator := strings.LastIndex(key, cacheKeySeparator)
+		if separator >= 0 {
+			updateUnix, parseErr := strconv.ParseInt(key[separator+len(cacheKeySeparator):], 10, 64)
+			if current, found := present[key[:separator]]; parseErr == nil && found && current == updateUnix {
+				continue
+			}
+		}
+		delete(cache.Vectors, key)
+		cache.dirty = true
+	}
+}
+
+// Save writes the cache back to disk when vectors were added or dropped.
+func (cache *Cache) Save() error {
+	if !cache.dirty {
+		return nil
+	}
+	if writeErr := utils.WriteFileAtomicStream(cache.path, func(destination io.Writer) error {
+		return gob.NewEncoder(destination).Encode(cache)
+	}); writeErr != nil {
+		return fmt.Errorf("save embedding cache: %w", writeErr)
+	}
+	cache.dirty = false
+	return nil
+}
+
+// cacheKey is the key the vector of a conversation is cached under: its id and update time.
+func cacheKey(conversationID string, updateUnix int64) string {
+	return conversationID + cacheKeySeparator + strconv.FormatInt(updateUnix, 10)
+}
@@ -0,0 +1,50 @@
+package semantic
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCacheRetain(t *testing.T) {
+	testCases := []struct {
+		name         string
+		present      map[string]int64
+		expectedKeys []string
+	}{
+		{name: "all present", present: map[string]int64{"c1": 100, "c2": 200}, expectedKeys: []string{"c1@100", "c2@200"}},
+		{name: "deleted conversation", present: map[string]int64{"c1": 100}, expectedKeys: []string{"c1@100"}},
+		{name: "updated conversation", present: map[string]int64{"c1": 150, "c2": 200}, expectedKeys: []string{"c2@200"}},
+		{name: "nothing present", present: map[string]int64{}, expectedKeys: []string{}},
+		{name: "id holding the separator", present: map[string]int64{"user@example": 300}, expectedKeys: []string{"user@example@300"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cachePath := filepath.Join(t.TempDir(), "embeddings.gob")
+			cache, loadErr := LoadCache(cachePath)
+			if loadErr != nil {
+				t.Fatalf("LoadCache: %v", loadErr)
+			}
+			embedder := HashingEmbedder{}
+			cache.Lookup(cacheKey("c1", 100), "terraform plan", embedder)
+			cache.Lookup(cacheKey("c2", 200), "ansible playbook", embedder)
+			cache.Lookup(cacheKey("user@example", 300), "mail setup", embedder)
+			cache.Retain(testCase.present)
+			if saveErr := cache.Save(); saveErr != nil {
+				t.Fatalf("Save: %v", saveErr)
+			}
+			reloaded, reloadErr := LoadCache(cachePath)
+			if reloadErr != nil {
+				t.Fatalf("LoadCache: %v", reloadErr)
+			}
+			keys := make([]string, 0, len(reloaded.Vectors))
+			for key := range reloaded.Vectors {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, testCase.expectedKeys) {
+				t.Errorf("cached keys = %v, want %v", keys, testCase.expectedKeys)
+			}
+		})
+	}
+}
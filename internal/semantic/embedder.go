@@ -0,0 +1,112 @@
+package semantic
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	embeddingDimensions = 1024
+	minimumTokenLength  = 2
+	bigramSeparator     = " "
+	// EmbedderVersion identifies the embedding scheme so cached vectors from other schemes are discarded.
+	EmbedderVersion = "hashing-v1"
+)
+
+var stopWords = map[string]struct{}{
+	"the": {}, "and": {}, "for": {}, "are": {}, "but": {}, "not": {}, "you": {}, "with": {}, "this": {},
+	"that": {}, "from": {}, "have": {}, "was": {}, "what": {}, "how": {}, "can": {}, "your": {}, "its": {},
+	"into": {}, "there": {}, "then": {}, "than": {}, "them": {}, "they": {}, "would": {}, "could": {},
+	"should": {}, "about": {}, "which": {}, "will": {}, "just": {}, "like": {}, "also": {}, "any": {},
+	"all": {}, "use": {}, "using": {}, "here": {}, "some": {}, "our": {}, "out": {}, "been": {}, "has": {},
+	"do": {}, "does": {}, "did": {}, "is": {}, "it": {}, "in": {}, "of": {}, "on": {}, "to": {}, "an": {},
+	"as": {}, "at": {}, "be": {}, "by": {}, "if": {}, "or": {}, "so": {}, "we": {}, "me": {}, "my": {},
+}
+
+// Vector is a unit-length embedding.
+type Vector []float32
+
+// Embedder turns text into embeddings.
+type Embedder interface {
+	Embed(text string) Vector
+}
+
+// HashingEmbedder computes local embeddings by feature-hashing word unigrams and bigrams with sublinear
+// term frequency weighting; it needs no model files or network access. Its similarity is lexical: texts are
+// close when they share words, and synonyms or paraphrases without common words are not recognized.
+type HashingEmbedder struct{}
+
+// Embed returns the L2-normalized hashed bag-of-words embedding of text.
+func (HashingEmbedder) Embed(text string) Vector {
+	vector := make(Vector, embeddingDimensions)
+	counts := make(map[string]int)
+	tokens := tokenize(text)
+	for index, token := range tokens {
+		counts[token]++
+		if index > 0 {
+			counts[tokens[index-1]+bigramSeparator+token]++
+		}
+	}
+	for feature, count := range counts {
+		bucket, sign := hashFeature(feature)
+		vector[bucket] += sign * float32(1+math.Log(float64(count)))
+	}
+	return normalize(vector)
+}
+
+// Cosine returns the cosine similarity of two unit-length vectors.
+func Cosine(left Vector, right Vector) float64 {
+	if len(left) != len(right) {
+		return 0
+	}
+	var dot float64
+	for index := range left {
+		dot += float64(left[index]) * float64(right[index])
+	}
+	return dot
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(current rune) bool {
+		return !unicode.IsLetter(current) && !unicode.IsDigit(current)
+	})
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < minimumTokenLength {
+			continue
+		}
+		if _, stop := stopWords[field]; stop {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+func hashFeature(feature string) (int, float32) {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+	sign := float32(1)
+	if sum>>63 == 1 {
+		sign = -1
+	}
+	return int(sum % embeddingDimensions), sign
+}
+
+func normalize(vector Vector) Vector {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for index := range vector {
+		vector[index] *= scale
+	}
+	return vector
+}
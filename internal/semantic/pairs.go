@@ -0,0 +1,84 @@
+package semantic
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// Random-hyperplane hashing parameters: each band hashes a vector to the signs of its projections on
+// hyperplanesPerBand hyperplanes, and two vectors become a candidate pair when any band agrees. With 32 bands of
+// 8, a pair with similarity 0.9 is missed with a probability below 1 in 50,000, 0.8 below 1 in 250.
+const (
+	similarityBands    = 32
+	hyperplanesPerBand = 8
+	hyperplaneSeed     = 1
+)
+
+// Pair names two vectors by index, Left below Right.
+type Pair struct {
+	Left  int
+	Right int
+}
+
+// SimilarPairs returns the pairs of vectors whose cosine similarity is at least threshold, ordered by index.
+// Rather than comparing every pair, candidates are drawn by locality-sensitive hashing on random hyperplanes,
+// so the work grows with the number of similar pairs instead of quadratically; a pair just above a low
+// threshold may therefore rarely be missed. Nil vectors are skipped.
+func SimilarPairs(vectors []Vector, threshold float64) []Pair {
+	planes := hyperplanes(similarityBands*hyperplanesPerBand, embeddingDimensions)
+	buckets := make(map[[2]uint64][]int)
+	for index, vector := range vectors {
+		if len(vector) != embeddingDimensions {
+			continue
+		}
+		for band := range similarityBands {
+			var signature uint64
+			for row := range hyperplanesPerBand {
+				signature <<= 1
+				if Cosine(vector, planes[band*hyperplanesPerBand+row]) >= 0 {
+					signature |= 1
+				}
+			}
+			key := [2]uint64{uint64(band), signature}
+			buckets[key] = append(buckets[key], index)
+		}
+	}
+
+	compared := make(map[Pair]struct{})
+	var pairs []Pair
+	for _, members := range buckets {
+		for leftPosition, left := range members {
+			for _, right := range members[leftPosition+1:] {
+				pair := Pair{Left: left, Right: right}
+				if _, seen := compared[pair]; seen {
+					continue
+				}
+				compared[pair] = struct{}{}
+				if Cosine(vectors[left], vectors[right]) >= threshold {
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(left, right int) bool {
+		if pairs[left].Left != pairs[right].Left {
+			return pairs[left].Left < pairs[right].Left
+		}
+		return pairs[left].Right < pairs[right].Right
+	})
+	return pairs
+}
+
+// hyperplanes returns count normals drawn from a fixed seed, so the same vectors always pair up the same way.
+func hyperplanes(count int, dimensions int) []Vector {
+	generator := rand.New(rand.NewPCG(hyperplaneSeed, hyperplaneSeed))
+	planes := make([]Vector, count)
+	for index := range planes {
+		plane := make(Vector, dimensions)
+		for dimension := range plane {
+			plane[dimension] = float32(generator.NormFloat64())
+		}
+		planes[index] = plane
+	}
+	return planes
+}
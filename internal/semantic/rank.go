@@ -0,0 +1,46 @@
+package semantic
+
+import "sort"
+
+// Document is a conversation offered for semantic ranking.
+type Document struct {
+	ID         string
+	UpdateUnix int64
+	Text       string
+}
+
+// Scored pairs a document index with its similarity to the query.
+type Scored struct {
+	Index int
+	Score float64
+}
+
+// Ranker scores documents against a natural-language query using cached embeddings.
+type Ranker struct {
+	Embedder Embedder
+	Cache    *Cache
+}
+
+// Rank returns the documents scoring at least minimumScore, most similar first.
+func (ranker Ranker) Rank(query string, documents []Document, minimumScore float64) []Scored {
+	queryVector := ranker.Embedder.Embed(query)
+	var scored []Scored
+	for index, document := range documents {
+		vector := ranker.vectorFor(document)
+		score := Cosine(queryVector, vector)
+		if score >= minimumScore {
+			scored = append(scored, Scored{Index: index, Score: score})
+		}
+	}
+	sort.SliceStable(scored, func(left, right int) bool {
+		return scored[left].Score > scored[right].Score
+	})
+	return scored
+}
+
+func (ranker Ranker) vectorFor(document Document) Vector {
+	if ranker.Cache == nil || document.ID == "" {
+		return ranker.Embedder.Embed(document.Text)
+	}
+	return ranker.Cache.Lookup(cacheKey(document.ID, document.UpdateUnix), document.Text, ranker.Embedder)
+}
@@ -0,0 +1,263 @@
+// Package synthetic generates fake ChatGPT exports of any size, shaped like the real thing, for measuring how
+// fast exports are read, matched, and written.
+package synthetic
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	conversationsFileName = "conversations.json"
+	// DefaultConversations, DefaultMessages, and DefaultAttachments size an export like a few years of daily use.
+	DefaultConversations = 10000
+	DefaultMessages      = 12
+	DefaultAttachments   = 500
+	// minAttachmentBytes and maxAttachmentBytes bound the size of each fake image.
+	minAttachmentBytes = 32 << 10
+	maxAttachmentBytes = 512 << 10
+	wordsPerParagraph  = 60
+)
+
+// firstCreateTime is when the first synthetic conversation was held; the others follow a few hours apart.
+var firstCreateTime = time.Date(2023, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// vocabulary is the prose of every message.
+var vocabulary = strings.Fields(`the a of to and in is it that for on with as this be are you can use your when
+how what which from by not or if but we will have should more each file value return function error list type
+string number config build server request response client test data table query index cache memory thread
+deploy release version update change branch merge commit review issue bug fix feature module package import
+python golang javascript rust docker postgres linux network latency throughput benchmark profile`)
+
+// topicWords are mixed into a share of the conversations only, so patterns for them match predictable parts of
+// the export: "terraform" about one conversation in four, "tokenizer" about one in fifty.
+var topicWords = []struct {
+	word  string
+	share float64
+}{
+	{"terraform", 0.25},
+	{"kubernetes", 0.1},
+	{"tokenizer", 0.02},
+}
+
+// codeSnippets are the bodies of the fenced code blocks, by fence language.
+var codeSnippets = map[string]string{
+	"python":     "def handler(event):\n    items = [item for item in event['items'] if item]\n    return {'count': len(items)}\n",
+	"go":         "func handler(items []string) int {\n\tcount := 0\n\tfor _, item := range items {\n\t\tif item != \"\" {\n\t\t\tcount++\n\t\t}\n\t}\n\treturn count\n}\n",
+	"javascript": "export function handler(items) {\n  return items.filter(Boolean).length;\n}\n",
+	"rust":       "fn handler(items: &[&str]) -> usize {\n    items.iter().filter(|item| !item.is_empty()).count()\n}\n",
+	"sql":        "SELECT id, title FROM conversations WHERE update_time > now() - interval '7 days';\n",
+	"bash":       "for file in *.json; do\n  jq '.title' \"$file\"\ndone\n",
+}
+
+// codeLanguages orders the snippet languages, so generation does not depend on map iteration order.
+var codeLanguages = []string{"python", "go", "javascript", "rust", "sql", "bash"}
+
+// models are the model slugs conversations are spread over.
+var models = []string{"gpt-4o", "gpt-4o-mini", "o1", "gpt-4"}
+
+// Options sizes a synthetic export.
+type Options struct {
+	Conversations int
+	// Messages is the number of user and assistant messages in each conversation.
+	Messages int
+	// Attachments is the number of image files under files/, spread evenly over the conversations, each
+	// referenced by one user message.
+	Attachments int
+	// Seed makes the export reproducible: the same options write the same bytes.
+	Seed uint64
+}
+
+// Generate writes a synthetic export ZIP to path: conversations.json holding Options.Conversations conversations
+// of prose, some with fenced code, and Options.Attachments images under files/, stored uncompressed as real
+// exports store images.
+func Generate(path string, options Options) error {
+	if options.Conversations < 1 || options.Messages < 1 || options.Attachments < 0 {
+		return errors.New("a synthetic export needs at least one conversation and one message, and no negative attachment count")
+	}
+	file, createErr := os.Create(path)
+	if createErr != nil {
+		return fmt.Errorf("create synthetic export: %w", createErr)
+	}
+	archive := zip.NewWriter(file)
+	generator := &generator{options: options, random: rand.New(rand.NewPCG(options.Seed, options.Seed^0x9e3779b97f4a7c15))}
+	writeErr := generator.write(archive)
+	if joined := errors.Join(writeErr, archive.Close(), file.Close()); joined != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("write synthetic export: %w", joined)
+	}
+	return nil
+}
+
+type generator struct {
+	options Options
+	random  *rand.Rand
+}
+
+func (generator *generator) write(archive *zip.Writer) error {
+	attachments := generator.attachmentNames()
+	conversations, createErr := archive.Create(conversationsFileName)
+	if createErr != nil {
+		return createErr
+	}
+	if _, writeErr := conversations.Write([]byte("[")); writeErr != nil {
+		return writeErr
+	}
+	encoder := json.NewEncoder(conversations)
+	for ordinal := range generator.options.Conversations {
+		if ordinal > 0 {
+			if _, writeErr := conversations.Write([]byte(",")); writeErr != nil {
+				return writeErr
+			}
+		}
+		if encodeErr := encoder.Encode(generator.conversation(ordinal, attachments[ordinal])); encodeErr != nil {
+			return encodeErr
+		}
+	}
+	if _, writeErr := conversations.Write([]byte("]")); writeErr != nil {
+		return writeErr
+	}
+	for ordinal := range generator.options.Conversations {
+		for _, attachment := range attachments[ordinal] {
+			if writeErr := generator.writeAttachment(archive, attachment); writeErr != nil {
+				return writeErr
+			}
+		}
+	}
+	return nil
+}
+
+// attachment is one fake image and the conversation message referencing it.
+type attachment struct {
+	fileID string
+	name   string
+}
+
+// attachmentNames spreads the attachments evenly over the conversations, by conversation ordinal.
+func (generator *generator) attachmentNames() map[int][]attachment {
+	spread := make(map[int][]attachment)
+	for ordinal := range generator.options.Attachments {
+		owner := ordinal * generator.options.Conversations / generator.options.Attachments
+		spread[owner] = append(spread[owner], attachment{
+			fileID: fmt.Sprintf("file-%016x", generator.random.Uint64()),
+			name:   fmt.Sprintf("IMG_%04d.png", ordinal+1),
+		})
+	}
+	return spread
+}
+
+func (generator *generator) writeAttachment(archive *zip.Writer, image attachment) error {
+	writer, createErr := archive.CreateHeader(&zip.FileHeader{Name: "files/" + image.fileID + "-" + image.name, Method: zip.Store})
+	if createErr != nil {
+		return createErr
+	}
+	content := make([]byte, minAttachmentBytes+generator.random.IntN(maxAttachmentBytes-minAttachmentBytes))
+	for position := 0; position+8 <= len(content); position += 8 {
+		value := generator.random.Uint64()
+		for shift := range 8 {
+			content[position+shift] = byte(value >> (8 * shift))
+		}
+	}
+	_, writeErr := writer.Write(content)
+	return writeErr
+}
+
+// conversation builds one conversation as conversations.json stores it: a mapping tree of messages below a
+// root node, alternating between user and assistant along a single branch.
+func (generator *generator) conversation(ordinal int, images []attachment) map[string]any {
+	conversationID := fmt.Sprintf("%08x-0000-4000-8000-%012x", ordinal, generator.random.Uint64()>>16)
+	created := firstCreateTime.Add(time.Duration(ordinal) * 3 * time.Hour)
+	topics := generator.topics()
+	mapping := map[string]any{"root": map[string]any{"id": "root", "parent": nil, "children": []string{"m1"}, "message": nil}}
+	parent := "root"
+	for turn := 1; turn <= generator.options.Messages; turn++ {
+		nodeID := fmt.Sprintf("m%d", turn)
+		children := []string{}
+		if turn < generator.options.Messages {
+			children = []string{fmt.Sprintf("m%d", turn+1)}
+		}
+		role := "user"
+		if turn%2 == 0 {
+			role = "assistant"
+		}
+		var turnImages []attachment
+		if turn == 1 {
+			turnImages = images
+		}
+		mapping[nodeID] = map[string]any{
+			"id":       nodeID,
+			"parent":   parent,
+			"children": children,
+			"message":  generator.message(nodeID, role, created.Add(time.Duration(turn)*time.Minute), topics, turnImages),
+		}
+		parent = nodeID
+	}
+	return map[string]any{
+		"id":                 conversationID,
+		"conversation_id":    conversationID,
+		"title":              generator.sentence(4, topics),
+		"create_time":        float64(created.Unix()),
+		"update_time":        float64(created.Add(time.Duration(generator.options.Messages+1) * time.Minute).Unix()),
+		"current_node":       parent,
+		"default_model_slug": models[ordinal%len(models)],
+		"is_archived":        ordinal%20 == 0,
+		"mapping":            mapping,
+	}
+}
+
+func (generator *generator) message(nodeID string, role string, written time.Time, topics []string, images []attachment) map[string]any {
+	text := generator.sentence(wordsPerParagraph, topics)
+	if role == "assistant" && generator.random.IntN(3) == 0 {
+		language := codeLanguages[generator.random.IntN(len(codeLanguages))]
+		text += "\n\n```" + language + "\n" + codeSnippets[language] + "```\n\n" + generator.sentence(wordsPerParagraph/3, topics)
+	}
+	content := map[string]any{"content_type": "text", "parts": []any{text}}
+	metadata := map[string]any{}
+	if len(images) > 0 {
+		parts := make([]any, 0, len(images)+1)
+		references := make([]any, 0, len(images))
+		for _, image := range images {
+			parts = append(parts, map[string]any{"content_type": "image_asset_pointer", "asset_pointer": "file-service://" + image.fileID})
+			references = append(references, map[string]any{"id": image.fileID, "name": image.name})
+		}
+		content = map[string]any{"content_type": "multimodal_text", "parts": append(parts, text)}
+		metadata["attachments"] = references
+	}
+	return map[string]any{
+		"id":          nodeID,
+		"author":      map[string]any{"role": role},
+		"create_time": float64(written.Unix()),
+		"content":     content,
+		"metadata":    metadata,
+	}
+}
+
+// topics picks the topic words of one conversation.
+func (generator *generator) topics() []string {
+	var picked []string
+	for _, topic := range topicWords {
+		if generator.random.Float64() < topic.share {
+			picked = append(picked, topic.word)
+		}
+	}
+	return picked
+}
+
+// sentence strings together count words of the vocabulary, with the conversation's topic words mixed in.
+func (generator *generator) sentence(count int, topics []string) string {
+	words := make([]string, count)
+	for position := range words {
+		if len(topics) > 0 && generator.random.IntN(20) == 0 {
+			words[position] = topics[generator.random.IntN(len(topics))]
+			continue
+		}
+		words[position] = vocabulary[generator.random.IntN(len(vocabulary))]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,71 @@
+package utils
+
+import "strings"
+
+const codeFence = "```"
+
+// CodeBlock is a fenced code block found in message text.
+type CodeBlock struct {
+	Language string
+	Body     string
+}
+
+// FindCodeBlocks returns the fenced code blocks contained in text, in order of appearance.
+func FindCodeBlocks(text string) []CodeBlock {
+	blocks, _ := scanCodeBlocks(text)
+	return blocks
+}
+
+// StripCodeBlocks returns text with every fenced code block removed.
+func StripCodeBlocks(text string) string {
+	_, prose := scanCodeBlocks(text)
+	return prose
+}
+
+func scanCodeBlocks(text string) ([]CodeBlock, string) {
+	var blocks []CodeBlock
+	var bodyLines []string
+	var proseLines []string
+	insideBlock := false
+	language := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !insideBlock {
+			if strings.HasPrefix(trimmed, codeFence) {
+				insideBlock = true
+				language = strings.TrimSpace(strings.TrimPrefix(trimmed, codeFence))
+				if fields := strings.Fields(language); len(fields) > 0 {
+					language = fields[0]
+				}
+				bodyLines = bodyLines[:0]
+				continue
+			}
+			proseLines = append(proseLines, line)
+			continue
+		}
+		if trimmed == codeFence {
+			blocks = append(blocks, CodeBlock{Language: language, Body: strings.Join(bodyLines, "\n")})
+			insideBlock = false
+			continue
+		}
+		bodyLines = append(bodyLines, line)
+	}
+	return blocks, strings.Join(proseLines, "\n")
+}
+
+// CodeBlockLines reports for each line of text whether it belongs to a fenced code block, fences included.
+func CodeBlockLines(text string) []bool {
+	lines := strings.Split(text, "\n")
+	fenced := make([]bool, len(lines))
+	insideBlock := false
+	for index, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		fenced[index] = insideBlock || strings.HasPrefix(trimmed, codeFence)
+		if insideBlock {
+			insideBlock = trimmed != codeFence
+		} else {
+			insideBlock = strings.HasPrefix(trimmed, codeFence)
+		}
+	}
+	return fenced
+}
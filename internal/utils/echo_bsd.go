@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package utils
+
+import "golang.org/x/sys/unix"
+
+const (
+	getTermiosRequest = unix.TIOCGETA
+	setTermiosRequest = unix.TIOCSETA
+)
@@ -0,0 +1,8 @@
+package utils
+
+import "golang.org/x/sys/unix"
+
+const (
+	getTermiosRequest = unix.TCGETS
+	setTermiosRequest = unix.TCSETS
+)
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package utils
+
+// disableEcho is unavailable on this platform, so passwords must be passed on the command line or environment.
+func disableEcho(int) (func(), error) {
+	return nil, ErrNoTerminal
+}
+
+// IsTerminal cannot tell terminals apart on this platform and reports none.
+func IsTerminal(int) bool {
+	return false
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package utils
+
+import "golang.org/x/sys/unix"
+
+// disableEcho turns off terminal echo on fd and returns a function restoring the previous settings.
+func disableEcho(fd int) (func(), error) {
+	original, getErr := unix.IoctlGetTermios(fd, getTermiosRequest)
+	if getErr != nil {
+		return nil, ErrNoTerminal
+	}
+	silent := *original
+	silent.Lflag &^= unix.ECHO
+	silent.Lflag |= unix.ICANON | unix.ISIG
+	if setErr := unix.IoctlSetTermios(fd, setTermiosRequest, &silent); setErr != nil {
+		return nil, setErr
+	}
+	return func() { _ = unix.IoctlSetTermios(fd, setTermiosRequest, original) }, nil
+}
+
+// IsTerminal reports whether fd is an interactive terminal.
+func IsTerminal(fd int) bool {
+	_, getErr := unix.IoctlGetTermios(fd, getTermiosRequest)
+	return getErr == nil
+}
@@ -1,11 +1,30 @@
 package utils
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 )
 
+const (
+	commentPrefix = "#"
+	// copyBufferSize is the size of the buffers CopyToFile streams through.
+	copyBufferSize = 256 << 10
+)
+
+// copyBuffers are reused across copies, which may run concurrently.
+var copyBuffers = sync.Pool{New: func() any {
+	buffer := make([]byte, copyBufferSize)
+	return &buffer
+}}
+
 func EnsureDir(dirPath string) error {
 	return os.MkdirAll(dirPath, 0o755)
 }
@@ -17,18 +36,112 @@ func WriteFile(path string, data []byte) error {
 	return nil
 }
 
-func WritePrettyJSON(path string, raw []byte) error {
-	var tmp any
-	if err := json.Unmarshal(raw, &tmp); err != nil {
-		return fmt.Errorf("validate json for %q: %w", path, err)
+// WriteFileAtomic replaces path with data through a temporary file and rename, so readers never see a partial file.
+func WriteFileAtomic(path string, data []byte) error {
+	return WriteFileAtomicStream(path, func(destination io.Writer) error {
+		_, writeErr := destination.Write(data)
+		return writeErr
+	})
+}
+
+// WriteFileAtomicStream replaces path with what write writes, as WriteFileAtomic does, for content produced
+// as a stream. A failed write leaves path untouched and removes the temporary file.
+func WriteFileAtomicStream(path string, write func(destination io.Writer) error) error {
+	return ReplaceFileAtomic(path, func(temporaryPath string) error {
+		temporary, openErr := os.OpenFile(temporaryPath, os.O_WRONLY|os.O_TRUNC, 0)
+		if openErr != nil {
+			return openErr
+		}
+		writeErr := write(temporary)
+		return errors.Join(writeErr, temporary.Close())
+	})
+}
+
+// ReplaceFileAtomic replaces path with the file build creates at temporaryPath, an empty file beside path, for
+// content written by code that opens files by name. A failed build leaves path untouched and removes the
+// temporary file.
+func ReplaceFileAtomic(path string, build func(temporaryPath string) error) error {
+	if mkErr := EnsureDir(filepath.Dir(path)); mkErr != nil {
+		return fmt.Errorf("write %q: %w", path, mkErr)
+	}
+	temporary, createErr := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if createErr != nil {
+		return fmt.Errorf("write %q: %w", path, createErr)
+	}
+	temporaryPath := temporary.Name()
+	if closeErr := temporary.Close(); closeErr != nil {
+		_ = os.Remove(temporaryPath)
+		return fmt.Errorf("write %q: %w", path, closeErr)
+	}
+	if joined := errors.Join(build(temporaryPath), os.Chmod(temporaryPath, 0o644)); joined != nil {
+		_ = os.Remove(temporaryPath)
+		return fmt.Errorf("write %q: %w", path, joined)
+	}
+	if renameErr := os.Rename(temporaryPath, path); renameErr != nil {
+		_ = os.Remove(temporaryPath)
+		return fmt.Errorf("write %q: %w", path, renameErr)
+	}
+	return nil
+}
+
+// CopyToFile streams source into a new file at path through a pooled buffer, so large files are never held whole.
+func CopyToFile(path string, source io.Reader) error {
+	file, createErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if createErr != nil {
+		return fmt.Errorf("write %q: %w", path, createErr)
+	}
+	buffer := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buffer)
+	if _, copyErr := io.CopyBuffer(file, source, *buffer); copyErr != nil {
+		file.Close()
+		return fmt.Errorf("write %q: %w", path, copyErr)
 	}
-	pretty, err := json.MarshalIndent(tmp, "", "  ")
+	if closeErr := file.Close(); closeErr != nil {
+		return fmt.Errorf("write %q: %w", path, closeErr)
+	}
+	return nil
+}
+
+func WritePrettyJSON(path string, raw []byte) error {
+	pretty, err := PrettyJSON(raw)
 	if err != nil {
-		return fmt.Errorf("pretty-print json for %q: %w", path, err)
+		return fmt.Errorf("%q: %w", path, err)
 	}
 	return WriteFile(path, pretty)
 }
 
+// PrettyJSON validates raw JSON and returns it indented with two spaces. The document is reindented as it
+// is, without decoding it, so key order and string escapes come through unchanged.
+func PrettyJSON(raw []byte) ([]byte, error) {
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+		return nil, fmt.Errorf("validate json: %w", err)
+	}
+	return pretty.Bytes(), nil
+}
+
 func PrintLine(line string) {
 	fmt.Println(line)
 }
+
+// ReadListFile returns the trimmed non-empty lines of a text file, skipping lines starting with #.
+func ReadListFile(path string) ([]string, error) {
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		return nil, fmt.Errorf("open %q: %w", path, openErr)
+	}
+	defer file.Close()
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, commentPrefix) {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, fmt.Errorf("read %q: %w", path, scanErr)
+	}
+	return entries, nil
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicStream(t *testing.T) {
+	const original = "original"
+	testCases := []struct {
+		name            string
+		write           func(destination io.Writer) error
+		expectErr       bool
+		expectedContent string
+	}{
+		{
+			name: "replaces the file",
+			write: func(destination io.Writer) error {
+				_, writeErr := io.WriteString(destination, "replaced")
+				return writeErr
+			},
+			expectedContent: "replaced",
+		},
+		{
+			name: "failed write keeps the file",
+			write: func(destination io.Writer) error {
+				_, _ = io.WriteString(destination, "partial")
+				return errors.New("interrupted")
+			},
+			expectErr:       true,
+			expectedContent: original,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			folder := t.TempDir()
+			path := filepath.Join(folder, "state.json")
+			if writeErr := os.WriteFile(path, []byte(original), 0o600); writeErr != nil {
+				t.Fatalf("WriteFile: %v", writeErr)
+			}
+			streamErr := WriteFileAtomicStream(path, testCase.write)
+			if (streamErr != nil) != testCase.expectErr {
+				t.Fatalf("WriteFileAtomicStream error = %v, want error %v", streamErr, testCase.expectErr)
+			}
+			content, readErr := os.ReadFile(path)
+			if readErr != nil {
+				t.Fatalf("ReadFile: %v", readErr)
+			}
+			if string(content) != testCase.expectedContent {
+				t.Errorf("content = %q, want %q", content, testCase.expectedContent)
+			}
+			entries, _ := os.ReadDir(folder)
+			if len(entries) != 1 {
+				t.Errorf("folder holds %d files, want only the target", len(entries))
+			}
+			info, statErr := os.Stat(path)
+			if statErr == nil && !testCase.expectErr && info.Mode().Perm() != 0o644 {
+				t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// unicodeEscapeLength is the length of one \uXXXX escape.
+	unicodeEscapeLength = 6
+	// firstPrintable is the first character an escape is decoded to; control characters stay escaped, as
+	// encoders write them whether or not they escape the rest.
+	firstPrintable = 0x20
+)
+
+var unicodeEscapePrefix = []byte(`\u`)
+
+// UnescapeJSONUnicode returns serialized JSON with its \uXXXX escapes, surrogate pairs included, replaced by the
+// UTF-8 characters they stand for, so text written by encoders that escape everything beyond ASCII, as Python's
+// does by default, or that escape HTML characters, can be matched as it reads. Escaped control characters,
+// lone surrogates, and the other escapes stay as they are. serialized itself is returned when it holds no \u.
+func UnescapeJSONUnicode(serialized []byte) []byte {
+	if !bytes.Contains(serialized, unicodeEscapePrefix) {
+		return serialized
+	}
+	unescaped := make([]byte, 0, len(serialized))
+	for position := 0; position < len(serialized); {
+		current := serialized[position]
+		if current != '\\' || position+1 == len(serialized) {
+			unescaped = append(unescaped, current)
+			position++
+			continue
+		}
+		character, width := decodeUnicodeEscape(serialized[position:])
+		if width == 0 {
+			unescaped = append(unescaped, current, serialized[position+1])
+			position += 2
+			continue
+		}
+		unescaped = utf8.AppendRune(unescaped, character)
+		position += width
+	}
+	return unescaped
+}
+
+// decodeUnicodeEscape decodes the \uXXXX escape, or the surrogate pair of escapes, escaped starts with. It
+// returns the character and the bytes its escapes span, or zero bytes when escaped starts with another escape
+// or one left escaped.
+func decodeUnicodeEscape(escaped []byte) (rune, int) {
+	first, isEscape := hexEscape(escaped)
+	if !isEscape || first < firstPrintable {
+		return 0, 0
+	}
+	if !utf16.IsSurrogate(first) {
+		return first, unicodeEscapeLength
+	}
+	second, isPair := hexEscape(escaped[unicodeEscapeLength:])
+	if !isPair {
+		return 0, 0
+	}
+	combined := utf16.DecodeRune(first, second)
+	if combined == utf8.RuneError {
+		return 0, 0
+	}
+	return combined, 2 * unicodeEscapeLength
+}
+
+// hexEscape parses the \uXXXX escape escaped starts with.
+func hexEscape(escaped []byte) (rune, bool) {
+	if len(escaped) < unicodeEscapeLength || !bytes.HasPrefix(escaped, unicodeEscapePrefix) {
+		return 0, false
+	}
+	value := rune(0)
+	for _, digit := range escaped[2:unicodeEscapeLength] {
+		switch {
+		case digit >= '0' && digit <= '9':
+			value = value<<4 | rune(digit-'0')
+		case digit >= 'a' && digit <= 'f':
+			value = value<<4 | rune(digit-'a'+10)
+		case digit >= 'A' && digit <= 'F':
+			value = value<<4 | rune(digit-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestUnescapeJSONUnicode(t *testing.T) {
+	testCases := []struct {
+		name       string
+		serialized string
+		expected   string
+	}{
+		{name: "no escapes", serialized: `{"text":"café"}`, expected: `{"text":"café"}`},
+		{name: "latin escape", serialized: `{"text":"caf\u00e9"}`, expected: `{"text":"café"}`},
+		{name: "upper case hex", serialized: `"O\u00F9 ?"`, expected: `"Où ?"`},
+		{name: "html escapes", serialized: `"\u003cb\u003ebold\u003c/b\u003e"`, expected: `"<b>bold</b>"`},
+		{name: "surrogate pair", serialized: `"smile \ud83d\ude00"`, expected: `"smile 😀"`},
+		{name: "lone surrogate kept", serialized: `"\ud83d!"`, expected: `"\ud83d!"`},
+		{name: "control character kept", serialized: `"a\u000ab"`, expected: `"a\u000ab"`},
+		{name: "escaped backslash before u kept", serialized: `"C:\\u00e9"`, expected: `"C:\\u00e9"`},
+		{name: "other escapes kept", serialized: `"line\nquote\" \u00e9"`, expected: `"line\nquote\" é"`},
+		{name: "truncated escape kept", serialized: `"\u00e`, expected: `"\u00e`},
+		{name: "invalid hex kept", serialized: `"\u00zz"`, expected: `"\u00zz"`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if unescaped := string(UnescapeJSONUnicode([]byte(testCase.serialized))); unescaped != testCase.expected {
+				t.Errorf("UnescapeJSONUnicode(%s) = %s, want %s", testCase.serialized, unescaped, testCase.expected)
+			}
+		})
+	}
+}
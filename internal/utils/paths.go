@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// unsafeNameCharacters cannot appear in a file name written from archive content: separators and drive
+// markers could redirect the write, and NUL truncates names on some platforms.
+const unsafeNameCharacters = "/\\:\x00"
+
+// SafeArchiveFileName returns the base name an archive entry may be written under. It rejects entries whose
+// path is absolute, on any platform, or climbs with "..", and base names that are empty, dot names, or contain
+// separators.
+func SafeArchiveFileName(archivePath string) (string, error) {
+	if strings.HasPrefix(archivePath, "/") || strings.HasPrefix(archivePath, "\\") || filepath.VolumeName(archivePath) != "" || hasDriveLetter(archivePath) {
+		return "", fmt.Errorf("unsafe archive path %q: absolute", archivePath)
+	}
+	for _, segment := range strings.FieldsFunc(archivePath, func(current rune) bool { return current == '/' || current == '\\' }) {
+		if segment == ".." {
+			return "", fmt.Errorf("unsafe archive path %q: parent directory reference", archivePath)
+		}
+	}
+	baseName := path.Base(archivePath)
+	if baseName == "" || baseName == "." || baseName == ".." || baseName == "/" || strings.ContainsAny(baseName, unsafeNameCharacters) {
+		return "", fmt.Errorf("unsafe archive path %q: invalid file name", archivePath)
+	}
+	return baseName, nil
+}
+
+// hasDriveLetter reports whether archivePath starts with a Windows drive such as C:, which filepath.VolumeName
+// recognizes only on Windows.
+func hasDriveLetter(archivePath string) bool {
+	return len(archivePath) >= 2 && archivePath[1] == ':' && ('a' <= archivePath[0] && archivePath[0] <= 'z' || 'A' <= archivePath[0] && archivePath[0] <= 'Z')
+}
+
+// ContainedPath joins name onto root and fails if the result would resolve outside root.
+func ContainedPath(root string, name string) (string, error) {
+	joined := filepath.Join(root, name)
+	relative, relErr := filepath.Rel(root, joined)
+	if relErr != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) || filepath.IsAbs(relative) {
+		return "", fmt.Errorf("path %q escapes %q", name, root)
+	}
+	return joined, nil
+}
+
+const slugMaxRunes = 60
+
+// Slugify turns a title into a lowercase, dash-separated name safe for a folder: letters and digits are kept,
+// every other run of characters becomes one dash, and the result is cut at a dash to at most 60 characters.
+func Slugify(title string) string {
+	var builder strings.Builder
+	pendingDash := false
+	for _, current := range strings.ToLower(title) {
+		if !unicode.IsLetter(current) && !unicode.IsDigit(current) {
+			pendingDash = builder.Len() > 0
+			continue
+		}
+		if pendingDash {
+			builder.WriteRune('-')
+			pendingDash = false
+		}
+		builder.WriteRune(current)
+	}
+	slug := []rune(builder.String())
+	if len(slug) <= slugMaxRunes {
+		return string(slug)
+	}
+	cut := string(slug[:slugMaxRunes])
+	if dash := strings.LastIndex(cut, "-"); dash > 0 {
+		cut = cut[:dash]
+	}
+	return cut
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeArchiveFileName(t *testing.T) {
+	testCases := []struct {
+		name         string
+		archivePath  string
+		expectedName string
+		expectErr    bool
+	}{
+		{name: "plain file", archivePath: "file-abc123-photo.png", expectedName: "file-abc123-photo.png"},
+		{name: "nested file", archivePath: "files/dalle/image.webp", expectedName: "image.webp"},
+		{name: "dot segment", archivePath: "files/./image.webp", expectedName: "image.webp"},
+		{name: "climbing path", archivePath: "files/../../evil", expectErr: true},
+		{name: "leading parent", archivePath: "../evil.png", expectErr: true},
+		{name: "absolute path", archivePath: "/etc/passwd", expectErr: true},
+		{name: "absolute backslash path", archivePath: `\Windows\evil.dll`, expectErr: true},
+		{name: "drive backslash path", archivePath: `C:\Windows\evil.dll`, expectErr: true},
+		{name: "drive slash path", archivePath: "C:/Windows/evil.dll", expectErr: true},
+		{name: "drive relative path", archivePath: "c:evil.dll", expectErr: true},
+		{name: "backslash climbing path", archivePath: `files\..\..\evil`, expectErr: true},
+		{name: "backslash in base name", archivePath: `files\evil.png`, expectErr: true},
+		{name: "nul in base name", archivePath: "files/evil\x00.png", expectErr: true},
+		{name: "colon in base name", archivePath: "files/evil:stream", expectErr: true},
+		{name: "parent base name", archivePath: "files/..", expectErr: true},
+		{name: "dot base name", archivePath: "files/.", expectErr: true},
+		{name: "trailing slash", archivePath: "files/", expectedName: "files"},
+		{name: "empty path", archivePath: "", expectErr: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fileName, nameErr := SafeArchiveFileName(testCase.archivePath)
+			if testCase.expectErr {
+				if nameErr == nil {
+					t.Fatalf("SafeArchiveFileName(%q) = %q, want an error", testCase.archivePath, fileName)
+				}
+				return
+			}
+			if nameErr != nil {
+				t.Fatalf("SafeArchiveFileName(%q): %v", testCase.archivePath, nameErr)
+			}
+			if fileName != testCase.expectedName {
+				t.Errorf("SafeArchiveFileName(%q) = %q, want %q", testCase.archivePath, fileName, testCase.expectedName)
+			}
+		})
+	}
+}
+
+func TestContainedPath(t *testing.T) {
+	root := t.TempDir()
+	testCases := []struct {
+		name         string
+		fileName     string
+		expectedPath string
+		expectErr    bool
+	}{
+		{name: "file in root", fileName: "image.png", expectedPath: filepath.Join(root, "image.png")},
+		{name: "nested file", fileName: "files/image.png", expectedPath: filepath.Join(root, "files", "image.png")},
+		{name: "climb back inside", fileName: "files/../image.png", expectedPath: filepath.Join(root, "image.png")},
+		{name: "parent", fileName: "..", expectErr: true},
+		{name: "climbing path", fileName: "files/../../evil", expectErr: true},
+		{name: "sibling with root prefix", fileName: "../" + filepath.Base(root) + "-evil/x", expectErr: true},
+		{name: "parent-like name", fileName: "..evil", expectedPath: filepath.Join(root, "..evil")},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			containedPath, containErr := ContainedPath(root, testCase.fileName)
+			if testCase.expectErr {
+				if containErr == nil {
+					t.Fatalf("ContainedPath(%q) = %q, want an error", testCase.fileName, containedPath)
+				}
+				return
+			}
+			if containErr != nil {
+				t.Fatalf("ContainedPath(%q): %v", testCase.fileName, containErr)
+			}
+			if containedPath != testCase.expectedPath {
+				t.Errorf("ContainedPath(%q) = %q, want %q", testCase.fileName, containedPath, testCase.expectedPath)
+			}
+		})
+	}
+}
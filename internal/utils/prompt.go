@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNoTerminal is returned by PromptPassword when stdin is not an interactive terminal.
+var ErrNoTerminal = errors.New("stdin is not a terminal")
+
+// PromptPassword writes prompt to stderr and reads one line from the terminal without echoing it.
+func PromptPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+	restore, echoErr := disableEcho(int(os.Stdin.Fd()))
+	if echoErr != nil {
+		return "", echoErr
+	}
+	defer restore()
+	line, readErr := bufio.NewReader(os.Stdin).ReadString('\n')
+	if readErr != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", readErr)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
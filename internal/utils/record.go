@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"time"
+)
+
+const partSeparator = "\n"
+
+// Message is a flattened view of one message in a conversation mapping.
+type Message struct {
+	ID          string
+	Role        string
+	AuthorName  string
+	Recipient   string
+	ContentType string
+	// Language is the language of a code message, as recorded by the export.
+	Language string
+	Text     string
+	// Reasoning holds the reasoning summaries of a reasoning model's "thoughts" message, which has no Text.
+	Reasoning  string
+	CreateTime time.Time
+	Metadata   map[string]any
+	Assets     []map[string]any
+	// Branch labels messages off the active branch when alternatives are rendered; empty on the active branch.
+	Branch string
+}
+
+var dialogueRoles = map[string]struct{}{
+	"user":      {},
+	"assistant": {},
+}
+
+const (
+	hiddenMessageMetadata = "is_visually_hidden_from_conversation"
+	broadcastRecipient    = "all"
+)
+
+var hiddenContentTypes = map[string]struct{}{
+	"user_editable_context":  {},
+	"model_editable_context": {},
+	"thoughts":               {},
+	"reasoning_recap":        {},
+	"system_error":           {},
+}
+
+// IsVisible reports whether the ChatGPT UI shows the message: a user or assistant message addressed to
+// everyone, not flagged as hidden, and not an internal context or reasoning payload.
+func IsVisible(message Message) bool {
+	if _, dialogue := dialogueRoles[message.Role]; !dialogue {
+		return false
+	}
+	if message.Recipient != "" && message.Recipient != broadcastRecipient {
+		return false
+	}
+	if hidden, _ := message.Metadata[hiddenMessageMetadata].(bool); hidden {
+		return false
+	}
+	_, internal := hiddenContentTypes[message.ContentType]
+	return !internal
+}
+
+// MessagesFrom keeps the messages authored by role, in order.
+func MessagesFrom(messages []Message, role string) []Message {
+	kept := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == role {
+			kept = append(kept, message)
+		}
+	}
+	return kept
+}
+
+// VisibleMessages keeps the messages the ChatGPT UI shows, in order.
+func VisibleMessages(messages []Message) []Message {
+	kept := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		if IsVisible(message) {
+			kept = append(kept, message)
+		}
+	}
+	return kept
+}
+
+// CountDialogueMessages counts the user and assistant messages, ignoring system and tool chatter.
+func CountDialogueMessages(messages []Message) int {
+	count := 0
+	for _, message := range messages {
+		if _, dialogue := dialogueRoles[message.Role]; dialogue {
+			count++
+		}
+	}
+	return count
+}
+
+// ConversationSpan returns the time between the first and last timestamped messages.
+func ConversationSpan(messages []Message) time.Duration {
+	var first, last time.Time
+	for _, message := range messages {
+		if message.CreateTime.IsZero() {
+			continue
+		}
+		if first.IsZero() || message.CreateTime.Before(first) {
+			first = message.CreateTime
+		}
+		if message.CreateTime.After(last) {
+			last = message.CreateTime
+		}
+	}
+	return last.Sub(first)
+}
@@ -3,14 +3,81 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-func CompileUserPattern(user string) (*regexp.Regexp, error) {
-	if looksLikeRegex(user) {
-		return regexp.Compile(user)
+const wordBoundary = `\b`
+
+// PatternSyntax selects how a user pattern is interpreted.
+type PatternSyntax string
+
+const (
+	// SyntaxAuto guesses from the pattern's characters whether it is a regular expression.
+	SyntaxAuto PatternSyntax = ""
+	// SyntaxRegex always compiles the pattern as a regular expression.
+	SyntaxRegex PatternSyntax = "regex"
+	// SyntaxLiteral always matches the pattern as plain text.
+	SyntaxLiteral PatternSyntax = "literal"
+)
+
+// syntaxPrefixes override the configured syntax for a single pattern.
+var syntaxPrefixes = map[string]PatternSyntax{
+	"re:":  SyntaxRegex,
+	"lit:": SyntaxLiteral,
+}
+
+// PatternOptions adjusts how plain-text user patterns are turned into expressions.
+type PatternOptions struct {
+	WholeWord     bool
+	CaseSensitive bool
+	Syntax        PatternSyntax
+}
+
+// CompileUserPattern compiles a user pattern. A leading "re:" or "lit:" forces regex or literal
+// interpretation; otherwise options.Syntax decides, guessing from the pattern when it is SyntaxAuto. Unless
+// options.CaseSensitive is set, the expression folds case itself, Unicode included, so text is matched as it is
+// instead of through a lowered copy.
+func CompileUserPattern(user string, options PatternOptions) (*regexp.Regexp, error) {
+	syntax, body := splitSyntaxPrefix(user, options.Syntax)
+	if syntax == SyntaxAuto && looksLikeRegex(body) {
+		syntax = SyntaxRegex
+	}
+	expression := body
+	if syntax != SyntaxRegex {
+		expression = literalExpression(body, options)
+	}
+	if options.CaseSensitive {
+		return regexp.Compile(expression)
+	}
+	return regexp.Compile("(?i)" + expression)
+}
+
+func literalExpression(user string, options PatternOptions) string {
+	quoted := regexp.QuoteMeta(user)
+	if !options.WholeWord || user == "" {
+		return quoted
+	}
+	if isWordByte(user[0]) {
+		quoted = wordBoundary + quoted
 	}
-	// plain string => case-insensitive literal
-	return regexp.Compile("(?i)" + regexp.QuoteMeta(user))
+	if isWordByte(user[len(user)-1]) {
+		quoted += wordBoundary
+	}
+	return quoted
+}
+
+func isWordByte(current byte) bool {
+	return current == '_' || (current >= '0' && current <= '9') || (current >= 'a' && current <= 'z') || (current >= 'A' && current <= 'Z')
+}
+
+func splitSyntaxPrefix(user string, fallback PatternSyntax) (PatternSyntax, string) {
+	for prefix, syntax := range syntaxPrefixes {
+		if strings.HasPrefix(user, prefix) {
+			return syntax, strings.TrimPrefix(user, prefix)
+		}
+	}
+	return fallback, user
 }
 
 func looksLikeRegex(s string) bool {
@@ -26,15 +93,26 @@ func looksLikeRegex(s string) bool {
 	return false
 }
 
-func BytesToLower(src []byte) []byte {
-	dst := make([]byte, len(src))
-	for index := 0; index < len(src); index++ {
+// AppendLower appends src to dst lowered rune by rune as strings.ToLower lowers it, except that invalid UTF-8
+// is copied unchanged, so callers can lower into a reused scratch buffer instead of a new copy each time.
+func AppendLower(dst []byte, src []byte) []byte {
+	for index := 0; index < len(src); {
 		current := src[index]
-		if current >= 'A' && current <= 'Z' {
-			dst[index] = current + 32
+		if current < utf8.RuneSelf {
+			if current >= 'A' && current <= 'Z' {
+				current += 'a' - 'A'
+			}
+			dst = append(dst, current)
+			index++
+			continue
+		}
+		decoded, size := utf8.DecodeRune(src[index:])
+		if decoded == utf8.RuneError && size == 1 {
+			dst = append(dst, current)
 		} else {
-			dst[index] = current
+			dst = utf8.AppendRune(dst, unicode.ToLower(decoded))
 		}
+		index += size
 	}
 	return dst
 }
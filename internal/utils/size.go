@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteSizeUnits maps size suffixes to multipliers; both KB and KiB mean 1024 bytes, as memory sizes usually do.
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30},
+	{"b", 1},
+}
+
+// ParseByteSize parses sizes such as "512MB", "1.5GiB", "64k", or a bare byte count.
+func ParseByteSize(text string) (int64, error) {
+	normalized := ToLowerTrim(text)
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(normalized, unit.suffix) {
+			normalized = strings.TrimSpace(strings.TrimSuffix(normalized, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	value, parseErr := strconv.ParseFloat(normalized, 64)
+	if parseErr != nil || value < 0 {
+		return 0, fmt.Errorf("parse %q: expected a size such as 512MB or 2GB", text)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatByteSize renders a byte count for reading, in the largest 1024-based unit it reaches: "3 B", "1.5 MB".
+func FormatByteSize(size int64) string {
+	units := []string{"KB", "MB", "GB", "TB"}
+	if size < 1<<10 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / (1 << 10)
+	unit := 0
+	for value >= 1<<10 && unit < len(units)-1 {
+		value /= 1 << 10
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -3,36 +3,43 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"math"
 	"time"
 )
 
-func ExtractCreateTime(record map[string]any) time.Time {
-	candidateKeys := []string{"create_time", "createTime", "create-time", "start_time"}
-	for _, key := range candidateKeys {
-		rawValue, exists := record[key]
-		if !exists {
-			continue
+// ParseTimestamp converts an export timestamp (epoch seconds as number or string, or RFC3339) into a time.
+func ParseTimestamp(rawValue any) (time.Time, bool) {
+	switch typed := rawValue.(type) {
+	case float64:
+		if typed > 0 {
+			whole, fraction := math.Modf(typed)
+			return time.Unix(int64(whole), int64(fraction*float64(time.Second))), true
 		}
-		switch typed := rawValue.(type) {
-		case float64:
-			seconds := int64(typed)
-			if seconds > 0 {
-				return time.Unix(seconds, 0)
-			}
-		case string:
-			if parsed, err := time.Parse(time.RFC3339, typed); err == nil {
-				return parsed
-			}
-			if seconds, err := parseInt64Strict(typed); err == nil && seconds > 0 {
-				return time.Unix(seconds, 0)
-			}
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, typed); err == nil {
+			return parsed, true
+		}
+		if seconds, err := parseInt64Strict(typed); err == nil && seconds > 0 {
+			return time.Unix(seconds, 0), true
 		}
 	}
-	return time.Now()
+	return time.Time{}, false
 }
 
-func FormatDatestamp(t time.Time) string {
-	return t.Format("010206-1504")
+// ParseDateBound parses an RFC3339 timestamp or a YYYY-MM-DD date in local time; a date-only value
+// resolves to the first instant of that day, or to its last instant when endOfDay is set.
+func ParseDateBound(text string, endOfDay bool) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, text); err == nil {
+		return parsed, nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, text, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse %q: expected RFC3339 or YYYY-MM-DD", text)
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
 }
 
 func parseInt64Strict(s string) (int64, error) {
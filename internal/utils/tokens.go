@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+const charactersPerToken = 4
+
+// CountWords counts whitespace-separated words in text.
+func CountWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// EstimateTokens approximates a BPE tokenizer: letter and digit runs cost one token per four characters,
+// every punctuation or symbol rune costs one token, and ideographic runes cost one token each.
+func EstimateTokens(text string) int {
+	tokens := 0
+	runLength := 0
+	flushRun := func() {
+		if runLength > 0 {
+			tokens += (runLength + charactersPerToken - 1) / charactersPerToken
+			runLength = 0
+		}
+	}
+	for _, current := range text {
+		switch {
+		case unicode.Is(unicode.Han, current) || unicode.Is(unicode.Hiragana, current) || unicode.Is(unicode.Katakana, current) || unicode.Is(unicode.Hangul, current):
+			flushRun()
+			tokens++
+		case unicode.IsLetter(current) || unicode.IsDigit(current):
+			runLength++
+		case unicode.IsSpace(current):
+			flushRun()
+		default:
+			flushRun()
+			tokens++
+		}
+	}
+	flushRun()
+	return tokens
+}
+
+// DialogueText joins the text of user and assistant messages.
+func DialogueText(messages []Message) string {
+	var texts []string
+	for _, message := range messages {
+		if _, dialogue := dialogueRoles[message.Role]; dialogue {
+			texts = append(texts, message.Text)
+		}
+	}
+	return strings.Join(texts, partSeparator)
+}
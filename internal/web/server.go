@@ -0,0 +1,288 @@
+// Package web serves a local, browsable mirror of loaded conversations: a search page with date filters, a
+// conversation viewer, and export of selected conversations as a ZIP download.
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	resultLimit         = 200
+	exportFileName      = "conversations.zip"
+	exportTempPattern   = "openai_extract-web-*.zip"
+	csrfFieldName       = "csrf_token"
+	listTimeLayout      = "2006-01-02 15:04"
+	messageTimeLayout   = "2006-01-02 15:04:05"
+	untitledPlaceholder = "Untitled conversation"
+	readHeaderTimeout   = 10 * time.Second
+	shutdownTimeout     = 5 * time.Second
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// ExportFunc writes the selected conversations to writer as a ZIP archive.
+type ExportFunc func(writer io.Writer, selected []filters.Candidate) error
+
+// Server answers the web UI's pages for a fixed set of conversations.
+type Server struct {
+	conversations []filters.Candidate
+	byID          map[string]filters.Candidate
+	export        ExportFunc
+	pages         *template.Template
+	csrfToken     string
+	crossOrigin   *http.CrossOriginProtection
+}
+
+type listedConversation struct {
+	ID       string
+	Title    string
+	Created  string
+	Messages int
+}
+
+type listPage struct {
+	CSRFToken     string
+	Query         string
+	Since         string
+	Until         string
+	Error         string
+	Conversations []listedConversation
+	Total         int
+	Truncated     bool
+}
+
+type shownMessage struct {
+	Role string
+	Time string
+	Text string
+}
+
+type conversationPage struct {
+	CSRFToken string
+	ID        string
+	Title     string
+	Created   string
+	Messages  []shownMessage
+}
+
+// NewServer prepares a server for the conversations, listed newest first, exporting selections with export.
+func NewServer(conversations []filters.Candidate, export ExportFunc) (*Server, error) {
+	pages, parseErr := template.ParseFS(templateFiles, "templates/*.html")
+	if parseErr != nil {
+		return nil, fmt.Errorf("parse web templates: %w", parseErr)
+	}
+	server := &Server{
+		conversations: conversations,
+		byID:          make(map[string]filters.Candidate, len(conversations)),
+		export:        export,
+		pages:         pages,
+		csrfToken:     rand.Text(),
+		crossOrigin:   http.NewCrossOriginProtection(),
+	}
+	for _, candidate := range conversations {
+		server.byID[candidate.Conversation.ID] = candidate
+	}
+	return server, nil
+}
+
+// Handler routes the web UI: the search page at /, one conversation at /conversations/:id, and POST /export,
+// which only accepts same-origin requests carrying the token of the pages this server rendered.
+func (server *Server) Handler() http.Handler {
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.SetHTMLTemplate(server.pages)
+	engine.GET("/", server.handleList)
+	engine.GET("/conversations/:id", server.handleConversation)
+	engine.POST("/export", server.requireSameOrigin, server.handleExport)
+	return engine
+}
+
+// Serve answers the web UI at address until ctx is done, then shuts the server down, letting requests in
+// flight finish for a few seconds. It returns nil after such a shutdown.
+func (server *Server) Serve(ctx context.Context, address string) error {
+	listener, listenErr := net.Listen("tcp", address)
+	if listenErr != nil {
+		return fmt.Errorf("listen on %s: %w", address, listenErr)
+	}
+	httpServer := &http.Server{
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	shutdownErr := make(chan error, 1)
+	stopShutdown := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+		shutdownErr <- httpServer.Shutdown(shutdownCtx)
+	})
+	defer stopShutdown()
+	serveErr := httpServer.Serve(listener)
+	if !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return <-shutdownErr
+}
+
+func (server *Server) handleList(ginContext *gin.Context) {
+	page := listPage{
+		CSRFToken: server.csrfToken,
+		Query:     strings.TrimSpace(ginContext.Query("q")),
+		Since:     strings.TrimSpace(ginContext.Query("since")),
+		Until:     strings.TrimSpace(ginContext.Query("until")),
+	}
+	matches, searchErr := server.search(page.Query, page.Since, page.Until)
+	if searchErr != nil {
+		page.Error = searchErr.Error()
+	}
+	page.Total = len(matches)
+	if len(matches) > resultLimit {
+		matches = matches[:resultLimit]
+		page.Truncated = true
+	}
+	for _, candidate := range matches {
+		conversation := candidate.Conversation
+		page.Conversations = append(page.Conversations, listedConversation{
+			ID:       conversation.ID,
+			Title:    displayTitle(conversation.Title),
+			Created:  conversation.CreateTime.Format(listTimeLayout),
+			Messages: utils.CountDialogueMessages(model.ActiveBranch(conversation)),
+		})
+	}
+	ginContext.HTML(http.StatusOK, "index.html", page)
+}
+
+// search returns the conversations matching the query text and created within the date bounds, newest first.
+func (server *Server) search(queryText string, since string, until string) ([]filters.Candidate, error) {
+	var criteria filters.Criteria
+	if since != "" {
+		bound, parseErr := utils.ParseDateBound(since, false)
+		if parseErr != nil {
+			return nil, fmt.Errorf("since: %w", parseErr)
+		}
+		criteria.Since = bound
+	}
+	if until != "" {
+		bound, parseErr := utils.ParseDateBound(until, true)
+		if parseErr != nil {
+			return nil, fmt.Errorf("until: %w", parseErr)
+		}
+		criteria.Until = bound
+	}
+	query := filters.Query{SearchScope: filters.SearchVisible}
+	if queryText != "" {
+		query.Patterns = []string{queryText}
+	}
+	matcher, compileErr := query.Compile()
+	if compileErr != nil {
+		return nil, compileErr
+	}
+	predicates := criteria.Predicates()
+	var matches []filters.Candidate
+	for _, candidate := range server.conversations {
+		if filters.MatchesAll(predicates, candidate) && matcher.Matches(candidate) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches, nil
+}
+
+func (server *Server) handleConversation(ginContext *gin.Context) {
+	candidate, found := server.byID[ginContext.Param("id")]
+	if !found {
+		ginContext.String(http.StatusNotFound, "conversation not found")
+		return
+	}
+	conversation := candidate.Conversation
+	page := conversationPage{
+		CSRFToken: server.csrfToken,
+		ID:        conversation.ID,
+		Title:     displayTitle(conversation.Title),
+		Created:   conversation.CreateTime.Format(listTimeLayout),
+	}
+	for _, message := range utils.VisibleMessages(model.ActiveBranch(conversation)) {
+		if strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		page.Messages = append(page.Messages, shownMessage{Role: message.Role, Time: formatTime(message.CreateTime), Text: message.Text})
+	}
+	ginContext.HTML(http.StatusOK, "conversation.html", page)
+}
+
+// requireSameOrigin rejects requests another site's page made the browser send: those a browser marks as
+// cross-origin, and those without the token embedded in the forms of this server's pages.
+func (server *Server) requireSameOrigin(ginContext *gin.Context) {
+	if originErr := server.crossOrigin.Check(ginContext.Request); originErr != nil {
+		ginContext.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": originErr.Error()})
+		return
+	}
+	token := ginContext.PostForm(csrfFieldName)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(server.csrfToken)) != 1 {
+		ginContext.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing or invalid form token"})
+		return
+	}
+	ginContext.Next()
+}
+
+// handleExport builds the ZIP of the selected conversations in a temporary file before sending any of it, so
+// a failed export is answered with an error instead of a truncated download.
+func (server *Server) handleExport(ginContext *gin.Context) {
+	var selected []filters.Candidate
+	for _, identifier := range ginContext.PostFormArray("id") {
+		if candidate, found := server.byID[identifier]; found {
+			selected = append(selected, candidate)
+		}
+	}
+	if len(selected) == 0 {
+		ginContext.JSON(http.StatusBadRequest, gin.H{"error": "no conversations selected"})
+		return
+	}
+	exportFile, createErr := os.CreateTemp("", exportTempPattern)
+	if createErr != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("create export file: %v", createErr)})
+		return
+	}
+	defer os.Remove(exportFile.Name())
+	exportErr := server.export(exportFile, selected)
+	if closeErr := exportFile.Close(); exportErr == nil {
+		exportErr = closeErr
+	}
+	if exportErr != nil {
+		ginContext.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("export conversations: %v", exportErr)})
+		return
+	}
+	ginContext.FileAttachment(exportFile.Name(), exportFileName)
+}
+
+func displayTitle(title string) string {
+	if strings.TrimSpace(title) == "" {
+		return untitledPlaceholder
+	}
+	return title
+}
+
+func formatTime(moment time.Time) string {
+	if moment.IsZero() {
+		return ""
+	}
+	return moment.Format(messageTimeLayout)
+}
@@ -0,0 +1,142 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+)
+
+const (
+	testConversationID = "c1"
+	testRecord         = `{"id":"c1","title":"Terraform <notes>","create_time":1709301792,"mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":["plan the module"]}}}},"current_node":"m1"}`
+	exportedContent    = "zip bytes"
+)
+
+func newTestServer(t *testing.T, export ExportFunc) *Server {
+	t.Helper()
+	conversation, decodeErr := model.Decode([]byte(testRecord))
+	if decodeErr != nil {
+		t.Fatalf("Decode: %v", decodeErr)
+	}
+	server, serverErr := NewServer([]filters.Candidate{{Conversation: conversation, Serialized: []byte(testRecord)}}, export)
+	if serverErr != nil {
+		t.Fatalf("NewServer: %v", serverErr)
+	}
+	return server
+}
+
+func TestServerPages(t *testing.T) {
+	server := newTestServer(t, nil)
+	testCases := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		contains       []string
+	}{
+		{name: "list", target: "/", expectedStatus: http.StatusOK, contains: []string{"Terraform &lt;notes&gt;", `name="csrf_token" value="` + server.csrfToken + `"`, "bootstrap.min.css"}},
+		{name: "search miss", target: "/?q=ansible", expectedStatus: http.StatusOK, contains: []string{"0 conversations"}},
+		{name: "bad date", target: "/?since=yesterday", expectedStatus: http.StatusOK, contains: []string{"alert-danger"}},
+		{name: "conversation", target: "/conversations/" + testConversationID, expectedStatus: http.StatusOK, contains: []string{"plan the module", `name="csrf_token"`}},
+		{name: "unknown conversation", target: "/conversations/missing", expectedStatus: http.StatusNotFound},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.target, nil))
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, testCase.expectedStatus)
+			}
+			for _, expected := range testCase.contains {
+				if !strings.Contains(recorder.Body.String(), expected) {
+					t.Errorf("page lacks %q:\n%s", expected, recorder.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServerExport(t *testing.T) {
+	succeed := func(writer io.Writer, selected []filters.Candidate) error {
+		_, writeErr := io.WriteString(writer, exportedContent)
+		return writeErr
+	}
+	failHalfway := func(writer io.Writer, selected []filters.Candidate) error {
+		_, _ = io.WriteString(writer, "partial")
+		return errors.New("disk full")
+	}
+	testCases := []struct {
+		name           string
+		export         ExportFunc
+		token          string
+		ids            []string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "same origin", export: succeed, ids: []string{testConversationID}, headers: map[string]string{"Origin": "http://example.com", "Sec-Fetch-Site": "same-origin"}, expectedStatus: http.StatusOK, expectedBody: exportedContent},
+		{name: "no browser headers", export: succeed, ids: []string{testConversationID}, expectedStatus: http.StatusOK, expectedBody: exportedContent},
+		{name: "cross site fetch", export: succeed, ids: []string{testConversationID}, headers: map[string]string{"Sec-Fetch-Site": "cross-site"}, expectedStatus: http.StatusForbidden},
+		{name: "foreign origin", export: succeed, ids: []string{testConversationID}, headers: map[string]string{"Origin": "http://evil.example"}, expectedStatus: http.StatusForbidden},
+		{name: "missing token", export: succeed, token: "-", ids: []string{testConversationID}, expectedStatus: http.StatusForbidden},
+		{name: "wrong token", export: succeed, token: "guessed", ids: []string{testConversationID}, expectedStatus: http.StatusForbidden},
+		{name: "nothing selected", export: succeed, ids: []string{"missing"}, expectedStatus: http.StatusBadRequest},
+		{name: "failed export answers with an error", export: failHalfway, ids: []string{testConversationID}, expectedStatus: http.StatusInternalServerError, expectedBody: `{"error":"export conversations: disk full"}`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newTestServer(t, testCase.export)
+			form := url.Values{"id": testCase.ids}
+			switch testCase.token {
+			case "":
+				form.Set(csrfFieldName, server.csrfToken)
+			case "-":
+			default:
+				form.Set(csrfFieldName, testCase.token)
+			}
+			request := httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for name, value := range testCase.headers {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			server.Handler().ServeHTTP(recorder, request)
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, testCase.expectedStatus, recorder.Body.String())
+			}
+			if testCase.expectedBody != "" && recorder.Body.String() != testCase.expectedBody {
+				t.Errorf("body = %q, want %q", recorder.Body.String(), testCase.expectedBody)
+			}
+		})
+	}
+}
+
+func TestServeStopsWithContext(t *testing.T) {
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0")
+	if listenErr != nil {
+		t.Fatalf("Listen: %v", listenErr)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- newTestServer(t, nil).Serve(ctx, address) }()
+	cancel()
+	select {
+	case serveErr := <-served:
+		if serveErr != nil {
+			t.Fatalf("Serve: %v", serveErr)
+		}
+	case <-time.After(shutdownTimeout + time.Second):
+		t.Fatal("Serve did not return once its context was done")
+	}
+}
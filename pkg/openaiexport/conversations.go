@@ -0,0 +1,58 @@
+package openaiexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/model"
+)
+
+// errStopped ends the scan of an export when the consumer of Conversations stops ranging.
+var errStopped = errors.New("iteration stopped")
+
+// Conversations streams every conversation of the export, in the order the export stores them, decoding one
+// at a time so the whole export is never held in memory. Unlike Search, it does no filtering and hands over
+// every copy of a conversation held more than once, for callers applying their own logic:
+//
+//	for conversation, readErr := range export.Conversations(ctx) {
+//		if readErr != nil {
+//			return readErr
+//		}
+//		...
+//	}
+//
+// A conversation that cannot be decoded is yielded as an error and the iteration goes on; an export that
+// cannot be read yields its error last, and once ctx is done the iteration ends with ctx's error.
+func (export *Export) Conversations(ctx context.Context) iter.Seq2[Conversation, error] {
+	return func(yield func(Conversation, error) bool) {
+		for index, source := range export.sources {
+			if len(export.sources) > 1 && !source.HasConversations() {
+				continue
+			}
+			scanErr := source.EachConversation(ctx, func(serialized []byte, _ archive.Origin) error {
+				conversation, decodeErr := model.Decode(serialized)
+				if decodeErr != nil {
+					decodeErr = fmt.Errorf("%s: decode conversation: %w", export.paths[index], decodeErr)
+					if !yield(Conversation{}, decodeErr) {
+						return errStopped
+					}
+					return nil
+				}
+				if !yield(newConversation(conversation, serialized), nil) {
+					return errStopped
+				}
+				return nil
+			})
+			if errors.Is(scanErr, errStopped) {
+				return
+			}
+			if scanErr != nil {
+				yield(Conversation{}, fmt.Errorf("%s: %w", export.paths[index], scanErr))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package openaiexport reads ChatGPT data exports, searches their conversations, and extracts the matches to a
+// folder, for Go programs that embed what the openai_extract command does instead of running it.
+//
+//	export, openErr := openaiexport.Open(openaiexport.Options{}, "export.zip")
+//	if openErr != nil {
+//		return openErr
+//	}
+//	defer export.Close()
+//	conversations, searchErr := export.Search(ctx, openaiexport.Query{Patterns: []string{"terraform"}})
+//
+// The types of this package are its stable surface; the command's internal packages may change at any time.
+package openaiexport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"openai_extract/internal/archive"
+	"openai_extract/internal/extract"
+	"openai_extract/internal/filters"
+	"openai_extract/internal/model"
+	"openai_extract/internal/render"
+)
+
+// ErrNoMatch is what Extract fails with when the query matches no conversation; test for it with errors.Is.
+var ErrNoMatch = filters.ErrNoMatch
+
+// Source names the service an export was made by.
+type Source string
+
+const (
+	// SourceOpenAI is a ChatGPT data export.
+	SourceOpenAI Source = Source(archive.SourceOpenAI)
+	// SourceGemini is a Google Takeout archive holding Gemini Apps activity.
+	SourceGemini Source = Source(archive.SourceGemini)
+)
+
+// Options tunes how exports are opened and read.
+type Options struct {
+	// Password decrypts encrypted ZIP exports.
+	Password string
+	// MaxMemory caps the bytes of decompressed entries held in memory for tar.gz exports; beyond it entries
+	// spill to a temporary folder. Zero means no cap.
+	MaxMemory int64
+	// Source selects how conversations are read; empty means SourceOpenAI.
+	Source Source
+	// RecordCacheDir is a folder where the positions of conversations in export files are cached, so later
+	// reads of an unchanged export skip parsing; empty disables the cache.
+	RecordCacheDir string
+	// Jobs is how many conversations are decoded and matched at once; zero means one per CPU.
+	Jobs int
+}
+
+// Export is one or more opened export files: a whole export, or the parts of a split one.
+type Export struct {
+	paths   []string
+	options Options
+	sources []*archive.Archive
+}
+
+// Open opens the export files at paths, which are ZIP, tar.gz, or unzipped export folders. Conversations and
+// attachments are read only when searched or extracted. Close the export when done.
+func Open(options Options, paths ...string) (*Export, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("open export: no paths given")
+	}
+	export := &Export{paths: paths, options: options}
+	for _, path := range paths {
+		source, openErr := archive.OpenArchive(path, archive.OpenOptions{
+			Password:       options.passwordSource(),
+			MemoryBudget:   options.MaxMemory,
+			Source:         archive.Source(options.Source),
+			RecordCacheDir: options.RecordCacheDir,
+		})
+		if openErr != nil {
+			_ = export.Close()
+			return nil, fmt.Errorf("open export %q: %w", path, openErr)
+		}
+		export.sources = append(export.sources, source)
+	}
+	return export, nil
+}
+
+// Close releases the opened export files.
+func (export *Export) Close() error {
+	var closeErrs []error
+	for _, source := range export.sources {
+		closeErrs = append(closeErrs, source.Close())
+	}
+	export.sources = nil
+	return errors.Join(closeErrs...)
+}
+
+// Search reads every conversation of the export once and returns those the query matches, oldest first, with
+// the newest copy of conversations held more than once. Matching nothing is not an error. Once ctx is done the
+// search stops with its error.
+func (export *Export) Search(ctx context.Context, query Query) ([]Conversation, error) {
+	filterQuery, criteria := query.internal()
+	candidates, searchErr := extract.Search(ctx, export.paths, export.sources, filterQuery, criteria, export.options.jobs())
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	conversations := make([]Conversation, 0, len(candidates))
+	for _, candidate := range candidates {
+		conversations = append(conversations, newConversation(candidate.Conversation, candidate.Serialized))
+	}
+	return conversations, nil
+}
+
+// Extract writes the conversations the query matches into outputFolder, one subfolder each with the
+// conversation document and its attachments, as the export command does, and prints each folder written to
+// stdout. It reads the export files opened by Open rather than opening them again. It fails with ErrNoMatch when
+// the query matches nothing. Once ctx is done the extraction stops, keeping what was written.
+func (export *Export) Extract(ctx context.Context, query Query, outputFolder string, options ExtractOptions) error {
+	if outputFolder == "" {
+		return errors.New("extract: no output folder given")
+	}
+	format := options.Format
+	if format == "" {
+		format = render.DefaultFormat
+	}
+	if _, formatErr := render.LookupFormat(format); formatErr != nil {
+		return formatErr
+	}
+	location := options.Location
+	if location == nil {
+		location = time.Local
+	}
+	filterQuery, criteria := query.internal()
+	return extract.RunArchives(ctx, extract.Options{
+		Input:      extract.InputSettings{Paths: export.paths},
+		Queries:    []extract.NamedQuery{{Query: filterQuery, Criteria: criteria}},
+		OutputRoot: outputFolder,
+		Output: extract.OutputSettings{
+			Format:           format,
+			ExtractCode:      options.ExtractCode,
+			IncludeReasoning: options.IncludeReasoning,
+			Location:         location,
+			TrustArchive:     options.TrustArchive,
+			StatePath:        options.StatePath,
+			Branches:         model.BranchCurrent,
+			Jobs:             export.options.jobs(),
+		},
+	}, export.sources)
+}
+
+func (options Options) passwordSource() archive.PasswordSource {
+	if options.Password == "" {
+		return nil
+	}
+	return func() (string, error) {
+		return options.Password, nil
+	}
+}
+
+func (options Options) jobs() int {
+	if options.Jobs < 1 {
+		return runtime.NumCPU()
+	}
+	return options.Jobs
+}
+
+// ExtractOptions selects what Extract writes for each matched conversation.
+type ExtractOptions struct {
+	// Format is the conversation document format: json, md, html, sqlite, or sharegpt; empty means json.
+	Format string
+	// ExtractCode also writes every fenced code block under code/.
+	ExtractCode bool
+	// IncludeReasoning renders the reasoning summaries of reasoning models in transcripts.
+	IncludeReasoning bool
+	// Location is the time zone of rendered times and folder names; nil means the local zone.
+	Location *time.Location
+	// TrustArchive writes linked files under their archive names without rejecting unsafe names.
+	TrustArchive bool
+	// StatePath records what was extracted in this JSON file and skips conversations extracted unchanged.
+	StatePath string
+}
+
+// Query selects conversations. Its zero value matches every conversation.
+type Query struct {
+	// Patterns must all be found in a conversation, or any of them when MatchAny is set. They are case
+	// insensitive words or regular expressions, and may be prefixed with a scope such as title:.
+	Patterns []string
+	// Excludes rejects conversations where any of them is found.
+	Excludes []string
+	// IDs selects conversations by id, alone or together with Patterns.
+	IDs           []string
+	MatchAny      bool
+	WholeWord     bool
+	CaseSensitive bool
+	TitleOnly     bool
+	// Role restricts pattern matching to messages of this author role: user, assistant, system, or tool.
+	Role string
+	// Since and Until bound the creation time of conversations; zero values leave them open.
+	Since time.Time
+	Until time.Time
+	// ContentTypes and Languages must all be present in a conversation, as content types of its messages and
+	// languages of its code blocks.
+	ContentTypes []string
+	Languages    []string
+	// Skip and Limit page through the matches ordered by creation time; a zero Limit means no limit.
+	Skip  int
+	Limit int
+}
+
+func (query Query) internal() (filters.Query, filters.Criteria) {
+	matchMode := filters.MatchAll
+	if query.MatchAny {
+		matchMode = filters.MatchAny
+	}
+	return filters.Query{
+		IDs:           query.IDs,
+		Patterns:      query.Patterns,
+		Excludes:      query.Excludes,
+		MatchMode:     matchMode,
+		WholeWord:     query.WholeWord,
+		CaseSensitive: query.CaseSensitive,
+		TitleOnly:     query.TitleOnly,
+		Role:          query.Role,
+	}, filters.Criteria{
+		ContentTypes: query.ContentTypes,
+		Languages:    query.Languages,
+		Since:        query.Since,
+		Until:        query.Until,
+		Page:         filters.Page{Skip: query.Skip, Limit: query.Limit},
+	}
+}
+
+// Conversation is one matched conversation.
+type Conversation struct {
+	ID         string
+	Title      string
+	CreateTime time.Time
+	UpdateTime time.Time
+	// Model is the model slug the conversation was held with.
+	Model    string
+	Archived bool
+	Starred  bool
+	// GPT and Project name the custom GPT or Project the conversation was held in, when there is one.
+	GPT     string
+	Project string
+	// Messages are the messages of the branch shown in ChatGPT, in order.
+	Messages []Message
+	// JSON is the conversation as the export stores it.
+	JSON json.RawMessage
+}
+
+// Message is one message of a conversation.
+type Message struct {
+	ID          string
+	Role        string
+	ContentType string
+	Text        string
+	CreateTime  time.Time
+}
+
+func newConversation(conversation model.Conversation, serialized []byte) Conversation {
+	branch := model.ActiveBranch(conversation)
+	messages := make([]Message, 0, len(branch))
+	for _, message := range branch {
+		messages = append(messages, Message{
+			ID:          message.ID,
+			Role:        message.Role,
+			ContentType: message.ContentType,
+			Text:        message.Text,
+			CreateTime:  message.CreateTime,
+		})
+	}
+	return Conversation{
+		ID:         conversation.ID,
+		Title:      conversation.Title,
+		CreateTime: conversation.CreateTime,
+		UpdateTime: conversation.UpdateTime,
+		Model:      model.Model(conversation),
+		Archived:   conversation.IsArchived,
+		Starred:    conversation.IsStarred,
+		GPT:        conversation.GizmoName,
+		Project:    conversation.ProjectName,
+		Messages:   messages,
+		JSON:       json.RawMessage(serialized),
+	}
+}
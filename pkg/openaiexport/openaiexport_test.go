@@ -0,0 +1,80 @@
+package openaiexport
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConversations = `[{"id":"c1","title":"Terraform notes","create_time":1709301792,"mapping":{"m1":{"id":"m1","children":[],"message":{"id":"m1","author":{"role":"user"},"content":{"content_type":"text","parts":["plan the terraform module"]}}}},"current_node":"m1"}]`
+
+func writeTestExport(t *testing.T) string {
+	t.Helper()
+	exportPath := filepath.Join(t.TempDir(), "export.zip")
+	file, createErr := os.Create(exportPath)
+	if createErr != nil {
+		t.Fatalf("Create: %v", createErr)
+	}
+	zipWriter := zip.NewWriter(file)
+	entry, entryErr := zipWriter.Create("conversations.json")
+	if entryErr != nil {
+		t.Fatalf("Create entry: %v", entryErr)
+	}
+	if _, writeErr := entry.Write([]byte(testConversations)); writeErr != nil {
+		t.Fatalf("Write: %v", writeErr)
+	}
+	if closeErr := errors.Join(zipWriter.Close(), file.Close()); closeErr != nil {
+		t.Fatalf("close export: %v", closeErr)
+	}
+	return exportPath
+}
+
+func TestExtractReadsOpenedExport(t *testing.T) {
+	testCases := []struct {
+		name          string
+		patterns      []string
+		expectedErr   error
+		expectFolders int
+	}{
+		{name: "match", patterns: []string{"terraform"}, expectFolders: 1},
+		{name: "no match", patterns: []string{"ansible"}, expectedErr: ErrNoMatch},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			exportPath := writeTestExport(t)
+			export, openErr := Open(Options{Jobs: 1}, exportPath)
+			if openErr != nil {
+				t.Fatalf("Open: %v", openErr)
+			}
+			defer export.Close()
+			if removeErr := os.Remove(exportPath); removeErr != nil {
+				t.Fatalf("Remove: %v", removeErr)
+			}
+			outputFolder := t.TempDir()
+			extractErr := export.Extract(context.Background(), Query{Patterns: testCase.patterns}, outputFolder, ExtractOptions{})
+			if !errors.Is(extractErr, testCase.expectedErr) {
+				t.Fatalf("Extract error = %v, want %v", extractErr, testCase.expectedErr)
+			}
+			entries, _ := os.ReadDir(outputFolder)
+			if len(entries) != testCase.expectFolders {
+				t.Errorf("output holds %d entries, want %d", len(entries), testCase.expectFolders)
+			}
+		})
+	}
+}
+
+func TestExtractAfterClose(t *testing.T) {
+	export, openErr := Open(Options{}, writeTestExport(t))
+	if openErr != nil {
+		t.Fatalf("Open: %v", openErr)
+	}
+	if closeErr := export.Close(); closeErr != nil {
+		t.Fatalf("Close: %v", closeErr)
+	}
+	if extractErr := export.Extract(context.Background(), Query{}, t.TempDir(), ExtractOptions{}); extractErr == nil {
+		t.Fatal("Extract succeeded on a closed export")
+	}
+}